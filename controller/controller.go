@@ -18,11 +18,16 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
@@ -80,6 +85,32 @@ var (
 			Help:      "Number of reconcile loops ending up with no changes on the DNS provider side.",
 		},
 	)
+	zoneErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "zone_errors_total",
+			Help:      "Number of failures to apply the changes of a zone.",
+		},
+		[]string{"zone"},
+	)
+	zoneLastSyncTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "zone_last_sync_timestamp_seconds",
+			Help:      "Timestamp of the last successful application of the changes of a zone.",
+		},
+		[]string{"zone"},
+	)
+	consecutiveFailures = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "consecutive_failures",
+			Help:      "Number of consecutive failed synchronizations, the next one is backed off accordingly.",
+		},
+	)
 	deprecatedRegistryErrors = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Subsystem: "registry",
@@ -105,6 +136,9 @@ func init() {
 	prometheus.MustRegister(deprecatedRegistryErrors)
 	prometheus.MustRegister(deprecatedSourceErrors)
 	prometheus.MustRegister(controllerNoChangesTotal)
+	prometheus.MustRegister(zoneErrorsTotal)
+	prometheus.MustRegister(zoneLastSyncTimestamp)
+	prometheus.MustRegister(consecutiveFailures)
 }
 
 // Controller is responsible for orchestrating the different components.
@@ -118,27 +152,217 @@ type Controller struct {
 	Registry registry.Registry
 	// The policy that defines which changes to DNS records are allowed
 	Policy plan.Policy
+	// The ConflictResolver decides which resource acquires a DNS name claimed by several ones
+	ConflictResolver plan.ConflictResolver
 	// The interval between individual synchronizations
 	Interval time.Duration
 	// The DomainFilter defines which DNS records to keep or exclude
 	DomainFilter endpoint.DomainFilterInterface
 	// The nextRunAt used for throttling and batching reconciliation
 	nextRunAt time.Time
-	// The nextRunAtMux is for atomic updating of nextRunAt
+	// The nextRunAtMux is for atomic updating of nextRunAt, failures and backoffUntil
 	nextRunAtMux sync.Mutex
+	// The number of consecutive failed runs
+	failures int
+	// No run starts before backoffUntil after a failed run
+	backoffUntil time.Time
 	// DNS record types that will be considered for management
 	ManagedRecordTypes []string
 	// MinEventSyncInterval is used as window for batching events
 	MinEventSyncInterval time.Duration
+	// MaxFailureBackoff caps the delay before retrying a failed run, which starts at Interval and doubles with
+	// every consecutive failure. Failed runs are retried after Interval when zero.
+	MaxFailureBackoff time.Duration
+	// AdoptionDomainFilter matches the DNS names of records without owner which may be adopted, adoption is disabled when nil
+	AdoptionDomainFilter endpoint.DomainFilterInterface
+	// ZoneLister lists the zones of the provider, the changes of every zone are applied separately when set.
+	// The records are still read for all zones at once, see RunOnce.
+	ZoneLister provider.ZoneIDNameLister
+	// OwnerID is the owner id of the registry, it is empty for registries without ownership
+	OwnerID string
+	// EventRecorder records events on the resources of the changed records, no events are recorded when nil
+	EventRecorder EventRecorder
+	// ResultReporters report the outcome of every synchronization back to the sources
+	ResultReporters []source.ResultReporter
+	// Status tracks the outcome of the synchronizations, it is not updated when nil
+	Status *Status
 }
 
 // RunOnce runs a single iteration of a reconciliation loop.
-func (c *Controller) RunOnce(ctx context.Context) error {
+// A zone which fails to apply its changes doesn't keep the other zones from converging. Reading the current
+// records isn't partitioned by zone though: registries and providers only list the records of all zones
+// together, and the plan can't be calculated safely without them, so a failure to read them aborts the
+// synchronization of every zone.
+func (c *Controller) RunOnce(ctx context.Context) (err error) {
+	defer func() { c.Status.recordRun(time.Now(), err) }()
+
+	plan, ctx, err := c.calculatePlan(ctx)
+	if err != nil {
+		return err
+	}
+	c.Status.recordPlan(time.Now(), plan.Changes)
+
+	var failed map[*endpoint.Endpoint]error
+	if plan.Changes.HasChanges() {
+		c.recordBlockedEvents(plan.Changes)
+		failed, err = c.applyChanges(ctx, plan.Changes)
+	} else {
+		controllerNoChangesTotal.Inc()
+		log.Info("All records are already up to date")
+	}
+	c.reportResults(ctx, plan, failed)
+	if err != nil {
+		return err
+	}
+
+	lastSyncTimestamp.SetToCurrentTime()
+	return nil
+}
+
+// applyChanges applies the changes of every zone separately if the zones are known, so that
+// the changes of the other zones are applied even if a zone fails. It returns the error of
+// every change which failed.
+func (c *Controller) applyChanges(ctx context.Context, changes *plan.Changes) (map[*endpoint.Endpoint]error, error) {
+	var zones provider.ZoneIDName
+	if c.ZoneLister != nil {
+		var err error
+		if zones, err = c.ZoneLister.ZoneIDNames(ctx); err != nil {
+			log.Warnf("Failed to list the zones, applying the changes of all zones at once: %v", err)
+		}
+	}
+	failed := map[*endpoint.Endpoint]error{}
+	if len(zones) == 0 {
+		err := c.Registry.ApplyChanges(ctx, changes)
+		c.recordChangeEvents(changes, err)
+		if err != nil {
+			registryErrorsTotal.Inc()
+			deprecatedRegistryErrors.Inc()
+			addFailedChanges(failed, changes, err)
+			return failed, err
+		}
+		return failed, nil
+	}
+
+	var failedZones []string
+	for _, zc := range changesByZone(zones, changes) {
+		if !zc.changes.HasChanges() {
+			continue
+		}
+		err := c.Registry.ApplyChanges(ctx, zc.changes)
+		c.recordChangeEvents(zc.changes, err)
+		if err != nil {
+			registryErrorsTotal.Inc()
+			deprecatedRegistryErrors.Inc()
+			addFailedChanges(failed, zc.changes, err)
+			if zc.zoneName == "" {
+				log.Errorf("Failed to apply the changes outside of the known zones: %v", err)
+				failedZones = append(failedZones, "<none>")
+				continue
+			}
+			log.Errorf("Failed to apply the changes of zone %s [Id: %s]: %v", zc.zoneName, zc.zoneID, err)
+			zoneErrorsTotal.WithLabelValues(zc.zoneName).Inc()
+			failedZones = append(failedZones, zc.zoneName)
+			continue
+		}
+		if zc.zoneName != "" {
+			log.Debugf("Applied the changes of zone %s [Id: %s]", zc.zoneName, zc.zoneID)
+			zoneLastSyncTimestamp.WithLabelValues(zc.zoneName).SetToCurrentTime()
+		}
+	}
+	if len(failedZones) > 0 {
+		return failed, &zonesError{zones: failedZones}
+	}
+	return failed, nil
+}
+
+// zonesError is returned when the changes of some zones failed while the other zones were applied
+type zonesError struct {
+	zones []string
+}
+
+func (e *zonesError) Error() string {
+	return fmt.Sprintf("failed to apply the changes of zones: %s", strings.Join(e.zones, ", "))
+}
+
+// addFailedChanges marks the records of all changes as failed with the error
+func addFailedChanges(failed map[*endpoint.Endpoint]error, changes *plan.Changes, err error) {
+	for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew, changes.Delete} {
+		for _, ep := range eps {
+			failed[ep] = err
+		}
+	}
+}
+
+// zoneChanges are the changes of the records in a zone
+type zoneChanges struct {
+	zoneID   string
+	zoneName string
+	changes  *plan.Changes
+}
+
+// changesByZone partitions the changes by the zone of their records, changes of records which belong to no
+// known zone are returned last with an empty zone. The old and new versions of updated records stay paired
+// since they have the same name.
+func changesByZone(zones provider.ZoneIDName, changes *plan.Changes) []*zoneChanges {
+	byZone := map[string]*zoneChanges{}
+	partition := func(ep *endpoint.Endpoint) *plan.Changes {
+		zoneID, zoneName := zones.FindZone(ep.DNSName)
+		zc, ok := byZone[zoneID]
+		if !ok {
+			zc = &zoneChanges{zoneID: zoneID, zoneName: zoneName, changes: &plan.Changes{}}
+			byZone[zoneID] = zc
+		}
+		return zc.changes
+	}
+	for _, ep := range changes.Create {
+		cs := partition(ep)
+		cs.Create = append(cs.Create, ep)
+	}
+	for _, ep := range changes.UpdateOld {
+		cs := partition(ep)
+		cs.UpdateOld = append(cs.UpdateOld, ep)
+	}
+	for _, ep := range changes.UpdateNew {
+		cs := partition(ep)
+		cs.UpdateNew = append(cs.UpdateNew, ep)
+	}
+	for _, ep := range changes.Delete {
+		cs := partition(ep)
+		cs.Delete = append(cs.Delete, ep)
+	}
+
+	result := make([]*zoneChanges, 0, len(byZone))
+	for _, zc := range byZone {
+		result = append(result, zc)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if (result[i].zoneName == "") != (result[j].zoneName == "") {
+			return result[j].zoneName == ""
+		}
+		if result[i].zoneName != result[j].zoneName {
+			return result[i].zoneName < result[j].zoneName
+		}
+		return result[i].zoneID < result[j].zoneID
+	})
+	return result
+}
+
+// Plan calculates the changes a single iteration of the reconciliation loop
+// would apply, without applying them.
+func (c *Controller) Plan(ctx context.Context) (*plan.Plan, error) {
+	plan, _, err := c.calculatePlan(ctx)
+	return plan, err
+}
+
+// calculatePlan reads the current and desired records and calculates the changes
+// between them. It returns the context to apply the changes with.
+func (c *Controller) calculatePlan(ctx context.Context) (*plan.Plan, context.Context, error) {
 	records, err := c.Registry.Records(ctx)
 	if err != nil {
+		// the records of all zones are read at once, so no zone is synchronized
 		registryErrorsTotal.Inc()
 		deprecatedRegistryErrors.Inc()
-		return err
+		return nil, ctx, err
 	}
 	registryEndpointsTotal.Set(float64(len(records)))
 
@@ -148,44 +372,42 @@ func (c *Controller) RunOnce(ctx context.Context) error {
 	if err != nil {
 		sourceErrorsTotal.Inc()
 		deprecatedSourceErrors.Inc()
-		return err
+		return nil, ctx, err
 	}
 	sourceEndpointsTotal.Set(float64(len(endpoints)))
 
 	endpoints = c.Registry.AdjustEndpoints(endpoints)
 
 	plan := &plan.Plan{
-		Policies:           []plan.Policy{c.Policy},
-		Current:            records,
-		Desired:            endpoints,
-		DomainFilter:       endpoint.MatchAllDomainFilters{c.DomainFilter, c.Registry.GetDomainFilter()},
-		PropertyComparator: c.Registry.PropertyValuesEqual,
-		ManagedRecords:     []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
-	}
-
-	plan = plan.Calculate()
-
-	if plan.Changes.HasChanges() {
-		err = c.Registry.ApplyChanges(ctx, plan.Changes)
-		if err != nil {
-			registryErrorsTotal.Inc()
-			deprecatedRegistryErrors.Inc()
-			return err
-		}
-	} else {
-		controllerNoChangesTotal.Inc()
-		log.Info("All records are already up to date")
+		Policies:             []plan.Policy{c.Policy},
+		Current:              records,
+		Desired:              endpoints,
+		DomainFilter:         endpoint.MatchAllDomainFilters{c.DomainFilter, c.Registry.GetDomainFilter()},
+		PropertyComparator:   c.Registry.PropertyValuesEqual,
+		ManagedRecords:       c.ManagedRecordTypes,
+		ConflictResolver:     c.ConflictResolver,
+		AdoptionDomainFilter: c.AdoptionDomainFilter,
 	}
 
-	lastSyncTimestamp.SetToCurrentTime()
-	return nil
+	return plan.Calculate(), ctx, nil
 }
 
+// backoffJitter is the maximum jitter added to the backoff after failed runs, as a fraction of the backoff,
+// so that replicas in many clusters don't retry against a rate limited provider at the same time
+const backoffJitter = 0.2
+
 // ScheduleRunOnce makes sure execution happens at most once per interval.
+// Events coalesce into the next run, which is never earlier than the backoff after a failed run.
 func (c *Controller) ScheduleRunOnce(now time.Time) {
 	c.nextRunAtMux.Lock()
 	defer c.nextRunAtMux.Unlock()
-	c.nextRunAt = now.Add(c.MinEventSyncInterval)
+	nextRunAt := now.Add(c.MinEventSyncInterval)
+	if nextRunAt.Before(c.backoffUntil) {
+		nextRunAt = c.backoffUntil
+	}
+	if nextRunAt.Before(c.nextRunAt) {
+		c.nextRunAt = nextRunAt
+	}
 }
 
 func (c *Controller) ShouldRunOnce(now time.Time) bool {
@@ -198,15 +420,56 @@ func (c *Controller) ShouldRunOnce(now time.Time) bool {
 	return true
 }
 
+// reportRunOnce backs off the next run after a failed run and resets the backoff after a successful one.
+// Runs in which only some zones failed aren't backed off, as that would delay the healthy zones as well.
+func (c *Controller) reportRunOnce(now time.Time, err error) {
+	c.nextRunAtMux.Lock()
+	defer c.nextRunAtMux.Unlock()
+	var zerr *zonesError
+	if err == nil || errors.As(err, &zerr) {
+		c.failures = 0
+		c.backoffUntil = time.Time{}
+		consecutiveFailures.Set(0)
+		return
+	}
+	c.failures++
+	consecutiveFailures.Set(float64(c.failures))
+	if c.MaxFailureBackoff <= 0 {
+		return
+	}
+	backoff := c.failureBackoff()
+	log.Infof("Retrying in %s after %d consecutive failures", backoff.Round(time.Second), c.failures)
+	c.backoffUntil = now.Add(backoff)
+	c.nextRunAt = c.backoffUntil
+}
+
+// failureBackoff returns the jittered delay after the current number of consecutive failures
+func (c *Controller) failureBackoff() time.Duration {
+	maxBackoff := c.MaxFailureBackoff
+	if maxBackoff < c.Interval {
+		maxBackoff = c.Interval
+	}
+	backoff := c.Interval
+	for i := 1; i < c.failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return wait.Jitter(backoff, backoffJitter)
+}
+
 // Run runs RunOnce in a loop with a delay until context is canceled
 func (c *Controller) Run(ctx context.Context) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 	for {
 		if c.ShouldRunOnce(time.Now()) {
-			if err := c.RunOnce(ctx); err != nil {
+			err := c.RunOnce(ctx)
+			if err != nil {
 				log.Error(err)
 			}
+			c.reportRunOnce(time.Now(), err)
 		}
 		select {
 		case <-ticker.C:
@@ -167,9 +167,10 @@ func TestRunOnce(t *testing.T) {
 
 	// Run our controller once to trigger the validation.
 	ctrl := &Controller{
-		Source:   source,
-		Registry: r,
-		Policy:   &plan.SyncPolicy{},
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
 	}
 
 	assert.NoError(t, ctrl.RunOnce(context.Background()))
@@ -178,6 +179,48 @@ func TestRunOnce(t *testing.T) {
 	source.AssertExpectations(t)
 }
 
+func TestPlan(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{
+			DNSName:    "create-record",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    endpoint.Targets{"1.2.3.4"},
+		},
+	}, nil)
+
+	provider := &filteredMockProvider{
+		RecordsStore: []*endpoint.Endpoint{
+			{
+				DNSName:    "delete-record",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"4.3.2.1"},
+			},
+		},
+	}
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	p, err := ctrl.Plan(context.Background())
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(p.Changes.Create, []*endpoint.Endpoint{
+		{DNSName: "create-record", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+	}))
+	assert.True(t, testutils.SameEndpoints(p.Changes.Delete, []*endpoint.Endpoint{
+		{DNSName: "delete-record", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"4.3.2.1"}},
+	}))
+	// planning never applies the changes
+	assert.Empty(t, provider.ApplyChangesCalls)
+	source.AssertExpectations(t)
+}
+
 func TestShouldRunOnce(t *testing.T) {
 	ctrl := &Controller{Interval: 10 * time.Minute, MinEventSyncInterval: 5 * time.Second}
 
@@ -217,6 +260,76 @@ func TestShouldRunOnce(t *testing.T) {
 	assert.False(t, ctrl.ShouldRunOnce(now))
 }
 
+func TestShouldRunOnceBackoff(t *testing.T) {
+	ctrl := &Controller{Interval: time.Minute, MinEventSyncInterval: 5 * time.Second, MaxFailureBackoff: 5 * time.Minute}
+	failure := errors.New("rate limited")
+
+	now := time.Now()
+	assert.True(t, ctrl.ShouldRunOnce(now))
+
+	// the first failure is retried after the interval, with jitter
+	ctrl.reportRunOnce(now, failure)
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Minute-time.Second)))
+
+	// events don't shorten the backoff but coalesce into the next run
+	ctrl.ScheduleRunOnce(now.Add(10 * time.Second))
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(15*time.Second)))
+	now = now.Add(72 * time.Second)
+	assert.True(t, ctrl.ShouldRunOnce(now))
+	assert.False(t, ctrl.ShouldRunOnce(now))
+
+	// the backoff doubles with every consecutive failure
+	ctrl.reportRunOnce(now, failure)
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(2*time.Minute-time.Second)))
+	now = now.Add(144 * time.Second)
+	assert.True(t, ctrl.ShouldRunOnce(now))
+
+	// up to the maximum
+	for i := 0; i < 10; i++ {
+		ctrl.reportRunOnce(now, failure)
+	}
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(5*time.Minute-time.Second)))
+	now = now.Add(6 * time.Minute)
+	assert.True(t, ctrl.ShouldRunOnce(now))
+
+	// a successful run resets the backoff
+	ctrl.reportRunOnce(now, nil)
+	ctrl.ScheduleRunOnce(now)
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(5*time.Second)))
+	ctrl.reportRunOnce(now, failure)
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(72*time.Second)))
+}
+
+func TestShouldRunOnceZoneFailures(t *testing.T) {
+	ctrl := &Controller{Interval: time.Minute, MinEventSyncInterval: 5 * time.Second, MaxFailureBackoff: 5 * time.Minute}
+
+	now := time.Now()
+	assert.True(t, ctrl.ShouldRunOnce(now))
+
+	// failing zones don't delay the other zones, the next run happens after the interval
+	for i := 0; i < 3; i++ {
+		ctrl.reportRunOnce(now, &zonesError{zones: []string{"broken.example.org"}})
+		assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Minute-time.Second)))
+		now = now.Add(time.Minute)
+		assert.True(t, ctrl.ShouldRunOnce(now))
+	}
+
+	// and don't count as consecutive failures
+	ctrl.reportRunOnce(now, errors.New("rate limited"))
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(72*time.Second)))
+}
+
+func TestShouldRunOnceWithoutBackoff(t *testing.T) {
+	ctrl := &Controller{Interval: time.Minute, MinEventSyncInterval: 5 * time.Second}
+
+	now := time.Now()
+	assert.True(t, ctrl.ShouldRunOnce(now))
+	for i := 0; i < 3; i++ {
+		ctrl.reportRunOnce(now, errors.New("rate limited"))
+	}
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(time.Minute)))
+}
+
 func testControllerFiltersDomains(t *testing.T, configuredEndpoints []*endpoint.Endpoint, domainFilter endpoint.DomainFilterInterface, providerEndpoints []*endpoint.Endpoint, expectedChanges []*plan.Changes) {
 	t.Helper()
 	source := new(testutils.MockSource)
@@ -231,10 +344,11 @@ func testControllerFiltersDomains(t *testing.T, configuredEndpoints []*endpoint.
 	require.NoError(t, err)
 
 	ctrl := &Controller{
-		Source:       source,
-		Registry:     r,
-		Policy:       &plan.SyncPolicy{},
-		DomainFilter: domainFilter,
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       domainFilter,
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
 	}
 
 	assert.NoError(t, ctrl.RunOnce(context.Background()))
@@ -368,3 +482,151 @@ func TestWhenMultipleControllerConsidersAllFilteredComain(t *testing.T) {
 		},
 	)
 }
+
+func TestControllerHonorsManagedRecordTypes(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{
+			DNSName:    "delegated.used.tld",
+			RecordType: endpoint.RecordTypeNS,
+			Targets:    endpoint.Targets{"ns1.example.org", "ns2.example.org"},
+		},
+		{
+			DNSName:    "_sip._tcp.used.tld",
+			RecordType: endpoint.RecordTypeSRV,
+			Targets:    endpoint.Targets{"10 5 5060 sip.used.tld"},
+		},
+	}, nil)
+
+	provider := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME, endpoint.RecordTypeNS},
+	}
+
+	assert.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, provider.ApplyChangesCalls, 1)
+	assert.Equal(t, plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{
+				DNSName:    "delegated.used.tld",
+				RecordType: endpoint.RecordTypeNS,
+				Targets:    endpoint.Targets{"ns1.example.org", "ns2.example.org"},
+			},
+		},
+	}, *provider.ApplyChangesCalls[0])
+}
+
+// zonedMockProvider lists its zones and fails to apply the changes of the failing zone.
+type zonedMockProvider struct {
+	filteredMockProvider
+	zones       provider.ZoneIDName
+	zonesErr    error
+	failingZone string
+}
+
+func (p *zonedMockProvider) ZoneIDNames(ctx context.Context) (provider.ZoneIDName, error) {
+	return p.zones, p.zonesErr
+}
+
+func (p *zonedMockProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	p.ApplyChangesCalls = append(p.ApplyChangesCalls, changes)
+	for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateOld, changes.UpdateNew, changes.Delete} {
+		for _, ep := range eps {
+			if _, zoneName := p.zones.FindZone(ep.DNSName); zoneName == p.failingZone {
+				return errors.New("zone is broken")
+			}
+		}
+	}
+	return nil
+}
+
+func newZonedController(t *testing.T, provider *zonedMockProvider) *Controller {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("foo.a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("foo.b.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("bar.b.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+		endpoint.NewEndpoint("foo.c.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("foo.example.net", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	return &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		ZoneLister:         provider,
+	}
+}
+
+func TestRunOnceAppliesChangesByZone(t *testing.T) {
+	provider := &zonedMockProvider{
+		zones: provider.ZoneIDName{
+			"zone-a": "a.example.org",
+			"zone-b": "b.example.org",
+			"zone-c": "c.example.org",
+		},
+		failingZone: "b.example.org",
+	}
+	ctrl := newZonedController(t, provider)
+
+	// the other zones converge even though a zone fails
+	err := ctrl.RunOnce(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "b.example.org")
+	require.Len(t, provider.ApplyChangesCalls, 4)
+	for i, expected := range [][]string{
+		{"foo.a.example.org"},
+		{"foo.b.example.org", "bar.b.example.org"},
+		{"foo.c.example.org"},
+		{"foo.example.net"},
+	} {
+		var created []string
+		for _, ep := range provider.ApplyChangesCalls[i].Create {
+			created = append(created, ep.DNSName)
+		}
+		assert.ElementsMatch(t, expected, created)
+	}
+}
+
+func TestRunOnceAppliesAllChangesWithoutZones(t *testing.T) {
+	provider := &zonedMockProvider{
+		zones:    provider.ZoneIDName{"zone-a": "a.example.org"},
+		zonesErr: errors.New("failed to list zones"),
+	}
+	ctrl := newZonedController(t, provider)
+
+	assert.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, provider.ApplyChangesCalls, 1)
+	assert.Len(t, provider.ApplyChangesCalls[0].Create, 5)
+}
+
+func TestChangesByZone(t *testing.T) {
+	zones := provider.ZoneIDName{
+		"zone-a":     "a.example.org",
+		"zone-sub-a": "sub.a.example.org",
+	}
+	changes := &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("foo.sub.a.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.a.example.org", endpoint.RecordTypeA, "1.2.3.4"), endpoint.NewEndpoint("bar.sub.a.example.org", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("foo.a.example.org", endpoint.RecordTypeA, "5.6.7.8"), endpoint.NewEndpoint("bar.sub.a.example.org", endpoint.RecordTypeA, "5.6.7.8")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.net", endpoint.RecordTypeA, "1.2.3.4")},
+	}
+
+	byZone := changesByZone(zones, changes)
+	require.Len(t, byZone, 3)
+	assert.Equal(t, "a.example.org", byZone[0].zoneName)
+	assert.Equal(t, &plan.Changes{UpdateOld: changes.UpdateOld[:1], UpdateNew: changes.UpdateNew[:1]}, byZone[0].changes)
+	assert.Equal(t, "sub.a.example.org", byZone[1].zoneName)
+	assert.Equal(t, &plan.Changes{Create: changes.Create, UpdateOld: changes.UpdateOld[1:], UpdateNew: changes.UpdateNew[1:]}, byZone[1].changes)
+	assert.Equal(t, "", byZone[2].zoneName)
+	assert.Equal(t, &plan.Changes{Delete: changes.Delete}, byZone[2].changes)
+}
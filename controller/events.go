@@ -0,0 +1,242 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// Reasons of the events recorded on the resources of records
+const (
+	reasonRecordCreated = "DNSRecordCreated"
+	reasonRecordUpdated = "DNSRecordUpdated"
+	reasonRecordDeleted = "DNSRecordDeleted"
+	reasonRecordBlocked = "DNSRecordOwnedByOther"
+	reasonRecordFailed  = "DNSRecordFailed"
+)
+
+// Events beyond the burst are dropped unless the rate allows them, so that a large initial
+// synchronization doesn't flood the API server. Repeated events of a single resource are
+// additionally aggregated and rate limited by the event broadcaster.
+const (
+	eventsPerSecond = 1
+	eventsBurst     = 50
+)
+
+// EventRecorder records events on the Kubernetes resources of records.
+type EventRecorder interface {
+	// Event records an event on the resource given in the format of the resource label of endpoints, e.g. ingress/default/foo
+	Event(resource, eventtype, reason, message string)
+}
+
+// eventResources maps the kinds of the resource labels of endpoints to the Kubernetes resources events are recorded on.
+// Ingresses are looked up in the version the ingress source reads them in, which is given to the recorder.
+var eventResources = map[string]schema.GroupVersionResource{
+	"service":        {Version: "v1", Resource: "services"},
+	"crd":            {Group: "externaldns.k8s.io", Version: "v1alpha1", Resource: "dnsendpoints"},
+	"gateway":        {Group: "networking.istio.io", Version: "v1alpha3", Resource: "gateways"},
+	"virtualservice": {Group: "networking.istio.io", Version: "v1alpha3", Resource: "virtualservices"},
+	"route":          {Group: "route.openshift.io", Version: "v1", Resource: "routes"},
+	"HTTPProxy":      {Group: "projectcontour.io", Version: "v1", Resource: "httpproxies"},
+	"httproute":      {Group: "gateway.networking.k8s.io", Version: "v1beta1", Resource: "httproutes"},
+	"tlsroute":       {Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "tlsroutes"},
+	"tcproute":       {Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "tcproutes"},
+}
+
+// kubeEventRecorder records events on the resources through the Kubernetes API. The resources
+// are looked up for their uid, so that the events show up when describing them. The lookups happen
+// in the background, so that they don't block the synchronization.
+type kubeEventRecorder struct {
+	ctx        context.Context
+	client     dynamic.Interface
+	recorder   record.EventRecorder
+	limiter    flowcontrol.RateLimiter
+	events     chan kubeEvent
+	ingressGVR schema.GroupVersionResource // the version of ingresses read by the ingress source, empty without one
+}
+
+// kubeEvent is an event waiting for its resource to be looked up
+type kubeEvent struct {
+	resource  string
+	eventtype string
+	reason    string
+	message   string
+}
+
+// NewKubeEventRecorder creates an EventRecorder recording events through the Kubernetes API until the context is canceled.
+// Events on ingresses are recorded in the version ingressGVR, which is the one the ingress source reads them in.
+func NewKubeEventRecorder(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, ingressGVR schema.GroupVersionResource) EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	go func() {
+		<-ctx.Done()
+		broadcaster.Shutdown()
+	}()
+
+	r := &kubeEventRecorder{
+		ctx:        ctx,
+		client:     dynamicClient,
+		recorder:   broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "external-dns"}),
+		limiter:    flowcontrol.NewTokenBucketRateLimiter(eventsPerSecond, eventsBurst),
+		events:     make(chan kubeEvent, eventsBurst),
+		ingressGVR: ingressGVR,
+	}
+	go r.run()
+	return r
+}
+
+// Event queues the event, events are dropped if the queue is full
+func (r *kubeEventRecorder) Event(resource, eventtype, reason, message string) {
+	if !r.limiter.TryAccept() {
+		log.Debugf("Dropping event %s on %s, too many events", reason, resource)
+		return
+	}
+	select {
+	case r.events <- kubeEvent{resource: resource, eventtype: eventtype, reason: reason, message: message}:
+	default:
+		log.Debugf("Dropping event %s on %s, too many events queued", reason, resource)
+	}
+}
+
+// run records the queued events until the context is canceled
+func (r *kubeEventRecorder) run() {
+	for {
+		select {
+		case e := <-r.events:
+			ref, err := r.reference(e.resource)
+			if err != nil {
+				log.Debugf("Not recording event %s on %s: %v", e.reason, e.resource, err)
+				continue
+			}
+			r.recorder.Event(ref, e.eventtype, e.reason, e.message)
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// reference looks up the resource given in the format of the resource label of endpoints
+func (r *kubeEventRecorder) reference(resource string) (*corev1.ObjectReference, error) {
+	parts := strings.Split(resource, "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unknown resource format")
+	}
+	gvr, ok := r.eventResource(parts[0])
+	if !ok {
+		return nil, fmt.Errorf("events aren't supported for %s resources", parts[0])
+	}
+	obj, err := r.client.Resource(gvr).Namespace(parts[1]).Get(r.ctx, parts[2], metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ObjectReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+	}, nil
+}
+
+// eventResource returns the Kubernetes resource events are recorded on for the kind of a resource label
+func (r *kubeEventRecorder) eventResource(kind string) (schema.GroupVersionResource, bool) {
+	if kind == "ingress" {
+		return r.ingressGVR, !r.ingressGVR.Empty()
+	}
+	gvr, ok := eventResources[kind]
+	return gvr, ok
+}
+
+// recordEvent records an event on the resource of the endpoint, if it has one
+func (c *Controller) recordEvent(ep *endpoint.Endpoint, eventtype, reason, message string) {
+	resource := ep.Labels[endpoint.ResourceLabelKey]
+	if resource == "" {
+		return
+	}
+	c.EventRecorder.Event(resource, eventtype, reason, message)
+}
+
+// recordBlockedEvents records a warning on the resources whose records aren't updated
+// because they are owned by someone else
+func (c *Controller) recordBlockedEvents(changes *plan.Changes) {
+	if c.EventRecorder == nil || c.OwnerID == "" {
+		return
+	}
+	blockedOld, blockedNew := registry.BlockedUpdates(c.OwnerID, changes)
+	for i, ep := range blockedNew {
+		owner := blockedOld[i].Labels[endpoint.OwnerLabelKey]
+		if owner == "" {
+			c.recordEvent(ep, corev1.EventTypeWarning, reasonRecordBlocked, fmt.Sprintf("Not updating %s, the existing record isn't managed by ExternalDNS", describeRecord(ep)))
+			continue
+		}
+		c.recordEvent(ep, corev1.EventTypeWarning, reasonRecordBlocked, fmt.Sprintf("Not updating %s, the existing record is owned by %q", describeRecord(ep), owner))
+	}
+}
+
+// recordChangeEvents records an event on the resource of every change the registry applied,
+// or a warning with the error if the provider rejected the changes
+func (c *Controller) recordChangeEvents(changes *plan.Changes, err error) {
+	if c.EventRecorder == nil {
+		return
+	}
+	if c.OwnerID != "" {
+		changes = registry.OwnedChanges(c.OwnerID, changes)
+	}
+	if err != nil {
+		for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew, changes.Delete} {
+			for _, ep := range eps {
+				c.recordEvent(ep, corev1.EventTypeWarning, reasonRecordFailed, fmt.Sprintf("Failed to apply the change of %s: %v", describeRecord(ep), err))
+			}
+		}
+		return
+	}
+	for _, ep := range changes.Create {
+		c.recordEvent(ep, corev1.EventTypeNormal, reasonRecordCreated, fmt.Sprintf("Created %s", describeRecord(ep)))
+	}
+	for _, ep := range changes.UpdateNew {
+		c.recordEvent(ep, corev1.EventTypeNormal, reasonRecordUpdated, fmt.Sprintf("Updated %s", describeRecord(ep)))
+	}
+	for _, ep := range changes.Delete {
+		// orphaned ownership records have no record of the resource
+		if _, ok := ep.Labels[endpoint.OrphanedOwnershipLabelKey]; ok {
+			continue
+		}
+		c.recordEvent(ep, corev1.EventTypeNormal, reasonRecordDeleted, fmt.Sprintf("Deleted %s", describeRecord(ep)))
+	}
+}
+
+// describeRecord describes the record of an endpoint for events
+func describeRecord(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s record %s with targets %s", ep.RecordType, ep.DNSName, strings.Join(ep.Targets, ", "))
+}
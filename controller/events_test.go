@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// fakeEventRecorder collects the recorded events as "resource type reason"
+type fakeEventRecorder struct {
+	events []string
+}
+
+func (r *fakeEventRecorder) Event(resource, eventtype, reason, message string) {
+	r.events = append(r.events, resource+" "+eventtype+" "+reason)
+}
+
+// failingMockProvider rejects all changes
+type failingMockProvider struct {
+	filteredMockProvider
+}
+
+func (p *failingMockProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	return errors.New("provider error")
+}
+
+func newEndpointWithResource(dnsName, target, owner, resource string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(dnsName, endpoint.RecordTypeA, target)
+	if owner != "" {
+		ep.Labels[endpoint.OwnerLabelKey] = owner
+	}
+	ep.Labels[endpoint.ResourceLabelKey] = resource
+	return ep
+}
+
+func newEventsTestController(p *filteredMockProvider, r registry.Registry) *Controller {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		newEndpointWithResource("new.example.org", "1.2.3.4", "", "ingress/default/new"),
+		newEndpointWithResource("owned.example.org", "5.6.7.8", "", "service/default/owned"),
+		newEndpointWithResource("foreign.example.org", "5.6.7.8", "", "service/default/foreign"),
+	}, nil)
+	p.RecordsStore = []*endpoint.Endpoint{
+		newEndpointWithResource("owned.example.org", "1.2.3.4", "owner", "service/default/owned"),
+		newEndpointWithResource("foreign.example.org", "1.2.3.4", "other", "service/default/other"),
+		newEndpointWithResource("stale.example.org", "1.2.3.4", "owner", "service/default/stale"),
+	}
+	return &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		OwnerID:            "owner",
+		EventRecorder:      &fakeEventRecorder{},
+	}
+}
+
+func TestRunOnceRecordsEvents(t *testing.T) {
+	p := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := newEventsTestController(p, r)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.ElementsMatch(t, []string{
+		"service/default/foreign Warning DNSRecordOwnedByOther",
+		"ingress/default/new Normal DNSRecordCreated",
+		"service/default/owned Normal DNSRecordUpdated",
+		"service/default/stale Normal DNSRecordDeleted",
+	}, ctrl.EventRecorder.(*fakeEventRecorder).events)
+}
+
+func TestRunOnceRecordsProviderErrorEvents(t *testing.T) {
+	p := &failingMockProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := newEventsTestController(&p.filteredMockProvider, r)
+
+	require.Error(t, ctrl.RunOnce(context.Background()))
+	assert.ElementsMatch(t, []string{
+		"service/default/foreign Warning DNSRecordOwnedByOther",
+		"ingress/default/new Warning DNSRecordFailed",
+		"service/default/owned Warning DNSRecordFailed",
+		"service/default/stale Warning DNSRecordFailed",
+	}, ctrl.EventRecorder.(*fakeEventRecorder).events)
+}
+
+func TestKubeEventRecorderReference(t *testing.T) {
+	service := &unstructured.Unstructured{}
+	service.SetAPIVersion("v1")
+	service.SetKind("Service")
+	service.SetNamespace("default")
+	service.SetName("foo")
+	service.SetUID("1234")
+
+	recorder := &kubeEventRecorder{
+		ctx:    context.Background(),
+		client: fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(), service),
+	}
+
+	ref, err := recorder.reference("service/default/foo")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", ref.APIVersion)
+	assert.Equal(t, "Service", ref.Kind)
+	assert.Equal(t, "default", ref.Namespace)
+	assert.Equal(t, "foo", ref.Name)
+	assert.EqualValues(t, "1234", ref.UID)
+
+	for _, resource := range []string{"service/default/bar", "node/foo", "unknown/default/foo"} {
+		_, err := recorder.reference(resource)
+		assert.Error(t, err, resource)
+	}
+}
+
+func TestKubeEventRecorderEvent(t *testing.T) {
+	service := &unstructured.Unstructured{}
+	service.SetAPIVersion("v1")
+	service.SetKind("Service")
+	service.SetNamespace("default")
+	service.SetName("foo")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fakeRecorder := record.NewFakeRecorder(10)
+	recorder := &kubeEventRecorder{
+		ctx:      ctx,
+		client:   fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme(), service),
+		recorder: fakeRecorder,
+		limiter:  flowcontrol.NewTokenBucketRateLimiter(eventsPerSecond, eventsBurst),
+		events:   make(chan kubeEvent, eventsBurst),
+	}
+	go recorder.run()
+
+	// the resources are looked up in the background, events of unknown resources are dropped
+	recorder.Event("service/default/bar", "Normal", reasonRecordCreated, "Created bar")
+	recorder.Event("service/default/foo", "Normal", reasonRecordCreated, "Created foo")
+	select {
+	case event := <-fakeRecorder.Events:
+		assert.Equal(t, "Normal DNSRecordCreated Created foo", event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event wasn't recorded")
+	}
+}
+
+func TestKubeEventRecorderEventResource(t *testing.T) {
+	// ingresses are looked up in the version the ingress source reads them in
+	ingressGVR := schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"}
+	recorder := &kubeEventRecorder{ingressGVR: ingressGVR}
+	gvr, ok := recorder.eventResource("ingress")
+	assert.True(t, ok)
+	assert.Equal(t, ingressGVR, gvr)
+
+	gvr, ok = recorder.eventResource("service")
+	assert.True(t, ok)
+	assert.Equal(t, "services", gvr.Resource)
+
+	_, ok = recorder.eventResource("unknown")
+	assert.False(t, ok)
+
+	// without an ingress source, there are no events on ingresses
+	_, ok = (&kubeEventRecorder{}).eventResource("ingress")
+	assert.False(t, ok)
+}
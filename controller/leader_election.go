@@ -0,0 +1,210 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var (
+	leaderElectionIsLeader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "leader_election_is_leader",
+			Help:      "Whether this instance currently holds the leader lease (1) or is a standby (0).",
+		},
+	)
+	leaderElectionTransitionsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "leader_election_transitions_total",
+			Help:      "Number of times this instance acquired the leader lease.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(leaderElectionIsLeader)
+	prometheus.MustRegister(leaderElectionTransitionsTotal)
+}
+
+// LeaderElectionConfig holds the settings for electing a single active instance
+// among multiple replicas using a coordination.k8s.io Lease.
+type LeaderElectionConfig struct {
+	// Client is used to read and update the Lease object
+	Client kubernetes.Interface
+	// Namespace and Name identify the Lease object
+	Namespace string
+	Name      string
+	// Identity is the unique name of this instance, usually the pod name
+	Identity string
+	// LeaseDuration is how long standbys wait before forcefully acquiring leadership
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries refreshing the lease before giving up
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval between attempts to acquire or renew the lease
+	RetryPeriod time.Duration
+}
+
+// LeaderElector runs a function only while this instance holds the leader lease.
+// Standby instances keep waiting for the lease and take over once the leader
+// releases it or stops renewing it.
+type LeaderElector struct {
+	elector  *leaderelection.LeaderElector
+	watchdog *leaderelection.HealthzAdaptor
+
+	// run is invoked with a context that is canceled when leadership is lost
+	run     func(ctx context.Context)
+	running sync.WaitGroup
+	// termLock orders the callbacks joining running before Run waits for them
+	termLock sync.Mutex
+}
+
+// NewLeaderElector returns a LeaderElector for the given configuration.
+func NewLeaderElector(cfg LeaderElectionConfig) (*LeaderElector, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("leader election requires a kubernetes client")
+	}
+	if cfg.Identity == "" {
+		return nil, errors.New("leader election requires an identity")
+	}
+
+	l := &LeaderElector{
+		// tolerate a renewal being overdue by one more retry before reporting unhealthy
+		watchdog: leaderelection.NewLeaderHealthzAdaptor(cfg.RetryPeriod),
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Namespace: cfg.Namespace,
+				Name:      cfg.Name,
+			},
+			Client: cfg.Client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: cfg.Identity,
+			},
+		},
+		LeaseDuration: cfg.LeaseDuration,
+		RenewDeadline: cfg.RenewDeadline,
+		RetryPeriod:   cfg.RetryPeriod,
+		// give up the lease on shutdown so that a standby can take over right away
+		ReleaseOnCancel: true,
+		WatchDog:        l.watchdog,
+		Name:            cfg.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: l.onStartedLeading,
+			OnStoppedLeading: l.onStoppedLeading,
+			OnNewLeader: func(identity string) {
+				if identity != cfg.Identity {
+					log.Infof("Current leader is %s", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.elector = elector
+
+	return l, nil
+}
+
+// Run takes part in the leader election until the context is canceled and
+// calls run every time this instance becomes the leader.
+func (l *LeaderElector) Run(ctx context.Context, run func(ctx context.Context)) {
+	l.run = run
+	for {
+		l.elector.Run(ctx)
+		// the elector starts the callback in a goroutine and cancels its context once it
+		// returns, so a callback that hasn't joined running by now won't start anymore
+		l.termLock.Lock()
+		l.termLock.Unlock() // nolint: staticcheck
+		// make sure the previous term has fully stopped before competing again
+		l.running.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			log.Info("Lost leadership, waiting to be elected again")
+		}
+	}
+}
+
+// IsLeader returns true if this instance currently holds the leader lease.
+func (l *LeaderElector) IsLeader() bool {
+	return l.elector.IsLeader()
+}
+
+// GetLeader returns the identity of the current leader.
+func (l *LeaderElector) GetLeader() string {
+	return l.elector.GetLeader()
+}
+
+// Check reports an error when this instance is the leader but failed to renew
+// its lease in time, which is the case when it may be stuck.
+func (l *LeaderElector) Check(req *http.Request) error {
+	return l.watchdog.Check(req)
+}
+
+func (l *LeaderElector) onStartedLeading(ctx context.Context) {
+	l.termLock.Lock()
+	if ctx.Err() != nil {
+		// the term ended before the callback was scheduled
+		l.termLock.Unlock()
+		return
+	}
+	l.running.Add(1)
+	l.termLock.Unlock()
+	defer l.running.Done()
+
+	log.Info("Acquired leadership, starting reconciliation")
+	leaderElectionIsLeader.Set(1)
+	leaderElectionTransitionsTotal.Inc()
+
+	l.run(ctx)
+}
+
+func (l *LeaderElector) onStoppedLeading() {
+	leaderElectionIsLeader.Set(0)
+}
+
+// RunWithLeaderElection runs the reconciliation loop only while the given
+// LeaderElector holds leadership. A new leader reconciles right away.
+func (c *Controller) RunWithLeaderElection(ctx context.Context, l *LeaderElector) {
+	l.Run(ctx, func(ctx context.Context) {
+		c.nextRunAtMux.Lock()
+		c.nextRunAt = time.Now()
+		c.nextRunAtMux.Unlock()
+		c.Status.Start(time.Now())
+		c.Run(ctx)
+	})
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testLeaderElectionConfig(identity string) LeaderElectionConfig {
+	return LeaderElectionConfig{
+		Namespace:     "default",
+		Name:          "external-dns",
+		Identity:      identity,
+		LeaseDuration: 3 * time.Second,
+		RenewDeadline: 2 * time.Second,
+		RetryPeriod:   100 * time.Millisecond,
+	}
+}
+
+func TestNewLeaderElectorValidation(t *testing.T) {
+	_, err := NewLeaderElector(testLeaderElectionConfig("replica-1"))
+	assert.Error(t, err)
+
+	cfg := testLeaderElectionConfig("")
+	cfg.Client = fake.NewSimpleClientset()
+	_, err = NewLeaderElector(cfg)
+	assert.Error(t, err)
+}
+
+func TestLeaderElectorRunsOnlyWhileLeading(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	cfg := testLeaderElectionConfig("replica-1")
+	cfg.Client = client
+	elector, err := NewLeaderElector(cfg)
+	require.NoError(t, err)
+	assert.False(t, elector.IsLeader())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		elector.Run(ctx, func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+		})
+		close(done)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("leader was not elected")
+	}
+	assert.True(t, elector.IsLeader())
+	assert.Equal(t, "replica-1", elector.GetLeader())
+
+	lease, err := client.CoordinationV1().Leases("default").Get(context.Background(), "external-dns", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "replica-1", *lease.Spec.HolderIdentity)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("leader election did not stop")
+	}
+}
+
+func TestLeaderElectorStandby(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	leaderCfg := testLeaderElectionConfig("replica-1")
+	leaderCfg.Client = client
+	leader, err := NewLeaderElector(leaderCfg)
+	require.NoError(t, err)
+
+	standbyCfg := testLeaderElectionConfig("replica-2")
+	standbyCfg.Client = client
+	standby, err := NewLeaderElector(standbyCfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leading := make(chan struct{})
+	go leader.Run(ctx, func(ctx context.Context) {
+		close(leading)
+		<-ctx.Done()
+	})
+	select {
+	case <-leading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("leader was not elected")
+	}
+
+	standbyRuns := make(chan struct{}, 1)
+	go standby.Run(ctx, func(ctx context.Context) {
+		standbyRuns <- struct{}{}
+		<-ctx.Done()
+	})
+
+	select {
+	case <-standbyRuns:
+		t.Fatal("standby must not run while the leader holds the lease")
+	case <-time.After(500 * time.Millisecond):
+	}
+	assert.False(t, standby.IsLeader())
+	assert.NoError(t, standby.Check(nil))
+}
+
+func TestLeaderElectorLateCallback(t *testing.T) {
+	cfg := testLeaderElectionConfig("replica-1")
+	cfg.Client = fake.NewSimpleClientset()
+	elector, err := NewLeaderElector(cfg)
+	require.NoError(t, err)
+
+	ran := false
+	elector.run = func(ctx context.Context) { ran = true }
+
+	// the callback of a term that already ended neither runs nor joins the running terms
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	elector.onStartedLeading(ctx)
+	elector.running.Wait()
+	assert.False(t, ran)
+
+	elector.onStartedLeading(context.Background())
+	assert.True(t, ran)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// reportResults reports the outcome of the synchronization of every desired endpoint to the result reporters:
+// the targets published after the changes were applied, who keeps the record from being published and the
+// error of the provider if the changes of the record failed
+func (c *Controller) reportResults(ctx context.Context, p *plan.Plan, failed map[*endpoint.Endpoint]error) {
+	if len(c.ResultReporters) == 0 {
+		return
+	}
+
+	current := map[string]*endpoint.Endpoint{}
+	for _, ep := range p.Current {
+		current[resultKey(ep)] = ep
+	}
+	changed := map[string]*endpoint.Endpoint{}
+	for _, eps := range [][]*endpoint.Endpoint{p.Changes.Create, p.Changes.UpdateNew} {
+		for _, ep := range eps {
+			changed[resultKey(ep)] = ep
+		}
+	}
+	blocked := map[*endpoint.Endpoint]*endpoint.Endpoint{}
+	if c.OwnerID != "" {
+		blockedOld, blockedNew := registry.BlockedUpdates(c.OwnerID, p.Changes)
+		for i, ep := range blockedNew {
+			blocked[ep] = blockedOld[i]
+		}
+	}
+
+	results := make([]source.EndpointResult, 0, len(p.Desired))
+	for _, desired := range p.Desired {
+		key := resultKey(desired)
+		result := source.EndpointResult{Endpoint: desired}
+		cur, exists := current[key]
+		if exists {
+			result.Targets = cur.Targets
+		}
+		change, ok := changed[key]
+		switch {
+		case ok && blocked[change] != nil:
+			result.Conflict = ownerConflict(blocked[change])
+		case ok && failed[change] != nil:
+			result.Err = failed[change]
+		case ok:
+			result.Targets = change.Targets
+		case exists && c.OwnerID != "" && cur.Labels[endpoint.OwnerLabelKey] != c.OwnerID:
+			result.Conflict = ownerConflict(cur)
+		}
+		// the record is published for the resource which won the conflict resolution
+		winner := cur
+		if ok {
+			winner = change
+		}
+		if result.Conflict == "" && winner != nil {
+			resource := winner.Labels[endpoint.ResourceLabelKey]
+			if resource != "" && resource != desired.Labels[endpoint.ResourceLabelKey] {
+				result.Conflict = fmt.Sprintf("claimed by %s", resource)
+			}
+		}
+		results = append(results, result)
+	}
+
+	for _, reporter := range c.ResultReporters {
+		reporter.ReportResults(ctx, results)
+	}
+}
+
+// ownerConflict describes the conflict with the owner of a record
+func ownerConflict(ep *endpoint.Endpoint) string {
+	if owner := ep.Labels[endpoint.OwnerLabelKey]; owner != "" {
+		return fmt.Sprintf("owned by %q", owner)
+	}
+	return "not managed by ExternalDNS"
+}
+
+// resultKey identifies the record of an endpoint
+func resultKey(ep *endpoint.Endpoint) string {
+	return strings.ToLower(strings.TrimSuffix(ep.DNSName, ".")) + "/" + ep.SetIdentifier + "/" + ep.RecordType
+}
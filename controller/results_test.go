@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// fakeResultReporter collects the reported results by resource
+type fakeResultReporter struct {
+	results map[string]source.EndpointResult
+}
+
+func (r *fakeResultReporter) ReportResults(ctx context.Context, results []source.EndpointResult) {
+	r.results = map[string]source.EndpointResult{}
+	for _, result := range results {
+		r.results[result.Endpoint.Labels[endpoint.ResourceLabelKey]] = result
+	}
+}
+
+func TestRunOnceReportsResults(t *testing.T) {
+	p := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := newEventsTestController(p, r)
+	reporter := &fakeResultReporter{}
+	ctrl.ResultReporters = []source.ResultReporter{reporter}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, reporter.results, 3)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, reporter.results["ingress/default/new"].Targets)
+	assert.Empty(t, reporter.results["ingress/default/new"].Conflict)
+	assert.Equal(t, endpoint.Targets{"5.6.7.8"}, reporter.results["service/default/owned"].Targets)
+	assert.Empty(t, reporter.results["service/default/owned"].Conflict)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, reporter.results["service/default/foreign"].Targets)
+	assert.Equal(t, `owned by "other"`, reporter.results["service/default/foreign"].Conflict)
+}
+
+func TestRunOnceReportsProviderErrorResults(t *testing.T) {
+	p := &failingMockProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := newEventsTestController(&p.filteredMockProvider, r)
+	reporter := &fakeResultReporter{}
+	ctrl.ResultReporters = []source.ResultReporter{reporter}
+
+	require.Error(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, reporter.results, 3)
+	assert.Empty(t, reporter.results["ingress/default/new"].Targets)
+	assert.EqualError(t, reporter.results["ingress/default/new"].Err, "provider error")
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, reporter.results["service/default/owned"].Targets)
+	assert.EqualError(t, reporter.results["service/default/owned"].Err, "provider error")
+	assert.NoError(t, reporter.results["service/default/foreign"].Err)
+	assert.Equal(t, `owned by "other"`, reporter.results["service/default/foreign"].Conflict)
+}
+
+func TestRunOnceReportsResolvedConflicts(t *testing.T) {
+	mockSource := new(testutils.MockSource)
+	mockSource.On("Endpoints").Return([]*endpoint.Endpoint{
+		newEndpointWithResource("shared.example.org", "1.2.3.4", "", "crd/default/winner"),
+		newEndpointWithResource("shared.example.org", "5.6.7.8", "", "crd/default/loser"),
+	}, nil)
+	p := &filteredMockProvider{
+		RecordsStore: []*endpoint.Endpoint{
+			newEndpointWithResource("shared.example.org", "1.2.3.4", "owner", "crd/default/winner"),
+		},
+	}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	reporter := &fakeResultReporter{}
+	ctrl := &Controller{
+		Source:             mockSource,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		OwnerID:            "owner",
+		ResultReporters:    []source.ResultReporter{reporter},
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, reporter.results, 2)
+	assert.Empty(t, reporter.results["crd/default/winner"].Conflict)
+	assert.Equal(t, "claimed by crd/default/winner", reporter.results["crd/default/loser"].Conflict)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, reporter.results["crd/default/loser"].Targets)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// errNotSynced is reported as long as no synchronization succeeded
+var errNotSynced = errors.New("no successful synchronization yet")
+
+// Status tracks the outcome of the synchronizations for the health, readiness and status endpoints.
+// A nil Status ignores all updates.
+type Status struct {
+	mux sync.Mutex
+	// started is when the synchronizations started, staleness is measured from it until the first successful one
+	started time.Time
+	// lastSync is the time of the last successful synchronization
+	lastSync time.Time
+	// lastError and lastErrorTime describe the last failed synchronization
+	lastError     error
+	lastErrorTime time.Time
+	// lastPlan summarizes the changes calculated by the last synchronization
+	lastPlan *PlanSummary
+	// sourceEndpoints is the number of endpoints last returned by every source
+	sourceEndpoints map[string]int
+}
+
+// PlanSummary counts the changes calculated by a synchronization.
+type PlanSummary struct {
+	Time   time.Time `json:"time"`
+	Create int       `json:"create"`
+	Update int       `json:"update"`
+	Delete int       `json:"delete"`
+}
+
+// StatusReport is the state of the synchronizations served by the status endpoint.
+type StatusReport struct {
+	LastSuccessfulSync *time.Time     `json:"lastSuccessfulSync,omitempty"`
+	LastError          string         `json:"lastError,omitempty"`
+	LastErrorTime      *time.Time     `json:"lastErrorTime,omitempty"`
+	LastPlan           *PlanSummary   `json:"lastPlan,omitempty"`
+	SourceEndpoints    map[string]int `json:"sourceEndpoints"`
+}
+
+// NewStatus creates a Status for synchronizations starting at the given time.
+func NewStatus(now time.Time) *Status {
+	return &Status{started: now, sourceEndpoints: map[string]int{}}
+}
+
+// Start restarts measuring the staleness at the given time, which is when this instance becomes the leader.
+func (s *Status) Start(now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.started = now
+}
+
+// SetSourceEndpoints records the number of endpoints returned by the named source.
+func (s *Status) SetSourceEndpoints(name string, count int) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.sourceEndpoints[name] = count
+}
+
+func (s *Status) recordPlan(now time.Time, changes *plan.Changes) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.lastPlan = &PlanSummary{
+		Time:   now,
+		Create: len(changes.Create),
+		Update: len(changes.UpdateNew),
+		Delete: len(changes.Delete),
+	}
+}
+
+func (s *Status) recordRun(now time.Time, err error) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err != nil {
+		s.lastError = err
+		s.lastErrorTime = now
+		return
+	}
+	s.lastSync = now
+}
+
+// Ready returns an error until the first successful synchronization.
+func (s *Status) Ready() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if !s.lastSync.IsZero() {
+		return nil
+	}
+	if s.lastError != nil {
+		return fmt.Errorf("%v, last error: %v", errNotSynced, s.lastError)
+	}
+	return errNotSynced
+}
+
+// Stale returns an error when neither a synchronization succeeded nor the synchronizations started
+// within maxAge. Synchronizations never become stale when maxAge is zero.
+func (s *Status) Stale(now time.Time, maxAge time.Duration) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if maxAge <= 0 {
+		return nil
+	}
+	since := s.started
+	if s.lastSync.After(since) {
+		since = s.lastSync
+	}
+	if age := now.Sub(since); age > maxAge {
+		return fmt.Errorf("no successful synchronization in %s", age.Round(time.Second))
+	}
+	return nil
+}
+
+// Report returns the state of the synchronizations.
+func (s *Status) Report() StatusReport {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	report := StatusReport{SourceEndpoints: map[string]int{}}
+	if !s.lastSync.IsZero() {
+		lastSync := s.lastSync
+		report.LastSuccessfulSync = &lastSync
+	}
+	if s.lastError != nil {
+		lastErrorTime := s.lastErrorTime
+		report.LastError = s.lastError.Error()
+		report.LastErrorTime = &lastErrorTime
+	}
+	if s.lastPlan != nil {
+		lastPlan := *s.lastPlan
+		report.LastPlan = &lastPlan
+	}
+	for name, count := range s.sourceEndpoints {
+		report.SourceEndpoints[name] = count
+	}
+	return report
+}
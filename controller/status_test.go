@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestStatusReady(t *testing.T) {
+	now := time.Now()
+	status := NewStatus(now)
+	assert.Equal(t, errNotSynced, status.Ready())
+
+	status.recordRun(now, errors.New("provider error"))
+	err := status.Ready()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "provider error")
+
+	status.recordRun(now.Add(time.Minute), nil)
+	assert.NoError(t, status.Ready())
+
+	// failures after the first successful synchronization don't make it unready
+	status.recordRun(now.Add(2*time.Minute), errors.New("provider error"))
+	assert.NoError(t, status.Ready())
+}
+
+func TestStatusStale(t *testing.T) {
+	now := time.Now()
+	status := NewStatus(now)
+
+	// disabled
+	assert.NoError(t, status.Stale(now.Add(time.Hour), 0))
+
+	// measured from the start until the first successful synchronization
+	assert.NoError(t, status.Stale(now.Add(2*time.Minute), 3*time.Minute))
+	assert.Error(t, status.Stale(now.Add(4*time.Minute), 3*time.Minute))
+
+	status.recordRun(now.Add(3*time.Minute), nil)
+	assert.NoError(t, status.Stale(now.Add(4*time.Minute), 3*time.Minute))
+
+	status.recordRun(now.Add(5*time.Minute), errors.New("provider error"))
+	assert.NoError(t, status.Stale(now.Add(5*time.Minute), 3*time.Minute))
+	assert.Error(t, status.Stale(now.Add(7*time.Minute), 3*time.Minute))
+
+	// a new leader is given time to synchronize
+	status.Start(now.Add(10 * time.Minute))
+	assert.NoError(t, status.Stale(now.Add(12*time.Minute), 3*time.Minute))
+	assert.Error(t, status.Stale(now.Add(14*time.Minute), 3*time.Minute))
+}
+
+func TestStatusReport(t *testing.T) {
+	now := time.Now()
+	status := NewStatus(now)
+	report := status.Report()
+	assert.Nil(t, report.LastSuccessfulSync)
+	assert.Empty(t, report.LastError)
+	assert.Nil(t, report.LastPlan)
+	assert.Empty(t, report.SourceEndpoints)
+
+	status.SetSourceEndpoints("service", 2)
+	status.SetSourceEndpoints("ingress", 1)
+	status.recordPlan(now, &plan.Changes{
+		Create:    []*endpoint.Endpoint{{DNSName: "create-record"}},
+		UpdateOld: []*endpoint.Endpoint{{DNSName: "update-record"}},
+		UpdateNew: []*endpoint.Endpoint{{DNSName: "update-record"}},
+	})
+	status.recordRun(now, nil)
+	status.recordRun(now.Add(time.Minute), errors.New("provider error"))
+
+	report = status.Report()
+	require.NotNil(t, report.LastSuccessfulSync)
+	assert.Equal(t, now, *report.LastSuccessfulSync)
+	assert.Equal(t, "provider error", report.LastError)
+	require.NotNil(t, report.LastErrorTime)
+	assert.Equal(t, now.Add(time.Minute), *report.LastErrorTime)
+	assert.Equal(t, &PlanSummary{Time: now, Create: 1, Update: 1}, report.LastPlan)
+	assert.Equal(t, map[string]int{"service": 2, "ingress": 1}, report.SourceEndpoints)
+}
+
+func TestRunOnceRecordsStatus(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		{DNSName: "create-record", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+	}, nil)
+
+	r, err := registry.NewNoopRegistry(&filteredMockProvider{})
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Status:             NewStatus(time.Now()),
+	}
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	report := ctrl.Status.Report()
+	assert.NotNil(t, report.LastSuccessfulSync)
+	assert.Empty(t, report.LastError)
+	assert.Equal(t, 1, report.LastPlan.Create)
+	assert.NoError(t, ctrl.Status.Ready())
+}
@@ -21,12 +21,15 @@ import (
 	"sort"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
 	// RecordTypeA is a RecordType enum value
 	RecordTypeA = "A"
+	// RecordTypeAAAA is a RecordType enum value
+	RecordTypeAAAA = "AAAA"
 	// RecordTypeCNAME is a RecordType enum value
 	RecordTypeCNAME = "CNAME"
 	// RecordTypeTXT is a RecordType enum value
@@ -205,6 +208,55 @@ type DNSEndpointStatus struct {
 	// The generation observed by the external-dns controller.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// The conditions of the records of the DNSEndpoint after the last synchronization.
+	// +optional
+	Conditions []DNSEndpointCondition `json:"conditions,omitempty"`
+	// The records of the endpoints as published by the DNS provider.
+	// +optional
+	Endpoints []DNSEndpointRecordStatus `json:"endpoints,omitempty"`
+}
+
+// DNSEndpointConditionType is the type of a condition of a DNSEndpoint
+type DNSEndpointConditionType string
+
+const (
+	// DNSEndpointReady is true when all records of a DNSEndpoint are published with their targets
+	DNSEndpointReady DNSEndpointConditionType = "Ready"
+	// DNSEndpointConflict is true when a record of a DNSEndpoint is owned or claimed by someone else
+	DNSEndpointConflict DNSEndpointConditionType = "Conflict"
+	// DNSEndpointProviderError is true when the DNS provider rejected the changes of a record of a DNSEndpoint
+	DNSEndpointProviderError DNSEndpointConditionType = "ProviderError"
+)
+
+// DNSEndpointCondition describes an aspect of the state of the records of a DNSEndpoint
+type DNSEndpointCondition struct {
+	// Type of the condition, one of Ready, Conflict and ProviderError.
+	Type DNSEndpointConditionType `json:"type"`
+	// Status of the condition, one of True, False and Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// The last time the condition changed its status.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The reason for the last transition in CamelCase.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// A human readable message describing the last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DNSEndpointRecordStatus is the observed state of the record of an endpoint of a DNSEndpoint
+type DNSEndpointRecordStatus struct {
+	// The hostname of the DNS record
+	DNSName string `json:"dnsName"`
+	// RecordType type of record, e.g. CNAME, A, SRV, TXT etc
+	RecordType string `json:"recordType,omitempty"`
+	// Identifier to distinguish multiple records with the same name and type
+	// +optional
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+	// The targets of the record published by the DNS provider, empty if the record doesn't exist
+	// +optional
+	Targets Targets `json:"targets,omitempty"`
 }
 
 // +genclient
@@ -41,6 +41,21 @@ const (
 
 	// DualstackLabelKey is the name of the label that identifies dualstack endpoints
 	DualstackLabelKey = "dualstack"
+
+	// OwnershipPriorityLabelKey is the name of the label that ranks resources which want to acquire the same DNS name
+	OwnershipPriorityLabelKey = "ownership-priority"
+	// CreationTimestampLabelKey is the name of the label that stores when the k8s resource which wants to acquire the DNS name was created
+	CreationTimestampLabelKey = "resource-created"
+	// MissingSinceLabelKey is the name of the label that stores when an owned Endpoint first disappeared from the sources
+	MissingSinceLabelKey = "missing-since"
+	// OrphanedOwnershipLabelKey is the name of the label that marks an ownership record without a matching record,
+	// its value is the name of the missing record
+	OrphanedOwnershipLabelKey = "orphaned-ownership"
+	// AdoptLabelKey is the name of the label that allows the k8s resource to adopt an existing record without owner
+	AdoptLabelKey = "adopt"
+	// PreviousOwnerLabelKey is the name of the label that marks an Endpoint owned by the previous owner id,
+	// its value is the previous owner id
+	PreviousOwnerLabelKey = "previous-owner"
 )
 
 // Labels store metadata related to the endpoint
@@ -55,9 +70,21 @@ func NewLabels() Labels {
 // NewLabelsFromString constructs endpoints labels from a provided format string
 // if heritage set to another value is found then error is returned
 // no heritage automatically assumes is not owned by external-dns and returns invalidHeritage error
+// encrypted or signed labels can only be read with NewLabelsFromStringWithCodec
 func NewLabelsFromString(labelText string) (Labels, error) {
+	return NewLabelsFromStringWithCodec(labelText, nil, LabelsRecord{})
+}
+
+// NewLabelsFromStringWithCodec constructs endpoints labels like NewLabelsFromString and transparently
+// decrypts and verifies labels protected by the codec for the record they were read from, a nil codec
+// only reads labels in plaintext
+func NewLabelsFromStringWithCodec(labelText string, codec *LabelsCodec, record LabelsRecord) (Labels, error) {
 	endpointLabels := map[string]string{}
 	labelText = strings.Trim(labelText, "\"") // drop quotes
+	labelText, err := codec.decode(labelText, record)
+	if err != nil {
+		return nil, err
+	}
 	tokens := strings.Split(labelText, ",")
 	foundExternalDNSHeritage := false
 	for _, token := range tokens {
@@ -88,6 +115,12 @@ func NewLabelsFromString(labelText string) (Labels, error) {
 // Serialize transforms endpoints labels into a external-dns recognizable format string
 // withQuotes adds additional quotes
 func (l Labels) Serialize(withQuotes bool) string {
+	return l.SerializeWithCodec(withQuotes, nil, LabelsRecord{})
+}
+
+// SerializeWithCodec transforms endpoints labels like Serialize and encrypts and/or signs them
+// with the codec for the record they are stored in, a nil codec serializes them in plaintext
+func (l Labels) SerializeWithCodec(withQuotes bool, codec *LabelsCodec, record LabelsRecord) string {
 	var tokens []string
 	tokens = append(tokens, fmt.Sprintf("heritage=%s", heritage))
 	var keys []string
@@ -99,8 +132,12 @@ func (l Labels) Serialize(withQuotes bool) string {
 	for _, key := range keys {
 		tokens = append(tokens, fmt.Sprintf("%s/%s=%s", heritage, key, l[key]))
 	}
+	text := strings.Join(tokens, ",")
+	if codec != nil {
+		text = codec.encode(tokens, record)
+	}
 	if withQuotes {
-		return fmt.Sprintf("\"%s\"", strings.Join(tokens, ","))
+		return fmt.Sprintf("\"%s\"", text)
 	}
-	return strings.Join(tokens, ",")
+	return text
 }
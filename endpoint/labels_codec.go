@@ -0,0 +1,210 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrMissingLabelsKey is returned when encrypted or signed labels are read without a key
+	ErrMissingLabelsKey = errors.New("labels are encrypted or signed but no key is configured")
+	// ErrUnprotectedLabels is returned when labels aren't encrypted or signed as required
+	ErrUnprotectedLabels = errors.New("labels are not encrypted or signed as required")
+	// ErrInvalidLabelsSignature is returned when the signature or encryption of labels doesn't match the key
+	ErrInvalidLabelsSignature = errors.New("labels signature or encryption does not match the key")
+)
+
+const (
+	// labelsKeySize is the size of the key of a LabelsCodec, AES-256 is used for the encryption
+	labelsKeySize = 32
+	// labelsSignatureSize is the size the HMAC-SHA256 signature is truncated to, keeping the TXT record short
+	labelsSignatureSize = 16
+
+	encryptedLabelsKey = "encrypted"
+	signatureLabelsKey = "signature"
+)
+
+// LabelsCodec encrypts and/or signs serialized labels, so that ownership records in public zones
+// neither reveal the resources behind the records nor can be forged without the key. The labels
+// are bound to the record holding them, so they can't be copied to the ownership record of another name.
+//
+// The encryption is deterministic: the nonce is derived from the labels, so the serialized
+// labels can be reconstructed from the labels to update or delete an existing TXT record.
+type LabelsCodec struct {
+	encrypt bool
+	sign    bool
+	// allowUnprotected accepts labels which aren't encrypted or signed, to switch existing records over
+	allowUnprotected bool
+
+	aead     cipher.AEAD
+	nonceKey []byte
+	signKey  []byte
+}
+
+// NewLabelsCodec returns a LabelsCodec for the key, which must be 32 bytes, either raw or base64 encoded
+func NewLabelsCodec(key []byte, encrypt, sign, allowUnprotected bool) (*LabelsCodec, error) {
+	if !encrypt && !sign {
+		return nil, errors.New("labels codec must encrypt or sign")
+	}
+	key, err := parseLabelsKey(key)
+	if err != nil {
+		return nil, err
+	}
+	// separate keys are derived for each purpose
+	block, err := aes.NewCipher(deriveLabelsKey(key, "encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &LabelsCodec{
+		encrypt:          encrypt,
+		sign:             sign,
+		allowUnprotected: allowUnprotected,
+		aead:             aead,
+		nonceKey:         deriveLabelsKey(key, "nonce"),
+		signKey:          deriveLabelsKey(key, "signature"),
+	}, nil
+}
+
+// LabelsRecord identifies the record the labels are stored in
+type LabelsRecord struct {
+	DNSName       string
+	RecordType    string
+	SetIdentifier string
+}
+
+// bytes returns the record as the additional data of the encryption and signature
+func (r LabelsRecord) bytes() []byte {
+	dnsName := strings.TrimSuffix(strings.ToLower(r.DNSName), ".")
+	return []byte(strings.Join([]string{dnsName, r.RecordType, r.SetIdentifier}, "\x00"))
+}
+
+func parseLabelsKey(key []byte) ([]byte, error) {
+	key = bytes.TrimSpace(key)
+	if len(key) == labelsKeySize {
+		return key, nil
+	}
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(key)))
+	n, err := base64.StdEncoding.Decode(decoded, key)
+	if err == nil && n == labelsKeySize {
+		return decoded[:n], nil
+	}
+	return nil, fmt.Errorf("labels key must be %d bytes, raw or base64 encoded", labelsKeySize)
+}
+
+func deriveLabelsKey(key []byte, purpose string) []byte {
+	return labelsMAC(key, []byte(purpose))
+}
+
+// encode protects the serialized label tokens which follow the heritage token, bound to the record holding them
+func (c *LabelsCodec) encode(tokens []string, record LabelsRecord) string {
+	text := strings.Join(tokens, ",")
+	if c.encrypt {
+		plaintext := []byte(strings.Join(tokens[1:], ","))
+		// the nonce depends on the record, too, since reusing it with other additional data would reveal the authentication key
+		nonce := labelsMAC(c.nonceKey, append(append(record.bytes(), 0), plaintext...))[:c.aead.NonceSize()]
+		sealed := c.aead.Seal(append([]byte{}, nonce...), nonce, plaintext, record.bytes())
+		text = fmt.Sprintf("%s,%s/%s=%s", tokens[0], heritage, encryptedLabelsKey, base64.RawURLEncoding.EncodeToString(sealed))
+	}
+	if c.sign {
+		text = fmt.Sprintf("%s,%s/%s=%s", text, heritage, signatureLabelsKey, c.signature(text, record))
+	}
+	return text
+}
+
+// decode verifies and decrypts the labels text read from the record and returns it in plaintext
+func (c *LabelsCodec) decode(labelText string, record LabelsRecord) (string, error) {
+	text, signature := splitLabelsSignature(labelText)
+	encrypted, isEncrypted := encryptedLabels(text)
+	if signature == "" && !isEncrypted {
+		if c != nil && !c.allowUnprotected {
+			return "", ErrUnprotectedLabels
+		}
+		return labelText, nil
+	}
+	if c == nil {
+		return "", ErrMissingLabelsKey
+	}
+
+	if signature != "" {
+		if !hmac.Equal([]byte(signature), []byte(c.signature(text, record))) {
+			return "", ErrInvalidLabelsSignature
+		}
+	} else if c.sign && !c.allowUnprotected {
+		return "", ErrUnprotectedLabels
+	}
+	if !isEncrypted {
+		if c.encrypt && !c.allowUnprotected {
+			return "", ErrUnprotectedLabels
+		}
+		return text, nil
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidLabelsSignature
+	}
+	nonce := sealed[:c.aead.NonceSize()]
+	plaintext, err := c.aead.Open(nil, nonce, sealed[c.aead.NonceSize():], record.bytes())
+	if err != nil {
+		return "", ErrInvalidLabelsSignature
+	}
+	return fmt.Sprintf("heritage=%s,%s", heritage, plaintext), nil
+}
+
+func (c *LabelsCodec) signature(text string, record LabelsRecord) string {
+	return base64.RawURLEncoding.EncodeToString(labelsMAC(c.signKey, append(append(record.bytes(), 0), text...))[:labelsSignatureSize])
+}
+
+func labelsMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// splitLabelsSignature separates the signature token, which is always the last one, from the signed text
+func splitLabelsSignature(labelText string) (text, signature string) {
+	separator := fmt.Sprintf(",%s/%s=", heritage, signatureLabelsKey)
+	i := strings.LastIndex(labelText, separator)
+	if i < 0 {
+		return labelText, ""
+	}
+	return labelText[:i], labelText[i+len(separator):]
+}
+
+// encryptedLabels returns the encrypted labels if the text holds them
+func encryptedLabels(text string) (string, bool) {
+	prefix := fmt.Sprintf("%s/%s=", heritage, encryptedLabelsKey)
+	for _, token := range strings.Split(text, ",") {
+		if strings.HasPrefix(token, prefix) {
+			return strings.TrimPrefix(token, prefix), true
+		}
+	}
+	return "", false
+}
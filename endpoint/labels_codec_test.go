@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testLabelsKey  = []byte("0123456789abcdef0123456789abcdef")
+	otherLabelsKey = []byte("fedcba9876543210fedcba9876543210")
+
+	testLabelsRecord = LabelsRecord{DNSName: "foo.example.org", RecordType: RecordTypeA}
+)
+
+func newTestLabelsCodec(t *testing.T, key []byte, encrypt, sign, allowUnprotected bool) *LabelsCodec {
+	codec, err := NewLabelsCodec(key, encrypt, sign, allowUnprotected)
+	require.NoError(t, err)
+	return codec
+}
+
+func TestNewLabelsCodec(t *testing.T) {
+	_, err := NewLabelsCodec(testLabelsKey, false, false, false)
+	assert.Error(t, err)
+
+	_, err = NewLabelsCodec([]byte("too-short"), true, false, false)
+	assert.Error(t, err)
+
+	_, err = NewLabelsCodec(testLabelsKey, true, true, false)
+	assert.NoError(t, err)
+
+	// base64 encoded keys with a trailing newline, like in a file
+	_, err = NewLabelsCodec([]byte(base64.StdEncoding.EncodeToString(testLabelsKey)+"\n"), true, false, false)
+	assert.NoError(t, err)
+}
+
+func TestLabelsCodecRoundTrip(t *testing.T) {
+	labels := Labels{
+		OwnerLabelKey:    "owner",
+		ResourceLabelKey: "service/default/foo",
+	}
+	for _, tc := range []struct {
+		title   string
+		encrypt bool
+		sign    bool
+	}{
+		{title: "encrypted", encrypt: true},
+		{title: "signed", sign: true},
+		{title: "encrypted and signed", encrypt: true, sign: true},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			codec := newTestLabelsCodec(t, testLabelsKey, tc.encrypt, tc.sign, false)
+			text := labels.SerializeWithCodec(true, codec, testLabelsRecord)
+			assert.True(t, strings.HasPrefix(text, `"heritage=external-dns,`))
+			assert.Equal(t, tc.encrypt, !strings.Contains(text, "service/default/foo"))
+
+			// the serialized labels are stable, so existing TXT records can be reconstructed
+			assert.Equal(t, text, labels.SerializeWithCodec(true, codec, testLabelsRecord))
+
+			decoded, err := NewLabelsFromStringWithCodec(text, codec, testLabelsRecord)
+			require.NoError(t, err)
+			assert.Equal(t, labels, decoded)
+
+			// readers without the key don't recognize the labels
+			_, err = NewLabelsFromString(text)
+			assert.Equal(t, ErrMissingLabelsKey, err)
+
+			_, err = NewLabelsFromStringWithCodec(text, newTestLabelsCodec(t, otherLabelsKey, tc.encrypt, tc.sign, false), testLabelsRecord)
+			assert.Equal(t, ErrInvalidLabelsSignature, err)
+		})
+	}
+}
+
+func TestLabelsCodecForgery(t *testing.T) {
+	codec := newTestLabelsCodec(t, testLabelsKey, false, true, false)
+	text := Labels{OwnerLabelKey: "owner"}.SerializeWithCodec(false, codec, testLabelsRecord)
+
+	forged := strings.Replace(text, "owner=owner", "owner=other", 1)
+	_, err := NewLabelsFromStringWithCodec(forged, codec, testLabelsRecord)
+	assert.Equal(t, ErrInvalidLabelsSignature, err)
+
+	// labels in plaintext are only accepted while switching existing records over
+	plaintext := Labels{OwnerLabelKey: "owner"}.Serialize(false)
+	_, err = NewLabelsFromStringWithCodec(plaintext, codec, testLabelsRecord)
+	assert.Equal(t, ErrUnprotectedLabels, err)
+
+	labels, err := NewLabelsFromStringWithCodec(plaintext, newTestLabelsCodec(t, testLabelsKey, false, true, true), testLabelsRecord)
+	require.NoError(t, err)
+	assert.Equal(t, "owner", labels[OwnerLabelKey])
+
+	// signed labels which aren't encrypted are not accepted when encrypting
+	_, err = NewLabelsFromStringWithCodec(text, newTestLabelsCodec(t, testLabelsKey, true, false, false), testLabelsRecord)
+	assert.Equal(t, ErrUnprotectedLabels, err)
+}
+
+func TestLabelsCodecRecordBinding(t *testing.T) {
+	labels := Labels{OwnerLabelKey: "owner"}
+	for _, tc := range []struct {
+		title   string
+		encrypt bool
+		sign    bool
+	}{
+		{title: "encrypted", encrypt: true},
+		{title: "signed", sign: true},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			codec := newTestLabelsCodec(t, testLabelsKey, tc.encrypt, tc.sign, false)
+			text := labels.SerializeWithCodec(false, codec, testLabelsRecord)
+
+			// the case of the name and a trailing dot don't matter
+			_, err := NewLabelsFromStringWithCodec(text, codec, LabelsRecord{DNSName: "Foo.example.org.", RecordType: RecordTypeA})
+			assert.NoError(t, err)
+
+			// labels copied to the ownership record of another name, record type or set identifier aren't valid
+			for _, record := range []LabelsRecord{
+				{DNSName: "bar.example.org", RecordType: RecordTypeA},
+				{DNSName: "foo.example.org", RecordType: RecordTypeCNAME},
+				{DNSName: "foo.example.org", RecordType: RecordTypeA, SetIdentifier: "eu"},
+			} {
+				_, err := NewLabelsFromStringWithCodec(text, codec, record)
+				assert.Equal(t, ErrInvalidLabelsSignature, err, record)
+			}
+		})
+	}
+}
@@ -18,14 +18,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"sigs.k8s.io/external-dns/controller"
@@ -96,7 +102,33 @@ func main() {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	go serveMetrics(cfg.MetricsAddress)
+	clientGenerator := &source.SingletonClientGenerator{
+		KubeConfig:   cfg.KubeConfig,
+		APIServerURL: cfg.APIServerURL,
+		// If update events are enabled, disable timeout.
+		RequestTimeout: func() time.Duration {
+			if cfg.UpdateEvents {
+				return 0
+			}
+			return cfg.RequestTimeout
+		}(),
+	}
+
+	// the outcome of the synchronizations is served by the health, readiness and status endpoints
+	status := controller.NewStatus(time.Now())
+
+	// the plan and migrate-registry commands exit when done, so they don't serve metrics nor take part in leader election
+	var leaderElector *controller.LeaderElector
+	if cfg.Command == "run" {
+		if cfg.LeaderElection && !cfg.Once {
+			leaderElector, err = newLeaderElector(cfg, clientGenerator)
+			if err != nil {
+				log.Fatalf("failed to set up leader election: %v", err)
+			}
+		}
+
+		go serveMetrics(cfg.MetricsAddress, leaderElector, status, time.Duration(cfg.HealthStaleSyncIntervals)*cfg.Interval)
+	}
 	go handleSigterm(cancel)
 
 	// Create a source.Config from the flags passed by the user.
@@ -109,13 +141,20 @@ func main() {
 		IgnoreHostnameAnnotation:       cfg.IgnoreHostnameAnnotation,
 		IgnoreIngressTLSSpec:           cfg.IgnoreIngressTLSSpec,
 		IgnoreIngressRulesSpec:         cfg.IgnoreIngressRulesSpec,
+		IngressClassNames:              cfg.IngressClassNames,
 		Compatibility:                  cfg.Compatibility,
 		PublishInternal:                cfg.PublishInternal,
 		PublishHostIP:                  cfg.PublishHostIP,
+		PublishPodIPs:                  cfg.PublishPodIPs,
 		AlwaysPublishNotReadyAddresses: cfg.AlwaysPublishNotReadyAddresses,
 		ConnectorServer:                cfg.ConnectorSourceServer,
+		ConnectorCAFile:                cfg.ConnectorSourceCAFile,
+		ConnectorCertFile:              cfg.ConnectorSourceCertFile,
+		ConnectorKeyFile:               cfg.ConnectorSourceKeyFile,
+		ConnectorTokenFile:             cfg.ConnectorSourceTokenFile,
 		CRDSourceAPIVersion:            cfg.CRDSourceAPIVersion,
 		CRDSourceKind:                  cfg.CRDSourceKind,
+		FilePaths:                      cfg.FileSourcePaths,
 		KubeConfig:                     cfg.KubeConfig,
 		APIServerURL:                   cfg.APIServerURL,
 		ServiceTypeFilter:              cfg.ServiceTypeFilter,
@@ -130,21 +169,25 @@ func main() {
 	}
 
 	// Lookup all the selected sources by names and pass them the desired configuration.
-	sources, err := source.ByNames(&source.SingletonClientGenerator{
-		KubeConfig:   cfg.KubeConfig,
-		APIServerURL: cfg.APIServerURL,
-		// If update events are enabled, disable timeout.
-		RequestTimeout: func() time.Duration {
-			if cfg.UpdateEvents {
-				return 0
-			}
-			return cfg.RequestTimeout
-		}(),
-	}, cfg.Sources, sourceCfg)
+	sources, err := source.ByNames(clientGenerator, cfg.Sources, sourceCfg)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Sources like the DNSEndpoint CRD report the outcome of synchronizations on their resources.
+	var resultReporters []source.ResultReporter
+	for _, s := range sources {
+		if reporter, ok := s.(source.ResultReporter); ok {
+			resultReporters = append(resultReporters, reporter)
+		}
+	}
+
+	// Count the endpoints of every source for the status endpoint.
+	for i := range sources {
+		name := cfg.Sources[i]
+		sources[i] = source.NewCountingSource(sources[i], func(count int) { status.SetSourceEndpoints(name, count) })
+	}
+
 	// Combine multiple sources into a single, deduplicated source.
 	endpointsSource := source.NewDedupSource(source.NewMultiSource(sources, sourceCfg.DefaultTargets))
 
@@ -324,35 +367,98 @@ func main() {
 		log.Fatal(err)
 	}
 
-	var r registry.Registry
-	switch cfg.Registry {
-	case "noop":
-		r, err = registry.NewNoopRegistry(p)
-	case "txt":
-		r, err = registry.NewTXTRegistry(p, cfg.TXTPrefix, cfg.TXTSuffix, cfg.TXTOwnerID, cfg.TXTCacheInterval, cfg.TXTWildcardReplacement)
-	case "aws-sd":
-		r, err = registry.NewAWSSDRegistry(p.(*awssd.AWSSDProvider), cfg.TXTOwnerID)
-	default:
-		log.Fatalf("unknown registry: %s", cfg.Registry)
-	}
-
+	r, err := newRegistry(ctx, cfg, p, clientGenerator)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if cfg.Command == "migrate-registry" {
+		runMigrateRegistry(ctx, r, p, cfg, clientGenerator)
+		os.Exit(0)
+	}
+
 	policy, exists := plan.Policies[cfg.Policy]
 	if !exists {
 		log.Fatalf("unknown policy: %s", cfg.Policy)
 	}
+	if cfg.DeletionGracePeriod > 0 {
+		policy = plan.NewDelayedDeletionPolicy(policy, cfg.DeletionGracePeriod)
+	}
+	if cfg.SafetyMaxDeletes > 0 || cfg.SafetyMaxDeletePercentage > 0 || cfg.SafetyMaxUpdates > 0 || cfg.SafetyMaxUpdatePercentage > 0 {
+		overrideFile := func() bool { return false }
+		if cfg.SafetyOverrideFile != "" {
+			overrideFile = plan.SafetyOverrideFile(cfg.SafetyOverrideFile)
+		}
+		policy = &plan.SafetyPolicy{
+			Policy:              policy,
+			OwnerID:             cfg.TXTOwnerID,
+			MaxDeletes:          cfg.SafetyMaxDeletes,
+			MaxDeletePercentage: cfg.SafetyMaxDeletePercentage,
+			MaxUpdates:          cfg.SafetyMaxUpdates,
+			MaxUpdatePercentage: cfg.SafetyMaxUpdatePercentage,
+			Override: func() bool {
+				return cfg.SafetyOverride || overrideFile()
+			},
+		}
+	}
+
+	conflictResolver, exists := plan.ConflictResolvers[cfg.ConflictResolver]
+	if !exists {
+		log.Fatalf("unknown conflict resolver: %s", cfg.ConflictResolver)
+	}
 
 	ctrl := controller.Controller{
 		Source:               endpointsSource,
 		Registry:             r,
 		Policy:               policy,
+		ConflictResolver:     conflictResolver,
 		Interval:             cfg.Interval,
 		DomainFilter:         domainFilter,
 		ManagedRecordTypes:   cfg.ManagedDNSRecordTypes,
 		MinEventSyncInterval: cfg.MinEventSyncInterval,
+		MaxFailureBackoff:    cfg.MaxFailureBackoff,
+		Status:               status,
+	}
+	if len(cfg.AdoptDomains) > 0 {
+		ctrl.AdoptionDomainFilter = endpoint.NewDomainFilter(cfg.AdoptDomains)
+	}
+	// providers which list their zones have the changes of every zone applied separately
+	if zoneLister, ok := p.(provider.ZoneIDNameLister); ok {
+		ctrl.ZoneLister = zoneLister
+	}
+	// nothing is published in dry-run mode, so there are no results worth reporting
+	if !cfg.DryRun {
+		ctrl.ResultReporters = resultReporters
+	}
+	// registries with ownership only apply changes to the records they own
+	if cfg.Registry != "noop" {
+		ctrl.OwnerID = cfg.TXTOwnerID
+	}
+	// events would announce changes which are never applied in dry-run mode
+	if cfg.KubernetesEvents && !cfg.DryRun {
+		kubeClient, err := clientGenerator.KubeClient()
+		if err != nil {
+			log.Fatal(err)
+		}
+		dynamicClient, err := clientGenerator.DynamicKubernetesClient()
+		if err != nil {
+			log.Fatal(err)
+		}
+		// events on ingresses use the version the ingress source reads them in
+		var ingressGVR schema.GroupVersionResource
+		for _, name := range cfg.Sources {
+			if name != "ingress" {
+				continue
+			}
+			if ingressGVR, err = source.IngressGVR(kubeClient); err != nil {
+				log.Fatal(err)
+			}
+		}
+		ctrl.EventRecorder = controller.NewKubeEventRecorder(ctx, kubeClient, dynamicClient, ingressGVR)
+	}
+
+	if cfg.Command == "plan" {
+		os.Exit(runPlan(ctx, &ctrl, cfg))
 	}
 
 	if cfg.Once {
@@ -371,10 +477,175 @@ func main() {
 		ctrl.Source.AddEventHandler(ctx, func() { ctrl.ScheduleRunOnce(time.Now()) })
 	}
 
+	if leaderElector != nil {
+		// Sources and their informers are already running, so standby replicas
+		// can take over with a warm cache as soon as they acquire the lease.
+		ctrl.RunWithLeaderElection(ctx, leaderElector)
+		return
+	}
+
 	ctrl.ScheduleRunOnce(time.Now())
 	ctrl.Run(ctx)
 }
 
+// Exit codes of the plan command, errors exit with 1
+const (
+	planExitCodeUpToDate = 0
+	planExitCodeChanges  = 2
+)
+
+// runPlan prints the changes a single synchronization would apply and returns
+// the exit code signaling whether changes are pending.
+func runPlan(ctx context.Context, ctrl *controller.Controller, cfg *externaldns.Config) int {
+	p, err := ctrl.Plan(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	changes := p.Changes
+	// registries with ownership only apply changes to the records they own
+	if cfg.Registry != "noop" {
+		changes = registry.OwnedChanges(cfg.TXTOwnerID, changes)
+	}
+
+	report := plan.NewReport(changes)
+	if err := report.Write(os.Stdout, cfg.PlanFormat); err != nil {
+		log.Fatal(err)
+	}
+	if report.HasChanges {
+		return planExitCodeChanges
+	}
+	return planExitCodeUpToDate
+}
+
+// runMigrateRegistry migrates the ownership of the records from the current registry
+// to the registry configured by the --migrate-to-* flags.
+func runMigrateRegistry(ctx context.Context, from registry.Registry, p provider.Provider, cfg *externaldns.Config, clientGenerator source.ClientGenerator) {
+	toCfg := *cfg
+	toCfg.Registry = cfg.MigrateToRegistry
+	toCfg.TXTPrefix = cfg.MigrateToTXTPrefix
+	toCfg.TXTSuffix = cfg.MigrateToTXTSuffix
+	toCfg.TXTWildcardReplacement = cfg.MigrateToTXTWildcard
+	toCfg.TXTCacheInterval = 0
+	toCfg.TXTDeleteOrphans = false
+	toCfg.TXTPreviousOwnerID = ""
+	toCfg.ConfigMapRegistryNamespace = cfg.MigrateToConfigMapNamespace
+	toCfg.ConfigMapRegistryName = cfg.MigrateToConfigMapName
+	to, err := newRegistry(ctx, &toCfg, p, clientGenerator)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migration := &registry.Migration{
+		From:               from,
+		To:                 to,
+		OwnerID:            cfg.TXTOwnerID,
+		AdoptAll:           cfg.Registry == "noop",
+		ManagedRecordTypes: cfg.ManagedDNSRecordTypes,
+		Cleanup:            cfg.MigrateCleanup,
+		DryRun:             cfg.DryRun,
+	}
+	result, err := migration.Run(ctx)
+	if err != nil {
+		log.Fatalf("registry migration failed: %v", err)
+	}
+	if cfg.DryRun {
+		log.Infof("Would migrate %d records, %d are already migrated and %d are owned by another owner in the target registry", len(result.Migrated), len(result.Skipped), len(result.Conflicts))
+		return
+	}
+	log.Infof("Migrated %d records, %d were already migrated and %d are owned by another owner in the target registry", len(result.Migrated), len(result.Skipped), len(result.Conflicts))
+	if len(result.CleanedUp) > 0 {
+		log.Infof("Removed the ownership of %d records from the %s registry", len(result.CleanedUp), cfg.Registry)
+	}
+}
+
+func newRegistry(ctx context.Context, cfg *externaldns.Config, p provider.Provider, clientGenerator source.ClientGenerator) (registry.Registry, error) {
+	switch cfg.Registry {
+	case "noop":
+		return registry.NewNoopRegistry(p)
+	case "txt":
+		codec, err := newLabelsCodec(ctx, cfg, clientGenerator)
+		if err != nil {
+			return nil, err
+		}
+		return registry.NewTXTRegistry(p, cfg.TXTPrefix, cfg.TXTSuffix, cfg.TXTOwnerID, cfg.TXTCacheInterval, cfg.TXTWildcardReplacement, registry.TXTRegistryOptions{
+			DeleteOrphans:   cfg.TXTDeleteOrphans,
+			PreviousOwnerID: cfg.TXTPreviousOwnerID,
+			LabelsCodec:     codec,
+			RecordTypeNames: cfg.TXTNameFormat == "record-type",
+		})
+	case "aws-sd":
+		return registry.NewAWSSDRegistry(p.(*awssd.AWSSDProvider), cfg.TXTOwnerID)
+	case "configmap":
+		kubeClient, err := clientGenerator.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		return registry.NewConfigMapRegistry(p, kubeClient, cfg.ConfigMapRegistryNamespace, cfg.ConfigMapRegistryName, cfg.TXTOwnerID)
+	default:
+		return nil, fmt.Errorf("unknown registry: %s", cfg.Registry)
+	}
+}
+
+// newLabelsCodec returns the codec protecting the labels in TXT records, or nil if they are stored in plaintext.
+// The key is read from --txt-key-file or from the "key" entry of the Secret given by --txt-key-secret.
+func newLabelsCodec(ctx context.Context, cfg *externaldns.Config, clientGenerator source.ClientGenerator) (*endpoint.LabelsCodec, error) {
+	if !cfg.TXTEncrypt && !cfg.TXTSign {
+		return nil, nil
+	}
+
+	var key []byte
+	if cfg.TXTKeyFile != "" {
+		data, err := ioutil.ReadFile(cfg.TXTKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the TXT key: %v", err)
+		}
+		key = data
+	} else {
+		// validation ensures the secret is given as namespace/name
+		ref := strings.SplitN(cfg.TXTKeySecret, "/", 2)
+		namespace, name := ref[0], ref[1]
+		kubeClient, err := clientGenerator.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the TXT key: %v", err)
+		}
+		var ok bool
+		if key, ok = secret.Data["key"]; !ok {
+			return nil, fmt.Errorf("secret %s/%s has no key entry", namespace, name)
+		}
+	}
+	return endpoint.NewLabelsCodec(key, cfg.TXTEncrypt, cfg.TXTSign, cfg.TXTAllowUnprotected)
+}
+
+func newLeaderElector(cfg *externaldns.Config, clientGenerator source.ClientGenerator) (*controller.LeaderElector, error) {
+	kubeClient, err := clientGenerator.KubeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	identity := cfg.LeaderElectionIdentity
+	if identity == "" {
+		identity, err = os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return controller.NewLeaderElector(controller.LeaderElectionConfig{
+		Client:        kubeClient,
+		Namespace:     cfg.LeaderElectionNamespace,
+		Name:          cfg.LeaderElectionID,
+		Identity:      identity,
+		LeaseDuration: cfg.LeaderElectionLeaseDuration,
+		RenewDeadline: cfg.LeaderElectionRenewDeadline,
+		RetryPeriod:   cfg.LeaderElectionRetryPeriod,
+	})
+}
+
 func handleSigterm(cancel func()) {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGTERM)
@@ -383,12 +654,57 @@ func handleSigterm(cancel func()) {
 	cancel()
 }
 
-func serveMetrics(address string) {
-	http.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+func serveMetrics(address string, leaderElector *controller.LeaderElector, status *controller.Status, maxSyncAge time.Duration) {
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if leaderElector != nil {
+			if err := leaderElector.Check(r); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(err.Error()))
+				return
+			}
+			// standby replicas don't synchronize, so their synchronizations can't be stale
+			if !leaderElector.IsLeader() {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(fmt.Sprintf("OK (standby, leader: %s)", leaderElector.GetLeader())))
+				return
+			}
+		}
+		if err := status.Stale(time.Now(), maxSyncAge); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if leaderElector != nil {
+			w.Write([]byte("OK (leader)"))
+		} else {
+			w.Write([]byte("OK"))
+		}
+	})
+
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		// standby replicas are ready to take over as soon as they acquire the lease
+		if leaderElector != nil && !leaderElector.IsLeader() {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OK (standby)"))
+			return
+		}
+		if err := status.Ready(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(err.Error()))
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	http.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status.Report()); err != nil {
+			log.Errorf("Failed to write the status: %v", err)
+		}
+	})
+
 	http.Handle("/metrics", promhttp.Handler())
 
 	log.Fatal(http.ListenAndServe(address, nil))
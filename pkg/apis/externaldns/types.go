@@ -58,11 +58,17 @@ type Config struct {
 	IgnoreHostnameAnnotation          bool
 	IgnoreIngressTLSSpec              bool
 	IgnoreIngressRulesSpec            bool
+	IngressClassNames                 []string
 	Compatibility                     string
 	PublishInternal                   bool
 	PublishHostIP                     bool
+	PublishPodIPs                     bool
 	AlwaysPublishNotReadyAddresses    bool
 	ConnectorSourceServer             string
+	ConnectorSourceCAFile             string
+	ConnectorSourceCertFile           string
+	ConnectorSourceKeyFile            string
+	ConnectorSourceTokenFile          string
 	Provider                          string
 	GoogleProject                     string
 	GoogleBatchChangeSize             int
@@ -123,25 +129,64 @@ type Config struct {
 	TLSClientCert                     string
 	TLSClientCertKey                  string
 	Policy                            string
+	ConflictResolver                  string
+	DeletionGracePeriod               time.Duration
+	SafetyMaxDeletes                  int
+	SafetyMaxDeletePercentage         int
+	SafetyMaxUpdates                  int
+	SafetyMaxUpdatePercentage         int
+	SafetyOverride                    bool
+	SafetyOverrideFile                string
 	Registry                          string
 	TXTOwnerID                        string
+	TXTPreviousOwnerID                string
 	TXTPrefix                         string
 	TXTSuffix                         string
 	Interval                          time.Duration
 	MinEventSyncInterval              time.Duration
+	MaxFailureBackoff                 time.Duration
 	Once                              bool
+	Command                           string
+	PlanFormat                        string
 	DryRun                            bool
 	UpdateEvents                      bool
+	KubernetesEvents                  bool
+	LeaderElection                    bool
+	LeaderElectionNamespace           string
+	LeaderElectionID                  string
+	LeaderElectionIdentity            string
+	LeaderElectionLeaseDuration       time.Duration
+	LeaderElectionRenewDeadline       time.Duration
+	LeaderElectionRetryPeriod         time.Duration
 	LogFormat                         string
 	MetricsAddress                    string
+	HealthStaleSyncIntervals          int
 	LogLevel                          string
 	TXTCacheInterval                  time.Duration
 	TXTWildcardReplacement            string
+	TXTNameFormat                     string
+	TXTDeleteOrphans                  bool
+	AdoptDomains                      []string
+	TXTEncrypt                        bool
+	TXTSign                           bool
+	TXTKeyFile                        string
+	TXTKeySecret                      string
+	TXTAllowUnprotected               bool
+	ConfigMapRegistryNamespace        string
+	ConfigMapRegistryName             string
+	MigrateToRegistry                 string
+	MigrateToTXTPrefix                string
+	MigrateToTXTSuffix                string
+	MigrateToTXTWildcard              string
+	MigrateToConfigMapNamespace       string
+	MigrateToConfigMapName            string
+	MigrateCleanup                    bool
 	ExoscaleEndpoint                  string
 	ExoscaleAPIKey                    string `secure:"yes"`
 	ExoscaleAPISecret                 string `secure:"yes"`
 	CRDSourceAPIVersion               string
 	CRDSourceKind                     string
+	FileSourcePaths                   []string
 	ServiceTypeFilter                 []string
 	CFAPIEndpoint                     string
 	CFUsername                        string
@@ -190,10 +235,16 @@ var defaultConfig = &Config{
 	IgnoreHostnameAnnotation:    false,
 	IgnoreIngressTLSSpec:        false,
 	IgnoreIngressRulesSpec:      false,
+	IngressClassNames:           []string{},
 	Compatibility:               "",
 	PublishInternal:             false,
 	PublishHostIP:               false,
+	PublishPodIPs:               false,
 	ConnectorSourceServer:       "localhost:8080",
+	ConnectorSourceCAFile:       "",
+	ConnectorSourceCertFile:     "",
+	ConnectorSourceKeyFile:      "",
+	ConnectorSourceTokenFile:    "",
 	Provider:                    "",
 	GoogleProject:               "",
 	GoogleBatchChangeSize:       1000,
@@ -246,25 +297,64 @@ var defaultConfig = &Config{
 	TLSClientCert:               "",
 	TLSClientCertKey:            "",
 	Policy:                      "sync",
+	ConflictResolver:            "per-resource",
+	DeletionGracePeriod:         0,
+	SafetyMaxDeletes:            0,
+	SafetyMaxDeletePercentage:   0,
+	SafetyMaxUpdates:            0,
+	SafetyMaxUpdatePercentage:   0,
+	SafetyOverride:              false,
+	SafetyOverrideFile:          "",
 	Registry:                    "txt",
 	TXTOwnerID:                  "default",
+	TXTPreviousOwnerID:          "",
 	TXTPrefix:                   "",
 	TXTSuffix:                   "",
 	TXTCacheInterval:            0,
 	TXTWildcardReplacement:      "",
+	TXTNameFormat:               "shared",
+	TXTDeleteOrphans:            false,
+	AdoptDomains:                []string{},
+	TXTEncrypt:                  false,
+	TXTSign:                     false,
+	TXTKeyFile:                  "",
+	TXTKeySecret:                "",
+	TXTAllowUnprotected:         false,
+	ConfigMapRegistryNamespace:  "default",
+	ConfigMapRegistryName:       "external-dns-ownership",
+	MigrateToRegistry:           "",
+	MigrateToTXTPrefix:          "",
+	MigrateToTXTSuffix:          "",
+	MigrateToTXTWildcard:        "",
+	MigrateToConfigMapNamespace: "default",
+	MigrateToConfigMapName:      "external-dns-ownership",
+	MigrateCleanup:              false,
 	MinEventSyncInterval:        5 * time.Second,
+	MaxFailureBackoff:           10 * time.Minute,
 	Interval:                    time.Minute,
 	Once:                        false,
+	Command:                     "run",
+	PlanFormat:                  "json",
 	DryRun:                      false,
 	UpdateEvents:                false,
+	KubernetesEvents:            false,
+	LeaderElection:              false,
+	LeaderElectionNamespace:     "default",
+	LeaderElectionID:            "external-dns",
+	LeaderElectionIdentity:      "",
+	LeaderElectionLeaseDuration: 15 * time.Second,
+	LeaderElectionRenewDeadline: 10 * time.Second,
+	LeaderElectionRetryPeriod:   2 * time.Second,
 	LogFormat:                   "text",
 	MetricsAddress:              ":7979",
+	HealthStaleSyncIntervals:    0,
 	LogLevel:                    logrus.InfoLevel.String(),
 	ExoscaleEndpoint:            "https://api.exoscale.ch/dns",
 	ExoscaleAPIKey:              "",
 	ExoscaleAPISecret:           "",
 	CRDSourceAPIVersion:         "externaldns.k8s.io/v1alpha1",
 	CRDSourceKind:               "DNSEndpoint",
+	FileSourcePaths:             []string{},
 	ServiceTypeFilter:           []string{},
 	CFAPIEndpoint:               "",
 	CFUsername:                  "",
@@ -356,24 +446,31 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("skipper-routegroup-groupversion", "The resource version for skipper routegroup").Default(source.DefaultRoutegroupVersion).StringVar(&cfg.SkipperRouteGroupVersion)
 
 	// Flags related to processing sources
-	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: service, ingress, node, fake, connector, istio-gateway, istio-virtualservice, cloudfoundry, contour-ingressroute, contour-httpproxy, gloo-proxy, crd, empty, skipper-routegroup, openshift-route, ambassador-host, kong-tcpingress)").Required().PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "pod", "istio-gateway", "istio-virtualservice", "cloudfoundry", "contour-ingressroute", "contour-httpproxy", "gloo-proxy", "fake", "connector", "crd", "empty", "skipper-routegroup", "openshift-route", "ambassador-host", "kong-tcpingress")
+	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required unless migrating the registry, options: service, ingress, node, fake, connector, file, istio-gateway, istio-virtualservice, cloudfoundry, contour-ingressroute, contour-httpproxy, gateway-httproute, gateway-tlsroute, gateway-tcproute, gloo-proxy, crd, empty, skipper-routegroup, openshift-route, ambassador-host, kong-tcpingress)").PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "pod", "istio-gateway", "istio-virtualservice", "cloudfoundry", "contour-ingressroute", "contour-httpproxy", "gateway-httproute", "gateway-tlsroute", "gateway-tcproute", "gloo-proxy", "fake", "connector", "file", "crd", "empty", "skipper-routegroup", "openshift-route", "ambassador-host", "kong-tcpingress")
 	app.Flag("namespace", "Limit sources of endpoints to a specific namespace (default: all namespaces)").Default(defaultConfig.Namespace).StringVar(&cfg.Namespace)
 	app.Flag("annotation-filter", "Filter sources managed by external-dns via annotation using label selector semantics (default: all sources)").Default(defaultConfig.AnnotationFilter).StringVar(&cfg.AnnotationFilter)
-	app.Flag("label-filter", "Filter sources managed by external-dns via label selector when listing all resources; currently only supported by source CRD").Default(defaultConfig.LabelFilter).StringVar(&cfg.LabelFilter)
+	app.Flag("label-filter", "Filter sources managed by external-dns via label selector when listing all resources; currently only supported by source CRD, pod and the Gateway API route sources").Default(defaultConfig.LabelFilter).StringVar(&cfg.LabelFilter)
 	app.Flag("fqdn-template", "A templated string that's used to generate DNS names from sources that don't define a hostname themselves, or to add a hostname suffix when paired with the fake source (optional). Accepts comma separated list for multiple global FQDN.").Default(defaultConfig.FQDNTemplate).StringVar(&cfg.FQDNTemplate)
 	app.Flag("combine-fqdn-annotation", "Combine FQDN template and Annotations instead of overwriting").BoolVar(&cfg.CombineFQDNAndAnnotation)
 	app.Flag("ignore-hostname-annotation", "Ignore hostname annotation when generating DNS names, valid only when using fqdn-template is set (optional, default: false)").BoolVar(&cfg.IgnoreHostnameAnnotation)
 	app.Flag("ignore-ingress-tls-spec", "Ignore tls spec section in ingresses resources, applicable only for ingress sources (optional, default: false)").BoolVar(&cfg.IgnoreIngressTLSSpec)
 	app.Flag("compatibility", "Process annotation semantics from legacy implementations (optional, options: mate, molecule, kops-dns-controller)").Default(defaultConfig.Compatibility).EnumVar(&cfg.Compatibility, "", "mate", "molecule", "kops-dns-controller")
 	app.Flag("ignore-ingress-rules-spec", "Ignore rules spec section in ingresses resources, applicable only for ingress sources (optional, default: false)").BoolVar(&cfg.IgnoreIngressRulesSpec)
+	app.Flag("ingress-class", "Require an ingress to have this class in spec.ingressClassName or, without it, in the kubernetes.io/ingress.class annotation; specify multiple times for multiple classes, applicable only for ingress sources (optional, default: all classes)").StringsVar(&cfg.IngressClassNames)
 	app.Flag("publish-internal-services", "Allow external-dns to publish DNS records for ClusterIP services (optional)").BoolVar(&cfg.PublishInternal)
 	app.Flag("publish-host-ip", "Allow external-dns to publish host-ip for headless services (optional)").BoolVar(&cfg.PublishHostIP)
+	app.Flag("publish-pod-ips", "Allow external-dns to publish the IPs of annotated pods without hostNetwork, hostNetwork pods keep publishing the external IPs of their nodes, valid only when using pod source (optional)").BoolVar(&cfg.PublishPodIPs)
 	app.Flag("always-publish-not-ready-addresses", "Always publish also not ready addresses for headless services (optional)").BoolVar(&cfg.AlwaysPublishNotReadyAddresses)
-	app.Flag("connector-source-server", "The server to connect for connector source, valid only when using connector source").Default(defaultConfig.ConnectorSourceServer).StringVar(&cfg.ConnectorSourceServer)
+	app.Flag("connector-source-server", "The server to connect for connector source, either the http(s) URL of a server of the HTTP protocol or the host:port of a legacy tcp server, valid only when using connector source").Default(defaultConfig.ConnectorSourceServer).StringVar(&cfg.ConnectorSourceServer)
+	app.Flag("connector-source-ca-file", "The CA to verify the certificate of an https connector server with, instead of the system CAs (optional)").Default(defaultConfig.ConnectorSourceCAFile).StringVar(&cfg.ConnectorSourceCAFile)
+	app.Flag("connector-source-cert-file", "The client certificate to present to an https connector server requiring mutual TLS, requires connector-source-key-file (optional)").Default(defaultConfig.ConnectorSourceCertFile).StringVar(&cfg.ConnectorSourceCertFile)
+	app.Flag("connector-source-key-file", "The key of the client certificate to present to an https connector server (optional)").Default(defaultConfig.ConnectorSourceKeyFile).StringVar(&cfg.ConnectorSourceKeyFile)
+	app.Flag("connector-source-token-file", "The file with the bearer token to present to an https connector server, read for every request (optional)").Default(defaultConfig.ConnectorSourceTokenFile).StringVar(&cfg.ConnectorSourceTokenFile)
 	app.Flag("crd-source-apiversion", "API version of the CRD for crd source, e.g. `externaldns.k8s.io/v1alpha1`, valid only when using crd source").Default(defaultConfig.CRDSourceAPIVersion).StringVar(&cfg.CRDSourceAPIVersion)
 	app.Flag("crd-source-kind", "Kind of the CRD for the crd source in API group and version specified by crd-source-apiversion").Default(defaultConfig.CRDSourceKind).StringVar(&cfg.CRDSourceKind)
+	app.Flag("file-source-path", "A YAML, JSON or zone file with the endpoints for the file source; specify multiple times for multiple files, valid only when using file source").StringsVar(&cfg.FileSourcePaths)
 	app.Flag("service-type-filter", "The service types to take care about (default: all, expected: ClusterIP, NodePort, LoadBalancer or ExternalName)").StringsVar(&cfg.ServiceTypeFilter)
-	app.Flag("managed-record-types", "Comma separated list of record types to manage (default: A, CNAME) (supported records: CNAME, A, NS").Default("A", "CNAME").StringsVar(&cfg.ManagedDNSRecordTypes)
+	app.Flag("managed-record-types", "Comma separated list of record types to manage (default: A, CNAME) (supported records: CNAME, A, AAAA, NS, SRV; TXT only when not using the txt registry; the provider must support the given types)").Default("A", "CNAME").StringsVar(&cfg.ManagedDNSRecordTypes)
 	app.Flag("default-targets", "Set globally default IP address that will apply as a target instead of source addresses. Specify multiple times for multiple targets (optional)").StringsVar(&cfg.DefaultTargets)
 
 	// Flags related to providers
@@ -474,31 +571,73 @@ func (cfg *Config) ParseFlags(args []string) error {
 
 	// Flags related to policies
 	app.Flag("policy", "Modify how DNS records are synchronized between sources and providers (default: sync, options: sync, upsert-only, create-only)").Default(defaultConfig.Policy).EnumVar(&cfg.Policy, "sync", "upsert-only", "create-only")
+	app.Flag("conflict-resolver", "Modify how a DNS name claimed by more than one resource is assigned (default: per-resource, options: per-resource, ownership-priority, oldest-wins)").Default(defaultConfig.ConflictResolver).EnumVar(&cfg.ConflictResolver, "per-resource", "ownership-priority", "oldest-wins")
+	app.Flag("deletion-grace-period", "Only delete records which have been missing from the sources for at least this long; the time is stored in the registry labels (default: 0, delete immediately)").Default(defaultConfig.DeletionGracePeriod.String()).DurationVar(&cfg.DeletionGracePeriod)
+	app.Flag("safety-max-deletes", "Hold back all deletions of a sync if there are more than this many (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.SafetyMaxDeletes)).IntVar(&cfg.SafetyMaxDeletes)
+	app.Flag("safety-max-delete-percentage", "Hold back all deletions of a sync if they exceed this percentage of the managed records (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.SafetyMaxDeletePercentage)).IntVar(&cfg.SafetyMaxDeletePercentage)
+	app.Flag("safety-max-updates", "Hold back all updates of a sync if there are more than this many (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.SafetyMaxUpdates)).IntVar(&cfg.SafetyMaxUpdates)
+	app.Flag("safety-max-update-percentage", "Hold back all updates of a sync if they exceed this percentage of the managed records (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.SafetyMaxUpdatePercentage)).IntVar(&cfg.SafetyMaxUpdatePercentage)
+	app.Flag("safety-override", "Apply changes even if they exceed the safety thresholds (default: disabled)").BoolVar(&cfg.SafetyOverride)
+	app.Flag("safety-override-file", "Apply changes exceeding the safety thresholds while this file contains \"true\", or sets external-dns.alpha.kubernetes.io/safety-override=\"true\" like a downward API annotations file (optional)").Default(defaultConfig.SafetyOverrideFile).StringVar(&cfg.SafetyOverrideFile)
 
 	// Flags related to the registry
-	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop, aws-sd)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "noop", "aws-sd")
-	app.Flag("txt-owner-id", "When using the TXT registry, a name that identifies this instance of ExternalDNS (default: default)").Default(defaultConfig.TXTOwnerID).StringVar(&cfg.TXTOwnerID)
+	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop, aws-sd, configmap)").Default(defaultConfig.Registry).EnumVar(&cfg.Registry, "txt", "noop", "aws-sd", "configmap")
+	app.Flag("txt-owner-id", "When using the TXT or ConfigMap registry, a name that identifies this instance of ExternalDNS (default: default)").Default(defaultConfig.TXTOwnerID).StringVar(&cfg.TXTOwnerID)
+	app.Flag("txt-previous-owner-id", "When using the TXT registry, hand the records of this owner id over to --txt-owner-id as they are synchronized (optional)").Default(defaultConfig.TXTPreviousOwnerID).StringVar(&cfg.TXTPreviousOwnerID)
 	app.Flag("txt-prefix", "When using the TXT registry, a custom string that's prefixed to each ownership DNS record (optional). Mutual exclusive with txt-suffix!").Default(defaultConfig.TXTPrefix).StringVar(&cfg.TXTPrefix)
 	app.Flag("txt-suffix", "When using the TXT registry, a custom string that's suffixed to the host portion of each ownership DNS record (optional). Mutual exclusive with txt-prefix!").Default(defaultConfig.TXTSuffix).StringVar(&cfg.TXTSuffix)
 	app.Flag("txt-wildcard-replacement", "When using the TXT registry, a custom string that's used instead of an asterisk for TXT records corresponding to wildcard DNS records (optional)").Default(defaultConfig.TXTWildcardReplacement).StringVar(&cfg.TXTWildcardReplacement)
+	app.Flag("txt-name-format", "When using the TXT registry, the naming of TXT records; shared names them after the records and shares them between all record types with the name, record-type prefixes them with the record type, e.g. a-foo.example.org, while still recognizing shared ones (default: shared, options: shared, record-type)").Default(defaultConfig.TXTNameFormat).EnumVar(&cfg.TXTNameFormat, "shared", "record-type")
+	app.Flag("txt-delete-orphans", "When using the TXT registry, delete TXT records of this owner whose record no longer exists (optional, default: false)").BoolVar(&cfg.TXTDeleteOrphans)
+	app.Flag("adopt-domain", "When using the TXT registry, adopt existing records without owner in this domain if their resource has the external-dns.alpha.kubernetes.io/adopt annotation; specify multiple times for multiple domains (optional)").StringsVar(&cfg.AdoptDomains)
+	app.Flag("txt-encrypt", "When using the TXT registry, encrypt the labels in TXT records so they don't reveal the resources behind the records; requires --txt-key-file or --txt-key-secret (optional, default: false)").BoolVar(&cfg.TXTEncrypt)
+	app.Flag("txt-sign", "When using the TXT registry, sign the labels in TXT records so they can't be forged without the key; requires --txt-key-file or --txt-key-secret (optional, default: false)").BoolVar(&cfg.TXTSign)
+	app.Flag("txt-key-file", "When encrypting or signing TXT records, the file holding the 32 byte key, raw or base64 encoded (optional)").Default(defaultConfig.TXTKeyFile).StringVar(&cfg.TXTKeyFile)
+	app.Flag("txt-key-secret", "When encrypting or signing TXT records, the Secret holding the key in its \"key\" entry, in the form namespace/name (optional)").Default(defaultConfig.TXTKeySecret).StringVar(&cfg.TXTKeySecret)
+	app.Flag("txt-allow-unprotected", "When encrypting or signing TXT records, also accept TXT records in plaintext and switch them over as they are updated (optional, default: false)").BoolVar(&cfg.TXTAllowUnprotected)
+	app.Flag("configmap-registry-namespace", "When using the ConfigMap registry, the namespace of the ConfigMap storing the record ownership (default: default)").Default(defaultConfig.ConfigMapRegistryNamespace).StringVar(&cfg.ConfigMapRegistryNamespace)
+	app.Flag("configmap-registry-name", "When using the ConfigMap registry, the name of the ConfigMap storing the record ownership (default: external-dns-ownership)").Default(defaultConfig.ConfigMapRegistryName).StringVar(&cfg.ConfigMapRegistryName)
+	app.Flag("migrate-to-registry", "When using the migrate-registry command, the registry implementation the ownership is migrated to (required, options: txt, noop, aws-sd, configmap)").EnumVar(&cfg.MigrateToRegistry, "txt", "noop", "aws-sd", "configmap")
+	app.Flag("migrate-to-txt-prefix", "When migrating to the TXT registry, a custom string that's prefixed to each ownership DNS record (optional). Mutual exclusive with migrate-to-txt-suffix!").Default(defaultConfig.MigrateToTXTPrefix).StringVar(&cfg.MigrateToTXTPrefix)
+	app.Flag("migrate-to-txt-suffix", "When migrating to the TXT registry, a custom string that's suffixed to the host portion of each ownership DNS record (optional). Mutual exclusive with migrate-to-txt-prefix!").Default(defaultConfig.MigrateToTXTSuffix).StringVar(&cfg.MigrateToTXTSuffix)
+	app.Flag("migrate-to-txt-wildcard-replacement", "When migrating to the TXT registry, a custom string that's used instead of an asterisk for TXT records corresponding to wildcard DNS records (optional)").Default(defaultConfig.MigrateToTXTWildcard).StringVar(&cfg.MigrateToTXTWildcard)
+	app.Flag("migrate-to-configmap-namespace", "When migrating to the ConfigMap registry, the namespace of the ConfigMap storing the record ownership (default: default)").Default(defaultConfig.MigrateToConfigMapNamespace).StringVar(&cfg.MigrateToConfigMapNamespace)
+	app.Flag("migrate-to-configmap-name", "When migrating to the ConfigMap registry, the name of the ConfigMap storing the record ownership (default: external-dns-ownership)").Default(defaultConfig.MigrateToConfigMapName).StringVar(&cfg.MigrateToConfigMapName)
+	app.Flag("migrate-cleanup", "When using the migrate-registry command, remove the ownership from the current registry once the migration is verified (default: disabled)").BoolVar(&cfg.MigrateCleanup)
 
 	// Flags related to the main control loop
 	app.Flag("txt-cache-interval", "The interval between cache synchronizations in duration format (default: disabled)").Default(defaultConfig.TXTCacheInterval.String()).DurationVar(&cfg.TXTCacheInterval)
 	app.Flag("interval", "The interval between two consecutive synchronizations in duration format (default: 1m)").Default(defaultConfig.Interval.String()).DurationVar(&cfg.Interval)
 	app.Flag("min-event-sync-interval", "The minimum interval between two consecutive synchronizations triggered from kubernetes events in duration format (default: 5s)").Default(defaultConfig.MinEventSyncInterval.String()).DurationVar(&cfg.MinEventSyncInterval)
+	app.Flag("max-failure-backoff", "The maximum delay before retrying a failed synchronization in duration format; the delay starts at --interval and doubles with every consecutive failure, with up to 20% jitter, 0 retries after --interval (default: 10m)").Default(defaultConfig.MaxFailureBackoff.String()).DurationVar(&cfg.MaxFailureBackoff)
 	app.Flag("once", "When enabled, exits the synchronization loop after the first iteration (default: disabled)").BoolVar(&cfg.Once)
+	app.Flag("plan-format", "The format in which the plan command prints the changes (default: json, options: json, yaml)").Default(defaultConfig.PlanFormat).EnumVar(&cfg.PlanFormat, "json", "yaml")
 	app.Flag("dry-run", "When enabled, prints DNS record changes rather than actually performing them (default: disabled)").BoolVar(&cfg.DryRun)
 	app.Flag("events", "When enabled, in addition to running every interval, the reconciliation loop will get triggered when supported sources change (default: disabled)").BoolVar(&cfg.UpdateEvents)
+	app.Flag("kubernetes-events", "When enabled, records Kubernetes events on the resources of records which are created, updated, deleted, owned by someone else or rejected by the provider, not in dry-run mode (default: disabled)").BoolVar(&cfg.KubernetesEvents)
+	app.Flag("leader-election", "When enabled, only the replica holding a Kubernetes Lease reconciles DNS records while the others stay on standby (default: disabled)").BoolVar(&cfg.LeaderElection)
+	app.Flag("leader-election-namespace", "When using leader election, the namespace of the Lease object (default: default)").Default(defaultConfig.LeaderElectionNamespace).StringVar(&cfg.LeaderElectionNamespace)
+	app.Flag("leader-election-id", "When using leader election, the name of the Lease object; replicas sharing a name elect a single leader (default: external-dns)").Default(defaultConfig.LeaderElectionID).StringVar(&cfg.LeaderElectionID)
+	app.Flag("leader-election-identity", "When using leader election, the unique name of this replica (default: hostname)").Default(defaultConfig.LeaderElectionIdentity).StringVar(&cfg.LeaderElectionIdentity)
+	app.Flag("leader-election-lease-duration", "When using leader election, the duration standby replicas wait before taking over an expired lease (default: 15s)").Default(defaultConfig.LeaderElectionLeaseDuration.String()).DurationVar(&cfg.LeaderElectionLeaseDuration)
+	app.Flag("leader-election-renew-deadline", "When using leader election, the duration the leader keeps retrying to renew its lease before giving it up (default: 10s)").Default(defaultConfig.LeaderElectionRenewDeadline.String()).DurationVar(&cfg.LeaderElectionRenewDeadline)
+	app.Flag("leader-election-retry-period", "When using leader election, the interval between attempts to acquire or renew the lease (default: 2s)").Default(defaultConfig.LeaderElectionRetryPeriod.String()).DurationVar(&cfg.LeaderElectionRetryPeriod)
 
 	// Miscellaneous flags
 	app.Flag("log-format", "The format in which log messages are printed (default: text, options: text, json)").Default(defaultConfig.LogFormat).EnumVar(&cfg.LogFormat, "text", "json")
 	app.Flag("metrics-address", "Specify where to serve the metrics and health check endpoint (default: :7979)").Default(defaultConfig.MetricsAddress).StringVar(&cfg.MetricsAddress)
+	app.Flag("health-stale-sync-intervals", "Fail the health check when no synchronization succeeded for this many times --interval, which must exceed --max-failure-backoff (default: 0, disabled)").Default(strconv.Itoa(defaultConfig.HealthStaleSyncIntervals)).IntVar(&cfg.HealthStaleSyncIntervals)
 	app.Flag("log-level", "Set the level of logging. (default: info, options: panic, debug, info, warning, error, fatal").Default(defaultConfig.LogLevel).EnumVar(&cfg.LogLevel, allLogLevelsAsStrings()...)
 
-	_, err := app.Parse(args)
+	app.Command("run", "Synchronize the DNS records with the sources (default)").Default()
+	app.Command("plan", "Print the changes which would be applied to the DNS records and exit; the exit code is 0 if the records are up to date and 2 if changes are pending")
+	app.Command("migrate-registry", "Migrate the ownership of the records from the registry configured by --registry to the one configured by --migrate-to-registry and exit")
+
+	command, err := app.Parse(args)
 	if err != nil {
 		return err
 	}
+	cfg.Command = command
 
 	return nil
 }
@@ -114,6 +114,39 @@ var (
 		DigitalOceanAPIPageSize:     50,
 		ManagedDNSRecordTypes:       []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
 		RFC2136BatchChangeSize:      50,
+		LeaderElectionNamespace:     "default",
+		LeaderElectionID:            "external-dns",
+		LeaderElectionLeaseDuration: 15 * time.Second,
+		LeaderElectionRenewDeadline: 10 * time.Second,
+		LeaderElectionRetryPeriod:   2 * time.Second,
+		ConflictResolver:            "per-resource",
+		Command:                     "run",
+		PlanFormat:                  "json",
+		ConfigMapRegistryNamespace:  "default",
+		ConfigMapRegistryName:       "external-dns-ownership",
+		MigrateToRegistry:           "",
+		MigrateToTXTPrefix:          "",
+		MigrateToTXTSuffix:          "",
+		MigrateToTXTWildcard:        "",
+		MigrateToConfigMapNamespace: "default",
+		MigrateToConfigMapName:      "external-dns-ownership",
+		MigrateCleanup:              false,
+		TXTDeleteOrphans:            false,
+		TXTPreviousOwnerID:          "",
+		TXTEncrypt:                  false,
+		TXTSign:                     false,
+		TXTKeyFile:                  "",
+		TXTKeySecret:                "",
+		TXTAllowUnprotected:         false,
+		TXTNameFormat:               "shared",
+		MaxFailureBackoff:           10 * time.Minute,
+		HealthStaleSyncIntervals:    0,
+		KubernetesEvents:            false,
+		PublishPodIPs:               false,
+		ConnectorSourceCAFile:       "",
+		ConnectorSourceCertFile:     "",
+		ConnectorSourceKeyFile:      "",
+		ConnectorSourceTokenFile:    "",
 	}
 
 	overriddenConfig = &Config{
@@ -208,6 +241,51 @@ var (
 		DigitalOceanAPIPageSize:     100,
 		ManagedDNSRecordTypes:       []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
 		RFC2136BatchChangeSize:      100,
+		LeaderElection:              true,
+		LeaderElectionNamespace:     "external-dns",
+		LeaderElectionID:            "external-dns-private",
+		LeaderElectionIdentity:      "replica-1",
+		LeaderElectionLeaseDuration: 30 * time.Second,
+		LeaderElectionRenewDeadline: 20 * time.Second,
+		LeaderElectionRetryPeriod:   4 * time.Second,
+		ConflictResolver:            "oldest-wins",
+		SafetyMaxDeletes:            10,
+		SafetyMaxDeletePercentage:   25,
+		SafetyMaxUpdates:            20,
+		SafetyMaxUpdatePercentage:   50,
+		SafetyOverride:              true,
+		SafetyOverrideFile:          "/etc/podinfo/annotations",
+		DeletionGracePeriod:         10 * time.Minute,
+		Command:                     "run",
+		PlanFormat:                  "yaml",
+		ConfigMapRegistryNamespace:  "kube-system",
+		ConfigMapRegistryName:       "dns-ownership",
+		MigrateToRegistry:           "configmap",
+		MigrateToTXTPrefix:          "new-",
+		MigrateToTXTSuffix:          "",
+		MigrateToTXTWildcard:        "",
+		MigrateToConfigMapNamespace: "kube-system",
+		MigrateToConfigMapName:      "dns-ownership",
+		MigrateCleanup:              true,
+		TXTDeleteOrphans:            true,
+		TXTPreviousOwnerID:          "owner-0",
+		AdoptDomains:                []string{"legacy.example.org"},
+		TXTEncrypt:                  true,
+		TXTSign:                     true,
+		TXTKeyFile:                  "/etc/external-dns/txt.key",
+		TXTKeySecret:                "kube-system/txt-key",
+		TXTAllowUnprotected:         true,
+		TXTNameFormat:               "record-type",
+		MaxFailureBackoff:           30 * time.Minute,
+		HealthStaleSyncIntervals:    3,
+		KubernetesEvents:            true,
+		PublishPodIPs:               true,
+		ConnectorSourceCAFile:       "/etc/connector/ca",
+		ConnectorSourceCertFile:     "/etc/connector/cert",
+		ConnectorSourceKeyFile:      "/etc/connector/key",
+		ConnectorSourceTokenFile:    "/etc/connector/token",
+		FileSourcePaths:             []string{"/etc/records/vms.yaml", "/etc/records/partners.zone"},
+		IngressClassNames:           []string{"nginx", "internal"},
 	}
 )
 
@@ -326,6 +404,50 @@ func TestParseFlags(t *testing.T) {
 				"--transip-keyfile=/path/to/transip.key",
 				"--digitalocean-api-page-size=100",
 				"--rfc2136-batch-change-size=100",
+				"--leader-election",
+				"--leader-election-namespace=external-dns",
+				"--leader-election-id=external-dns-private",
+				"--leader-election-identity=replica-1",
+				"--leader-election-lease-duration=30s",
+				"--leader-election-renew-deadline=20s",
+				"--leader-election-retry-period=4s",
+				"--conflict-resolver=oldest-wins",
+				"--safety-max-deletes=10",
+				"--safety-max-delete-percentage=25",
+				"--safety-max-updates=20",
+				"--safety-max-update-percentage=50",
+				"--safety-override",
+				"--safety-override-file=/etc/podinfo/annotations",
+				"--deletion-grace-period=10m",
+				"--plan-format=yaml",
+				"--configmap-registry-namespace=kube-system",
+				"--configmap-registry-name=dns-ownership",
+				"--migrate-to-registry=configmap",
+				"--migrate-to-txt-prefix=new-",
+				"--migrate-to-configmap-namespace=kube-system",
+				"--migrate-to-configmap-name=dns-ownership",
+				"--migrate-cleanup",
+				"--txt-delete-orphans",
+				"--txt-previous-owner-id=owner-0",
+				"--adopt-domain=legacy.example.org",
+				"--txt-encrypt",
+				"--txt-sign",
+				"--txt-key-file=/etc/external-dns/txt.key",
+				"--txt-key-secret=kube-system/txt-key",
+				"--txt-allow-unprotected",
+				"--txt-name-format=record-type",
+				"--max-failure-backoff=30m",
+				"--health-stale-sync-intervals=3",
+				"--kubernetes-events",
+				"--publish-pod-ips",
+				"--connector-source-ca-file=/etc/connector/ca",
+				"--connector-source-cert-file=/etc/connector/cert",
+				"--connector-source-key-file=/etc/connector/key",
+				"--connector-source-token-file=/etc/connector/token",
+				"--file-source-path=/etc/records/vms.yaml",
+				"--file-source-path=/etc/records/partners.zone",
+				"--ingress-class=nginx",
+				"--ingress-class=internal",
 			},
 			envVars:  map[string]string{},
 			expected: overriddenConfig,
@@ -424,6 +546,48 @@ func TestParseFlags(t *testing.T) {
 				"EXTERNAL_DNS_TRANSIP_KEYFILE":                 "/path/to/transip.key",
 				"EXTERNAL_DNS_DIGITALOCEAN_API_PAGE_SIZE":      "100",
 				"EXTERNAL_DNS_RFC2136_BATCH_CHANGE_SIZE":       "100",
+				"EXTERNAL_DNS_LEADER_ELECTION":                 "1",
+				"EXTERNAL_DNS_LEADER_ELECTION_NAMESPACE":       "external-dns",
+				"EXTERNAL_DNS_LEADER_ELECTION_ID":              "external-dns-private",
+				"EXTERNAL_DNS_LEADER_ELECTION_IDENTITY":        "replica-1",
+				"EXTERNAL_DNS_LEADER_ELECTION_LEASE_DURATION":  "30s",
+				"EXTERNAL_DNS_LEADER_ELECTION_RENEW_DEADLINE":  "20s",
+				"EXTERNAL_DNS_LEADER_ELECTION_RETRY_PERIOD":    "4s",
+				"EXTERNAL_DNS_CONFLICT_RESOLVER":               "oldest-wins",
+				"EXTERNAL_DNS_SAFETY_MAX_DELETES":              "10",
+				"EXTERNAL_DNS_SAFETY_MAX_DELETE_PERCENTAGE":    "25",
+				"EXTERNAL_DNS_SAFETY_MAX_UPDATES":              "20",
+				"EXTERNAL_DNS_SAFETY_MAX_UPDATE_PERCENTAGE":    "50",
+				"EXTERNAL_DNS_SAFETY_OVERRIDE":                 "1",
+				"EXTERNAL_DNS_SAFETY_OVERRIDE_FILE":            "/etc/podinfo/annotations",
+				"EXTERNAL_DNS_DELETION_GRACE_PERIOD":           "10m",
+				"EXTERNAL_DNS_PLAN_FORMAT":                     "yaml",
+				"EXTERNAL_DNS_CONFIGMAP_REGISTRY_NAMESPACE":    "kube-system",
+				"EXTERNAL_DNS_CONFIGMAP_REGISTRY_NAME":         "dns-ownership",
+				"EXTERNAL_DNS_MIGRATE_TO_REGISTRY":             "configmap",
+				"EXTERNAL_DNS_MIGRATE_TO_TXT_PREFIX":           "new-",
+				"EXTERNAL_DNS_MIGRATE_TO_CONFIGMAP_NAMESPACE":  "kube-system",
+				"EXTERNAL_DNS_MIGRATE_TO_CONFIGMAP_NAME":       "dns-ownership",
+				"EXTERNAL_DNS_MIGRATE_CLEANUP":                 "1",
+				"EXTERNAL_DNS_TXT_DELETE_ORPHANS":              "1",
+				"EXTERNAL_DNS_TXT_PREVIOUS_OWNER_ID":           "owner-0",
+				"EXTERNAL_DNS_ADOPT_DOMAIN":                    "legacy.example.org",
+				"EXTERNAL_DNS_TXT_ENCRYPT":                     "1",
+				"EXTERNAL_DNS_TXT_SIGN":                        "1",
+				"EXTERNAL_DNS_TXT_KEY_FILE":                    "/etc/external-dns/txt.key",
+				"EXTERNAL_DNS_TXT_KEY_SECRET":                  "kube-system/txt-key",
+				"EXTERNAL_DNS_TXT_ALLOW_UNPROTECTED":           "1",
+				"EXTERNAL_DNS_TXT_NAME_FORMAT":                 "record-type",
+				"EXTERNAL_DNS_MAX_FAILURE_BACKOFF":             "30m",
+				"EXTERNAL_DNS_HEALTH_STALE_SYNC_INTERVALS":     "3",
+				"EXTERNAL_DNS_KUBERNETES_EVENTS":               "1",
+				"EXTERNAL_DNS_PUBLISH_POD_IPS":                 "1",
+				"EXTERNAL_DNS_CONNECTOR_SOURCE_CA_FILE":        "/etc/connector/ca",
+				"EXTERNAL_DNS_CONNECTOR_SOURCE_CERT_FILE":      "/etc/connector/cert",
+				"EXTERNAL_DNS_CONNECTOR_SOURCE_KEY_FILE":       "/etc/connector/key",
+				"EXTERNAL_DNS_CONNECTOR_SOURCE_TOKEN_FILE":     "/etc/connector/token",
+				"EXTERNAL_DNS_FILE_SOURCE_PATH":                "/etc/records/vms.yaml\n/etc/records/partners.zone",
+				"EXTERNAL_DNS_INGRESS_CLASS":                   "nginx\ninternal",
 			},
 			expected: overriddenConfig,
 		},
@@ -473,3 +637,23 @@ func TestPasswordsNotLogged(t *testing.T) {
 	assert.False(t, strings.Contains(s, "pdns-api-key"))
 	assert.False(t, strings.Contains(s, "tsig-secret"))
 }
+
+func TestParseCommand(t *testing.T) {
+	cfg := NewConfig()
+	require.NoError(t, cfg.ParseFlags([]string{"--source=service", "--provider=google"}))
+	assert.Equal(t, "run", cfg.Command)
+
+	cfg = NewConfig()
+	require.NoError(t, cfg.ParseFlags([]string{"plan", "--source=service", "--provider=google", "--plan-format=yaml"}))
+	assert.Equal(t, "plan", cfg.Command)
+	assert.Equal(t, "yaml", cfg.PlanFormat)
+
+	cfg = NewConfig()
+	require.NoError(t, cfg.ParseFlags([]string{"migrate-registry", "--provider=google", "--migrate-to-registry=configmap", "--migrate-cleanup"}))
+	assert.Equal(t, "migrate-registry", cfg.Command)
+	assert.Equal(t, "configmap", cfg.MigrateToRegistry)
+	assert.True(t, cfg.MigrateCleanup)
+
+	cfg = NewConfig()
+	require.Error(t, cfg.ParseFlags([]string{"unknown", "--source=service", "--provider=google"}))
+}
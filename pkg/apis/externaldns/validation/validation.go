@@ -19,17 +19,38 @@ package validation
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/provider"
 )
 
+// basicRecordTypes are the record types supported by every provider
+var basicRecordTypes = []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME, endpoint.RecordTypeTXT}
+
+// restrictedRecordTypeProviders lists the providers which only read and write some record types
+var restrictedRecordTypeProviders = map[string][]string{
+	"aws-sd":    basicRecordTypes,
+	"bluecat":   basicRecordTypes,
+	"coredns":   basicRecordTypes,
+	"designate": basicRecordTypes,
+	"dnsimple":  basicRecordTypes,
+	"dyn":       basicRecordTypes,
+	"infoblox":  basicRecordTypes,
+	"rdns":      basicRecordTypes,
+	"rfc2136":   append([]string{endpoint.RecordTypeAAAA}, basicRecordTypes...),
+	"skydns":    basicRecordTypes,
+}
+
 // ValidateConfig performs validation on the Config object
 func ValidateConfig(cfg *externaldns.Config) error {
 	// TODO: Should probably return field.ErrorList
 	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
 		return fmt.Errorf("unsupported log format: %s", cfg.LogFormat)
 	}
-	if len(cfg.Sources) == 0 {
+	if len(cfg.Sources) == 0 && cfg.Command != "migrate-registry" {
 		return errors.New("no sources specified")
 	}
 	if cfg.Provider == "" {
@@ -110,5 +131,125 @@ func ValidateConfig(cfg *externaldns.Config) error {
 		return errors.New("txt-prefix and txt-suffix are mutual exclusive")
 	}
 
+	if len(cfg.AdoptDomains) > 0 && cfg.Registry != "txt" {
+		return errors.New("--adopt-domain requires the txt registry")
+	}
+	if cfg.TXTPreviousOwnerID != "" {
+		if cfg.Registry != "txt" {
+			return errors.New("--txt-previous-owner-id requires the txt registry")
+		}
+		if cfg.TXTPreviousOwnerID == cfg.TXTOwnerID {
+			return errors.New("--txt-previous-owner-id must differ from --txt-owner-id")
+		}
+	}
+	if cfg.TXTEncrypt || cfg.TXTSign {
+		if cfg.Registry != "txt" {
+			return errors.New("--txt-encrypt and --txt-sign require the txt registry")
+		}
+		if (cfg.TXTKeyFile == "") == (cfg.TXTKeySecret == "") {
+			return errors.New("--txt-encrypt and --txt-sign require exactly one of --txt-key-file and --txt-key-secret")
+		}
+	} else if cfg.TXTKeyFile != "" || cfg.TXTKeySecret != "" || cfg.TXTAllowUnprotected {
+		return errors.New("--txt-key-file, --txt-key-secret and --txt-allow-unprotected require --txt-encrypt or --txt-sign")
+	}
+	if cfg.TXTKeySecret != "" {
+		if ref := strings.SplitN(cfg.TXTKeySecret, "/", 2); len(ref) != 2 || ref[0] == "" || ref[1] == "" {
+			return errors.New("--txt-key-secret must be in the form namespace/name")
+		}
+	}
+
+	if cfg.LeaderElection {
+		if cfg.LeaderElectionRenewDeadline >= cfg.LeaderElectionLeaseDuration {
+			return errors.New("--leader-election-lease-duration must be greater than --leader-election-renew-deadline")
+		}
+		if cfg.LeaderElectionRetryPeriod >= cfg.LeaderElectionRenewDeadline {
+			return errors.New("--leader-election-renew-deadline must be greater than --leader-election-retry-period")
+		}
+	}
+
+	if cfg.DeletionGracePeriod < 0 {
+		return errors.New("--deletion-grace-period cannot be negative")
+	}
+	if cfg.MaxFailureBackoff < 0 {
+		return errors.New("--max-failure-backoff cannot be negative")
+	}
+	if cfg.HealthStaleSyncIntervals < 0 {
+		return errors.New("--health-stale-sync-intervals cannot be negative")
+	}
+	// a failed synchronization is retried after up to --max-failure-backoff plus 20% jitter, a shorter window
+	// fails the health check before the retry had a chance to succeed
+	if cfg.HealthStaleSyncIntervals > 0 && time.Duration(cfg.HealthStaleSyncIntervals)*cfg.Interval <= cfg.MaxFailureBackoff+cfg.MaxFailureBackoff/5 {
+		return errors.New("--health-stale-sync-intervals times --interval must exceed --max-failure-backoff plus 20% jitter")
+	}
+	if cfg.SafetyMaxDeletes < 0 || cfg.SafetyMaxUpdates < 0 {
+		return errors.New("--safety-max-deletes and --safety-max-updates cannot be negative")
+	}
+	if cfg.SafetyMaxDeletePercentage < 0 || cfg.SafetyMaxDeletePercentage > 100 {
+		return errors.New("--safety-max-delete-percentage must be between 0 and 100")
+	}
+	if cfg.SafetyMaxUpdatePercentage < 0 || cfg.SafetyMaxUpdatePercentage > 100 {
+		return errors.New("--safety-max-update-percentage must be between 0 and 100")
+	}
+
+	for _, recordType := range cfg.ManagedDNSRecordTypes {
+		if err := validateManagedRecordType(cfg, recordType); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Command == "migrate-registry" {
+		return validateRegistryMigration(cfg)
+	}
+
 	return nil
 }
+
+// validateRegistryMigration checks that the migrate-registry command has a target registry
+// configuration different from the current one
+func validateRegistryMigration(cfg *externaldns.Config) error {
+	if cfg.MigrateToRegistry == "" {
+		return errors.New("no registry to migrate to specified")
+	}
+	if len(cfg.MigrateToTXTPrefix) > 0 && len(cfg.MigrateToTXTSuffix) > 0 {
+		return errors.New("migrate-to-txt-prefix and migrate-to-txt-suffix are mutual exclusive")
+	}
+	if cfg.MigrateToRegistry == "aws-sd" && cfg.Provider != "aws-sd" {
+		return errors.New("the aws-sd registry can only be used with the aws-sd provider")
+	}
+	if cfg.MigrateToRegistry != cfg.Registry {
+		return nil
+	}
+
+	unchanged := true
+	switch cfg.Registry {
+	case "txt":
+		unchanged = cfg.MigrateToTXTPrefix == cfg.TXTPrefix && cfg.MigrateToTXTSuffix == cfg.TXTSuffix && cfg.MigrateToTXTWildcard == cfg.TXTWildcardReplacement
+	case "configmap":
+		unchanged = cfg.MigrateToConfigMapNamespace == cfg.ConfigMapRegistryNamespace && cfg.MigrateToConfigMapName == cfg.ConfigMapRegistryName
+	}
+	if unchanged {
+		return errors.New("the registry to migrate to must differ from the current registry")
+	}
+	return nil
+}
+
+// validateManagedRecordType checks that a record type passed to --managed-record-types
+// can be handled by the selected registry and provider
+func validateManagedRecordType(cfg *externaldns.Config, recordType string) error {
+	if !provider.SupportedRecordType(recordType) {
+		return fmt.Errorf("unsupported managed record type: %s", recordType)
+	}
+	if recordType == endpoint.RecordTypeTXT && cfg.Registry == "txt" {
+		return errors.New("TXT records cannot be managed when using the txt registry")
+	}
+	supported, restricted := restrictedRecordTypeProviders[cfg.Provider]
+	if !restricted {
+		return nil
+	}
+	for _, t := range supported {
+		if t == recordType {
+			return nil
+		}
+	}
+	return fmt.Errorf("record type %s is not supported by the %s provider", recordType, cfg.Provider)
+}
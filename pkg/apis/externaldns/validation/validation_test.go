@@ -18,7 +18,9 @@ package validation
 
 import (
 	"testing"
+	"time"
 
+	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
 
 	"github.com/stretchr/testify/assert"
@@ -279,3 +281,186 @@ func TestValidateGoodRfc2136GssTsigConfig(t *testing.T) {
 		assert.Nil(t, err)
 	}
 }
+
+func TestValidateLeaderElection(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.LeaderElection = true
+	cfg.LeaderElectionLeaseDuration = 15 * time.Second
+	cfg.LeaderElectionRenewDeadline = 10 * time.Second
+	cfg.LeaderElectionRetryPeriod = 2 * time.Second
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.LeaderElectionRenewDeadline = 15 * time.Second
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.LeaderElectionRenewDeadline = 10 * time.Second
+	cfg.LeaderElectionRetryPeriod = 10 * time.Second
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateSafetyThresholds(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.SafetyMaxDeletes = 10
+	cfg.SafetyMaxDeletePercentage = 100
+	cfg.SafetyMaxUpdatePercentage = 50
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.SafetyMaxDeletes = -1
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.SafetyMaxDeletePercentage = 101
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.SafetyMaxUpdatePercentage = -5
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateDeletionGracePeriod(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.DeletionGracePeriod = 5 * time.Minute
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.DeletionGracePeriod = -time.Minute
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateMaxFailureBackoff(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.MaxFailureBackoff = 0
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.MaxFailureBackoff = -time.Minute
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateHealthStaleSyncIntervals(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.HealthStaleSyncIntervals = 13
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.HealthStaleSyncIntervals = -1
+	assert.Error(t, ValidateConfig(cfg))
+
+	// the window has to cover the backoff after failures
+	cfg.HealthStaleSyncIntervals = 3
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.MaxFailureBackoff = 0
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateManagedRecordTypes(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.Provider = "aws"
+	cfg.ManagedDNSRecordTypes = []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME, endpoint.RecordTypeNS}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.ManagedDNSRecordTypes = []string{endpoint.RecordTypeA, "MX"}
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.Registry = "txt"
+	cfg.ManagedDNSRecordTypes = []string{endpoint.RecordTypeTXT}
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.Registry = "noop"
+	cfg.ManagedDNSRecordTypes = []string{endpoint.RecordTypeTXT}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.Provider = "rfc2136"
+	cfg.RFC2136BatchChangeSize = 50
+	cfg.ManagedDNSRecordTypes = []string{endpoint.RecordTypeA, endpoint.RecordTypeNS}
+	assert.Error(t, ValidateConfig(cfg))
+	cfg.ManagedDNSRecordTypes = []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.Provider = "aws-sd"
+	cfg.ManagedDNSRecordTypes = []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA}
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateOwnershipClaims(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.AdoptDomains = []string{"legacy.example.org"}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Registry = "noop"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.TXTOwnerID = "new"
+	cfg.TXTPreviousOwnerID = "old"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.TXTPreviousOwnerID = "new"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.TXTPreviousOwnerID = "old"
+	cfg.Registry = "configmap"
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateTXTProtection(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.TXTEncrypt = true
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.TXTKeyFile = "/etc/external-dns/txt.key"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.TXTKeySecret = "kube-system/txt-key"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.TXTKeyFile = ""
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.TXTKeySecret = "txt-key"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.TXTKeySecret = "kube-system/txt-key"
+	cfg.Registry = "configmap"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.TXTSign = true
+	cfg.TXTKeySecret = "kube-system/txt-key"
+	cfg.TXTAllowUnprotected = true
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.TXTSign = false
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateRegistryMigration(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.Command = "migrate-registry"
+	cfg.Sources = nil
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.MigrateToRegistry = "configmap"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.MigrateToRegistry = "txt"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.MigrateToTXTPrefix = "new-"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.MigrateToTXTSuffix = "-new"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg = newValidConfig(t)
+	cfg.Command = "migrate-registry"
+	cfg.Registry = "configmap"
+	cfg.MigrateToRegistry = "configmap"
+	assert.Error(t, ValidateConfig(cfg))
+
+	cfg.MigrateToConfigMapNamespace = "kube-system"
+	assert.NoError(t, ValidateConfig(cfg))
+}
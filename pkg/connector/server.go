@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package connector implements the server side of version 1 of the HTTP protocol of the connector source.
+//
+// The endpoints are served as JSON at EndpointsPath. A request with the WaitParameter set to the version
+// of the endpoints it knows long-polls: it's answered as soon as the endpoints have another version, or with
+// 304 Not Modified once the timeout passed.
+package connector
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// EndpointsPath is the path the endpoints are served at
+	EndpointsPath = "/v1/endpoints"
+	// WaitParameter is the query parameter with the version of the endpoints a long-polling request waits to change
+	WaitParameter = "wait"
+	// TimeoutParameter is the query parameter with the seconds a long-polling request waits at most
+	TimeoutParameter = "timeout"
+	// DefaultMaxPollTimeout is the longest time long-polling requests wait by default
+	DefaultMaxPollTimeout = 5 * time.Minute
+)
+
+// EndpointsResponse is the JSON body of the responses with the endpoints.
+type EndpointsResponse struct {
+	// Version changes whenever the endpoints change
+	Version   string               `json:"version"`
+	Endpoints []*endpoint.Endpoint `json:"endpoints"`
+}
+
+// Server serves endpoints to the connector source. It is safe for concurrent use.
+type Server struct {
+	token          string
+	maxPollTimeout time.Duration
+
+	mux sync.Mutex
+	// generation distinguishes the versions of different instances of the server, e.g. after a restart
+	generation int64
+	revision   int64
+	endpoints  []*endpoint.Endpoint
+	// changed is closed when the endpoints change
+	changed chan struct{}
+}
+
+// NewServer creates a Server which requires requests to present the bearer token, if one is given. Long-polling
+// requests wait at most maxPollTimeout, or DefaultMaxPollTimeout if it is zero.
+func NewServer(token string, maxPollTimeout time.Duration) *Server {
+	if maxPollTimeout <= 0 {
+		maxPollTimeout = DefaultMaxPollTimeout
+	}
+	return &Server{
+		token:          token,
+		maxPollTimeout: maxPollTimeout,
+		generation:     time.Now().UnixNano(),
+		changed:        make(chan struct{}),
+	}
+}
+
+// SetEndpoints sets the endpoints served and wakes up the long-polling requests. The endpoints must not be
+// modified afterwards. Setting the same endpoints again doesn't change the version. Until the endpoints are
+// set the first time, requests are answered with 503 Service Unavailable, so that ExternalDNS doesn't delete
+// any records while the producer is starting.
+func (s *Server) SetEndpoints(endpoints []*endpoint.Endpoint) {
+	if endpoints == nil {
+		endpoints = []*endpoint.Endpoint{}
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.endpoints != nil && reflect.DeepEqual(s.endpoints, endpoints) {
+		return
+	}
+	s.endpoints = endpoints
+	s.revision++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// current returns the endpoints, their version and a channel which is closed when they change
+func (s *Server) current() (*EndpointsResponse, <-chan struct{}) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.endpoints == nil {
+		return nil, s.changed
+	}
+	return &EndpointsResponse{
+		Version:   fmt.Sprintf("%d-%d", s.generation, s.revision),
+		Endpoints: s.endpoints,
+	}, s.changed
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != EndpointsPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	response, changed := s.current()
+
+	query := r.URL.Query()
+	if wait, ok := query[WaitParameter]; ok {
+		timeout := s.maxPollTimeout
+		if seconds, err := strconv.Atoi(query.Get(TimeoutParameter)); err == nil && seconds > 0 && time.Duration(seconds)*time.Second < timeout {
+			timeout = time.Duration(seconds) * time.Second
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		for response == nil || response.Version == wait[0] {
+			select {
+			case <-changed:
+				response, changed = s.current()
+			case <-timer.C:
+				w.WriteHeader(http.StatusNotModified)
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+
+	if response == nil {
+		http.Error(w, "endpoints aren't available yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Warnf("Failed to write the endpoints: %v", err)
+	}
+}
+
+// authorized returns whether the request presents the token, if one is required
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// NewServerTLSConfig creates a tls.Config for serving with the certificate and key. If the path of a CA is
+// given, clients must present a certificate signed by it.
+func NewServerTLSConfig(certPath, keyPath, clientCAPath string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS cert: %s", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCAPath == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(clientCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", clientCAPath, err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("could not read client CA certs from %s", clientCAPath)
+	}
+	config.ClientCAs = clientCAs
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
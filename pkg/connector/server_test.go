@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func get(t *testing.T, server *Server, target string, header http.Header) (*httptest.ResponseRecorder, *EndpointsResponse) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec, nil
+	}
+	response := &EndpointsResponse{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(response))
+	return rec, response
+}
+
+func TestServerEndpoints(t *testing.T) {
+	server := NewServer("", 0)
+
+	rec, _ := get(t, server, EndpointsPath, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "endpoints must not be served before they are set")
+
+	endpoints := []*endpoint.Endpoint{{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA}}
+	server.SetEndpoints(endpoints)
+	rec, response := get(t, server, EndpointsPath, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, endpoints, response.Endpoints)
+	version := response.Version
+
+	server.SetEndpoints([]*endpoint.Endpoint{{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA}})
+	_, response = get(t, server, EndpointsPath, nil)
+	assert.Equal(t, version, response.Version, "setting the same endpoints must not change the version")
+
+	server.SetEndpoints(nil)
+	_, response = get(t, server, EndpointsPath, nil)
+	assert.NotEqual(t, version, response.Version)
+	assert.Empty(t, response.Endpoints)
+	assert.NotNil(t, response.Endpoints)
+
+	rec, _ = get(t, server, "/v2/endpoints", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, EndpointsPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestServerToken(t *testing.T) {
+	server := NewServer("secret", 0)
+	server.SetEndpoints(nil)
+
+	for _, tc := range []struct {
+		title         string
+		authorization string
+		expected      int
+	}{
+		{title: "no token", expected: http.StatusUnauthorized},
+		{title: "wrong token", authorization: "Bearer other", expected: http.StatusUnauthorized},
+		{title: "token without scheme", authorization: "secret", expected: http.StatusUnauthorized},
+		{title: "token", authorization: "Bearer secret", expected: http.StatusOK},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			header := http.Header{}
+			if tc.authorization != "" {
+				header.Set("Authorization", tc.authorization)
+			}
+			rec, _ := get(t, server, EndpointsPath, header)
+			assert.Equal(t, tc.expected, rec.Code)
+		})
+	}
+}
+
+func TestServerLongPoll(t *testing.T) {
+	server := NewServer("", 0)
+	server.SetEndpoints(nil)
+	_, response := get(t, server, EndpointsPath, nil)
+
+	// an outdated version is answered right away
+	_, outdated := get(t, server, EndpointsPath+"?wait=outdated", nil)
+	assert.Equal(t, response.Version, outdated.Version)
+
+	// the current version is answered once the endpoints change
+	endpoints := []*endpoint.Endpoint{{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA}}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		server.SetEndpoints(endpoints)
+	}()
+	rec, changed := get(t, server, EndpointsPath+"?wait="+response.Version, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, response.Version, changed.Version)
+	assert.Equal(t, endpoints, changed.Endpoints)
+
+	// or not modified after the timeout
+	rec, _ = get(t, server, EndpointsPath+"?timeout=1&wait="+changed.Version, nil)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestServerLongPollBeforeEndpointsAreSet(t *testing.T) {
+	server := NewServer("", time.Second)
+
+	rec, _ := get(t, server, EndpointsPath+"?wait=", nil)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		server.SetEndpoints(nil)
+	}()
+	rec, _ = get(t, server, EndpointsPath+"?wait=", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
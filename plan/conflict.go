@@ -18,10 +18,27 @@ package plan
 
 import (
 	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
+var conflicts = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "plan",
+		Name:      "conflicts",
+		Help:      "Number of DNS names claimed by more than one resource in the last plan calculation.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(conflicts)
+}
+
 // ConflictResolver is used to make a decision in case of two or more different kubernetes resources
 // are trying to acquire same DNS name
 type ConflictResolver interface {
@@ -29,6 +46,13 @@ type ConflictResolver interface {
 	ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint
 }
 
+// ConflictResolvers is a registry of available conflict resolvers.
+var ConflictResolvers = map[string]ConflictResolver{
+	"per-resource":       PerResource{},
+	"ownership-priority": OwnershipPriority{},
+	"oldest-wins":        OldestWins{},
+}
+
 // PerResource allows only one resource to own a given dns name
 type PerResource struct{}
 
@@ -67,4 +91,118 @@ func (s PerResource) less(x, y *endpoint.Endpoint) bool {
 	return x.Targets.IsLess(y.Targets)
 }
 
-// TODO: with cross-resource/cross-cluster setup alternative variations of ConflictResolver can be used
+// OwnershipPriority allows only one resource to own a given dns name and prefers
+// the resource with the highest ownership priority label. Resources without the
+// label have priority 0. Ties are resolved like PerResource.
+type OwnershipPriority struct{}
+
+// ResolveCreate picks the candidate with the highest priority
+func (s OwnershipPriority) ResolveCreate(candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	var best *endpoint.Endpoint
+	for _, ep := range candidates {
+		if best == nil || s.less(ep, best) {
+			best = ep
+		}
+	}
+	return best
+}
+
+// ResolveUpdate keeps the resource which already owns the dns name unless
+// another resource has a strictly higher priority
+func (s OwnershipPriority) ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	currentResource := current.Labels[endpoint.ResourceLabelKey]
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return s.less(candidates[i], candidates[j])
+	})
+	best := s.ResolveCreate(candidates)
+	for _, ep := range candidates {
+		if ep.Labels[endpoint.ResourceLabelKey] == currentResource && ownershipPriority(ep) >= ownershipPriority(best) {
+			return ep
+		}
+	}
+	return best
+}
+
+// less returns true if endpoint x should be preferred over y
+func (s OwnershipPriority) less(x, y *endpoint.Endpoint) bool {
+	px, py := ownershipPriority(x), ownershipPriority(y)
+	if px != py {
+		return px > py
+	}
+	return PerResource{}.less(x, y)
+}
+
+// ownershipPriority returns the priority of an endpoint, defaulting to 0
+func ownershipPriority(ep *endpoint.Endpoint) int {
+	priority, err := strconv.Atoi(ep.Labels[endpoint.OwnershipPriorityLabelKey])
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+// OldestWins allows only one resource to own a given dns name and prefers the
+// resource which was created first, as recorded in the creation timestamp label.
+// Resources without the label are considered newer than all others. Ties are
+// resolved like PerResource.
+type OldestWins struct{}
+
+// ResolveCreate picks the candidate created first
+func (s OldestWins) ResolveCreate(candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	var oldest *endpoint.Endpoint
+	for _, ep := range candidates {
+		if oldest == nil || s.less(ep, oldest) {
+			oldest = ep
+		}
+	}
+	return oldest
+}
+
+// ResolveUpdate picks the candidate created first, preferring the resource
+// which already owns the dns name if it was created at the same time
+func (s OldestWins) ResolveUpdate(current *endpoint.Endpoint, candidates []*endpoint.Endpoint) *endpoint.Endpoint {
+	currentResource := current.Labels[endpoint.ResourceLabelKey]
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return s.less(candidates[i], candidates[j])
+	})
+	oldest := s.ResolveCreate(candidates)
+	for _, ep := range candidates {
+		if ep.Labels[endpoint.ResourceLabelKey] == currentResource && !s.createdBefore(oldest, ep) {
+			return ep
+		}
+	}
+	return oldest
+}
+
+// less returns true if endpoint x should be preferred over y
+func (s OldestWins) less(x, y *endpoint.Endpoint) bool {
+	if s.createdBefore(x, y) {
+		return true
+	}
+	if s.createdBefore(y, x) {
+		return false
+	}
+	return PerResource{}.less(x, y)
+}
+
+// createdBefore returns true if the resource of endpoint x was created before the one of y
+func (s OldestWins) createdBefore(x, y *endpoint.Endpoint) bool {
+	cx, okx := creationTimestamp(x)
+	cy, oky := creationTimestamp(y)
+	if !okx {
+		return false
+	}
+	if !oky {
+		return true
+	}
+	return cx.Before(cy)
+}
+
+// creationTimestamp returns the creation timestamp of the resource of an endpoint
+func creationTimestamp(ep *endpoint.Endpoint) (time.Time, bool) {
+	created, err := time.Parse(time.RFC3339, ep.Labels[endpoint.CreationTimestampLabelKey])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return created, true
+}
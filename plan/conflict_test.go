@@ -25,6 +25,8 @@ import (
 )
 
 var _ ConflictResolver = PerResource{}
+var _ ConflictResolver = OwnershipPriority{}
+var _ ConflictResolver = OldestWins{}
 
 type ResolverSuite struct {
 	// resolvers
@@ -133,6 +135,66 @@ func (suite *ResolverSuite) TestStrictResolver() {
 	suite.Equal(suite.bar127A, suite.perResource.ResolveUpdate(suite.legacyBar192A, []*endpoint.Endpoint{suite.bar127A, suite.bar192A}), " legacy record's resource value will not match, should pick minimum")
 }
 
+func (suite *ResolverSuite) TestOwnershipPriorityResolver() {
+	resolver := OwnershipPriority{}
+	fooV2High := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v2"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey:          "ingress/default/foo-v2",
+			endpoint.OwnershipPriorityLabelKey: "10",
+		},
+	}
+	fooV3Low := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v3"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey:          "ingress/default/foo-v3",
+			endpoint.OwnershipPriorityLabelKey: "-1",
+		},
+	}
+
+	suite.Equal(fooV2High, resolver.ResolveCreate([]*endpoint.Endpoint{suite.fooV1Cname, fooV2High}), "should pick highest priority")
+	suite.Equal(suite.fooV1Cname, resolver.ResolveCreate([]*endpoint.Endpoint{fooV3Low, suite.fooV1Cname}), "missing priority should rank as 0")
+	suite.Equal(suite.fooV1Cname, resolver.ResolveCreate([]*endpoint.Endpoint{suite.fooV2Cname, suite.fooV1Cname}), "should pick min one on same priority")
+
+	suite.Equal(fooV2High, resolver.ResolveUpdate(suite.fooV1Cname, []*endpoint.Endpoint{suite.fooV1Cname, fooV2High}), "should take over from lower priority owner")
+	suite.Equal(suite.fooV2Cname, resolver.ResolveUpdate(suite.fooV2Cname, []*endpoint.Endpoint{suite.fooV1Cname, suite.fooV2Cname}), "should keep owner on same priority")
+	suite.Equal(suite.fooV1Cname, resolver.ResolveUpdate(fooV3Low, []*endpoint.Endpoint{suite.fooV1Cname}), "should pick new if resource was deleted")
+}
+
+func (suite *ResolverSuite) TestOldestWinsResolver() {
+	resolver := OldestWins{}
+	fooOld := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v3"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey:          "ingress/default/foo-old",
+			endpoint.CreationTimestampLabelKey: "2020-01-01T00:00:00Z",
+		},
+	}
+	fooNew := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v2"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey:          "ingress/default/foo-new",
+			endpoint.CreationTimestampLabelKey: "2021-01-01T00:00:00Z",
+		},
+	}
+
+	suite.Equal(fooOld, resolver.ResolveCreate([]*endpoint.Endpoint{fooNew, fooOld}), "should pick oldest")
+	suite.Equal(fooNew, resolver.ResolveCreate([]*endpoint.Endpoint{suite.fooV1Cname, fooNew}), "missing timestamp should rank as newest")
+	suite.Equal(suite.fooV1Cname, resolver.ResolveCreate([]*endpoint.Endpoint{suite.fooV2Cname, suite.fooV1Cname}), "should pick min one without timestamps")
+
+	suite.Equal(fooOld, resolver.ResolveUpdate(fooNew, []*endpoint.Endpoint{fooNew, fooOld}), "should pick oldest over current owner")
+	suite.Equal(fooOld, resolver.ResolveUpdate(fooOld, []*endpoint.Endpoint{fooNew, fooOld}), "should keep oldest owner")
+	suite.Equal(suite.fooV2Cname, resolver.ResolveUpdate(suite.fooV2Cname, []*endpoint.Endpoint{suite.fooV1Cname, suite.fooV2Cname}), "should keep owner without timestamps")
+}
+
 func TestConflictResolver(t *testing.T) {
 	suite.Run(t, new(ResolverSuite))
 }
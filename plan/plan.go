@@ -48,6 +48,10 @@ type Plan struct {
 	PropertyComparator PropertyComparator
 	// DNS record types that will be considered for management
 	ManagedRecords []string
+	// ConflictResolver decides which resource acquires a DNS name claimed by several ones, defaults to PerResource
+	ConflictResolver ConflictResolver
+	// AdoptionDomainFilter matches the DNS names of records without owner which may be adopted, adoption is disabled when nil
+	AdoptionDomainFilter endpoint.DomainFilterInterface
 }
 
 // Changes holds lists of actions to be executed by dns providers
@@ -80,8 +84,11 @@ type planTable struct {
 	resolver ConflictResolver
 }
 
-func newPlanTable() planTable { //TODO: make resolver configurable
-	return planTable{map[string]map[string]*planTableRow{}, PerResource{}}
+func newPlanTable(resolver ConflictResolver) planTable {
+	if resolver == nil {
+		resolver = PerResource{}
+	}
+	return planTable{map[string]map[string]*planTableRow{}, resolver}
 }
 
 // planTableRow
@@ -96,26 +103,62 @@ func (t planTableRow) String() string {
 	return fmt.Sprintf("planTableRow{current=%v, candidates=%v}", t.current, t.candidates)
 }
 
+// resources returns the distinct resources claiming the dns name of the row
+func (t planTableRow) resources() []string {
+	seen := map[string]bool{}
+	resources := []string{}
+	for _, ep := range t.candidates {
+		resource := ep.Labels[endpoint.ResourceLabelKey]
+		if !seen[resource] {
+			seen[resource] = true
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// reportConflict logs dns names which are claimed by more than one resource and returns whether there is a conflict
+func reportConflict(row *planTableRow, resolved *endpoint.Endpoint) bool {
+	resources := row.resources()
+	if len(resources) < 2 {
+		return false
+	}
+	log.Infof("Conflicting resources %v claim %s, resolved in favor of %q", resources, resolved.DNSName, resolved.Labels[endpoint.ResourceLabelKey])
+	return true
+}
+
 func (t planTable) addCurrent(e *endpoint.Endpoint) {
 	dnsName := normalizeDNSName(e.DNSName)
+	key := rowKey(e)
 	if _, ok := t.rows[dnsName]; !ok {
 		t.rows[dnsName] = make(map[string]*planTableRow)
 	}
-	if _, ok := t.rows[dnsName][e.SetIdentifier]; !ok {
-		t.rows[dnsName][e.SetIdentifier] = &planTableRow{}
+	if _, ok := t.rows[dnsName][key]; !ok {
+		t.rows[dnsName][key] = &planTableRow{}
 	}
-	t.rows[dnsName][e.SetIdentifier].current = e
+	t.rows[dnsName][key].current = e
 }
 
 func (t planTable) addCandidate(e *endpoint.Endpoint) {
 	dnsName := normalizeDNSName(e.DNSName)
+	key := rowKey(e)
 	if _, ok := t.rows[dnsName]; !ok {
 		t.rows[dnsName] = make(map[string]*planTableRow)
 	}
-	if _, ok := t.rows[dnsName][e.SetIdentifier]; !ok {
-		t.rows[dnsName][e.SetIdentifier] = &planTableRow{}
+	if _, ok := t.rows[dnsName][key]; !ok {
+		t.rows[dnsName][key] = &planTableRow{}
+	}
+	t.rows[dnsName][key].candidates = append(t.rows[dnsName][key].candidates, e)
+}
+
+// rowKey returns the key of the row of an endpoint within its dns name, which
+// is its set identifier. AAAA records get separate rows as they can exist next
+// to the A record of the same name.
+func rowKey(e *endpoint.Endpoint) string {
+	if e.RecordType == endpoint.RecordTypeAAAA {
+		return e.SetIdentifier + "/" + endpoint.RecordTypeAAAA
 	}
-	t.rows[dnsName][e.SetIdentifier].candidates = append(t.rows[dnsName][e.SetIdentifier].candidates, e)
+	return e.SetIdentifier
 }
 
 func (c *Changes) HasChanges() bool {
@@ -129,13 +172,14 @@ func (c *Changes) HasChanges() bool {
 // state. It then passes those changes to the current policy for further
 // processing. It returns a copy of Plan with the changes populated.
 func (p *Plan) Calculate() *Plan {
-	t := newPlanTable()
+	t := newPlanTable(p.ConflictResolver)
 
 	if p.DomainFilter == nil {
 		p.DomainFilter = endpoint.MatchAllDomainFilters(nil)
 	}
 
-	for _, current := range filterRecordsForPlan(p.Current, p.DomainFilter, p.ManagedRecords) {
+	currentRecords := filterRecordsForPlan(p.Current, p.DomainFilter, p.ManagedRecords)
+	for _, current := range currentRecords {
 		t.addCurrent(current)
 	}
 	for _, desired := range filterRecordsForPlan(p.Desired, p.DomainFilter, p.ManagedRecords) {
@@ -143,11 +187,23 @@ func (p *Plan) Calculate() *Plan {
 	}
 
 	changes := &Changes{}
+	// ownership records the registry found without a matching record aren't part of the plan table
+	// as they have no desired counterpart, they are always deleted
+	for _, current := range p.Current {
+		if _, ok := current.Labels[endpoint.OrphanedOwnershipLabelKey]; ok && p.DomainFilter.Match(current.DNSName) {
+			changes.Delete = append(changes.Delete, current)
+		}
+	}
 
+	conflictCount := 0
 	for _, topRow := range t.rows {
 		for _, row := range topRow {
 			if row.current == nil { //dns name not taken
-				changes.Create = append(changes.Create, t.resolver.ResolveCreate(row.candidates))
+				create := t.resolver.ResolveCreate(row.candidates)
+				if reportConflict(row, create) {
+					conflictCount++
+				}
+				changes.Create = append(changes.Create, create)
 			}
 			if row.current != nil && len(row.candidates) == 0 {
 				changes.Delete = append(changes.Delete, row.current)
@@ -156,8 +212,16 @@ func (p *Plan) Calculate() *Plan {
 			// TODO: allows record type change, which might not be supported by all dns providers
 			if row.current != nil && len(row.candidates) > 0 { //dns name is taken
 				update := t.resolver.ResolveUpdate(row.current, row.candidates)
+				if reportConflict(row, update) {
+					conflictCount++
+				}
+				// registries adopt records for updates which still carry the adopt label
+				if _, ok := update.Labels[endpoint.AdoptLabelKey]; ok && !p.shouldAdopt(update, row.current) {
+					update = update.DeepCopy()
+					delete(update.Labels, endpoint.AdoptLabelKey)
+				}
 				// compare "update" to "current" to figure out if actual update is required
-				if shouldUpdateTTL(update, row.current) || targetChanged(update, row.current) || p.shouldUpdateProviderSpecific(update, row.current) {
+				if shouldUpdateTTL(update, row.current) || targetChanged(update, row.current) || p.shouldUpdateProviderSpecific(update, row.current) || p.shouldClaimOwnership(update, row.current) {
 					inheritOwner(row.current, update)
 					changes.UpdateNew = append(changes.UpdateNew, update)
 					changes.UpdateOld = append(changes.UpdateOld, row.current)
@@ -166,20 +230,44 @@ func (p *Plan) Calculate() *Plan {
 			}
 		}
 	}
+	conflicts.Set(float64(conflictCount))
 	for _, pol := range p.Policies {
+		if cp, ok := pol.(CurrentAwarePolicy); ok {
+			changes = cp.ApplyWithCurrent(changes, currentRecords)
+			continue
+		}
 		changes = pol.Apply(changes)
 	}
 
 	plan := &Plan{
-		Current:        p.Current,
-		Desired:        p.Desired,
-		Changes:        changes,
-		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		Current:          p.Current,
+		Desired:          p.Desired,
+		Changes:          changes,
+		ManagedRecords:   p.ManagedRecords,
+		ConflictResolver: p.ConflictResolver,
 	}
 
 	return plan
 }
 
+// shouldClaimOwnership returns whether the current record changes its owner even if nothing else changed,
+// because it is handed over from the previous owner or adopted
+func (p *Plan) shouldClaimOwnership(desired, current *endpoint.Endpoint) bool {
+	if _, ok := current.Labels[endpoint.PreviousOwnerLabelKey]; ok {
+		return true
+	}
+	return p.shouldAdopt(desired, current)
+}
+
+// shouldAdopt returns whether the current record without owner is adopted by a resource which allows it
+// and whose DNS name matches the adoption domain filter
+func (p *Plan) shouldAdopt(desired, current *endpoint.Endpoint) bool {
+	if p.AdoptionDomainFilter == nil || current.Labels[endpoint.OwnerLabelKey] != "" || desired.Labels[endpoint.AdoptLabelKey] != "true" {
+		return false
+	}
+	return p.AdoptionDomainFilter.Match(current.DNSName)
+}
+
 func inheritOwner(from, to *endpoint.Endpoint) {
 	if to.Labels == nil {
 		to.Labels = map[string]string{}
@@ -237,6 +325,7 @@ func (p *Plan) shouldUpdateProviderSpecific(desired, current *endpoint.Endpoint)
 // filterRecordsForPlan removes records that are not relevant to the planner.
 // Currently this just removes TXT records to prevent them from being
 // deleted erroneously by the planner (only the TXT registry should do this.)
+// Orphaned ownership records are deleted separately.
 //
 // Per RFC 1034, CNAME records conflict with all other records - it is the
 // only record with this property. The behavior of the planner may need to be
@@ -250,6 +339,9 @@ func filterRecordsForPlan(records []*endpoint.Endpoint, domainFilter endpoint.Do
 			log.Debugf("ignoring record %s that does not match domain filter", record.DNSName)
 			continue
 		}
+		if _, ok := record.Labels[endpoint.OrphanedOwnershipLabelKey]; ok {
+			continue
+		}
 		if isManagedRecord(record.RecordType, managedRecords) {
 			filtered = append(filtered, record)
 		}
@@ -461,6 +461,138 @@ func (suite *PlanTestSuite) TestIgnoreTXT() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+func (suite *PlanTestSuite) TestOrphanedOwnershipRecords() {
+	orphan := &endpoint.Endpoint{
+		DNSName:    "txt.foo",
+		Targets:    endpoint.Targets{"\"heritage=external-dns,external-dns/owner=pwner\""},
+		RecordType: endpoint.RecordTypeTXT,
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey:             "pwner",
+			endpoint.OrphanedOwnershipLabelKey: "foo",
+		},
+	}
+	current := []*endpoint.Endpoint{suite.fooV2TXT, orphan}
+	desired := []*endpoint.Endpoint{}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{orphan}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestAdoption() {
+	unowned := &endpoint.Endpoint{
+		DNSName:    "legacy.example.org",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: endpoint.RecordTypeCNAME,
+		Labels:     map[string]string{},
+	}
+	adopting := &endpoint.Endpoint{
+		DNSName:    "legacy.example.org",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: endpoint.RecordTypeCNAME,
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/legacy",
+			endpoint.AdoptLabelKey:    "true",
+		},
+	}
+	current := []*endpoint.Endpoint{unowned}
+	desired := []*endpoint.Endpoint{adopting}
+
+	// adoption is disabled without domains
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+	changes := p.Calculate().Changes
+	suite.False(changes.HasChanges())
+
+	p.AdoptionDomainFilter = endpoint.NewDomainFilter([]string{"other.org"})
+	changes = p.Calculate().Changes
+	suite.False(changes.HasChanges())
+
+	// the record is updated so that the registry adopts it
+	p.AdoptionDomainFilter = endpoint.NewDomainFilter([]string{"example.org"})
+	changes = p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), changes.UpdateOld, []*endpoint.Endpoint{unowned})
+	validateEntries(suite.T(), changes.UpdateNew, []*endpoint.Endpoint{adopting})
+	validateEntries(suite.T(), changes.Delete, []*endpoint.Endpoint{})
+}
+
+func (suite *PlanTestSuite) TestAdoptionNotAllowed() {
+	unowned := &endpoint.Endpoint{
+		DNSName:    "legacy.example.org",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: endpoint.RecordTypeCNAME,
+	}
+	adopting := &endpoint.Endpoint{
+		DNSName:    "legacy.example.org",
+		Targets:    endpoint.Targets{"v2"},
+		RecordType: endpoint.RecordTypeCNAME,
+		Labels:     map[string]string{endpoint.AdoptLabelKey: "true"},
+	}
+
+	p := &Plan{
+		Policies:             []Policy{&SyncPolicy{}},
+		Current:              []*endpoint.Endpoint{unowned},
+		Desired:              []*endpoint.Endpoint{adopting},
+		ManagedRecords:       []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		AdoptionDomainFilter: endpoint.NewDomainFilter([]string{"other.org"}),
+	}
+
+	// updates of records outside the adoption domains don't carry the adopt label
+	changes := p.Calculate().Changes
+	suite.Require().Len(changes.UpdateNew, 1)
+	suite.NotContains(changes.UpdateNew[0].Labels, endpoint.AdoptLabelKey)
+	suite.Contains(adopting.Labels, endpoint.AdoptLabelKey)
+}
+
+func (suite *PlanTestSuite) TestOwnershipHandover() {
+	previous := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: endpoint.RecordTypeCNAME,
+		Labels: map[string]string{
+			endpoint.OwnerLabelKey:         "old",
+			endpoint.PreviousOwnerLabelKey: "old",
+		},
+	}
+	current := []*endpoint.Endpoint{previous}
+	desired := []*endpoint.Endpoint{suite.fooV1Cname}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{previous}
+	expectedUpdateNew := []*endpoint.Endpoint{suite.fooV1Cname}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
 func (suite *PlanTestSuite) TestIgnoreTargetCase() {
 	current := []*endpoint.Endpoint{suite.fooV2Cname}
 	desired := []*endpoint.Endpoint{suite.fooV2CnameUppercase}
@@ -667,6 +799,120 @@ func (suite *PlanTestSuite) TestDomainFiltersUpdate() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+func (suite *PlanTestSuite) TestManagedRecordTypes() {
+	nsRecord := &endpoint.Endpoint{
+		DNSName:    "delegated.domain.tld",
+		Targets:    endpoint.Targets{"ns1.domain.tld", "ns2.domain.tld"},
+		RecordType: endpoint.RecordTypeNS,
+	}
+	current := []*endpoint.Endpoint{}
+	desired := []*endpoint.Endpoint{nsRecord, suite.bar127A}
+	expectedCreate := []*endpoint.Endpoint{nsRecord, suite.bar127A}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	managedRecords := []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME, endpoint.RecordTypeNS}
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: managedRecords,
+	}
+
+	calculated := p.Calculate()
+	suite.Equal(managedRecords, calculated.ManagedRecords)
+	changes := calculated.Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestConflictResolver() {
+	fooV2Priority := &endpoint.Endpoint{
+		DNSName:    "foo",
+		Targets:    endpoint.Targets{"v2"},
+		RecordType: "CNAME",
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey:          "ingress/default/foo-v2",
+			endpoint.OwnershipPriorityLabelKey: "1",
+		},
+	}
+	current := []*endpoint.Endpoint{}
+	desired := []*endpoint.Endpoint{suite.fooV1Cname, fooV2Priority}
+	expectedCreate := []*endpoint.Endpoint{fooV2Priority}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies:         []Policy{&SyncPolicy{}},
+		Current:          current,
+		Desired:          desired,
+		ManagedRecords:   []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		ConflictResolver: OwnershipPriority{},
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestDualStackRecords() {
+	barAAAA := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"2001:db8::1"},
+		RecordType: endpoint.RecordTypeAAAA,
+		Labels: map[string]string{
+			endpoint.ResourceLabelKey: "ingress/default/bar-127",
+		},
+	}
+	current := []*endpoint.Endpoint{suite.bar127A}
+	desired := []*endpoint.Endpoint{suite.bar127A, barAAAA}
+	expectedCreate := []*endpoint.Endpoint{barAAAA}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
+func (suite *PlanTestSuite) TestSafetyPolicyPercentage() {
+	current := []*endpoint.Endpoint{suite.fooV1Cname, suite.bar127A}
+	desired := []*endpoint.Endpoint{}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{}
+	expectedUpdateNew := []*endpoint.Endpoint{}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies:       []Policy{&SafetyPolicy{Policy: &SyncPolicy{}, MaxDeletePercentage: 50}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
 func TestPlan(t *testing.T) {
 	suite.Run(t, new(PlanTestSuite))
 }
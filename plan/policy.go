@@ -16,11 +16,49 @@ limitations under the License.
 
 package plan
 
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// SafetyOverrideAnnotationKey is the pod annotation which lets held back changes
+// proceed when the pod annotations are exposed as SafetyOverrideFile through the downward API
+const SafetyOverrideAnnotationKey = "external-dns.alpha.kubernetes.io/safety-override"
+
+var heldBackChanges = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "plan",
+		Name:      "held_back_changes",
+		Help:      "Number of changes held back by the safety policy during the last sync, by change type.",
+	},
+	[]string{"type"},
+)
+
+func init() {
+	prometheus.MustRegister(heldBackChanges)
+}
+
 // Policy allows to apply different rules to a set of changes.
 type Policy interface {
 	Apply(changes *Changes) *Changes
 }
 
+// CurrentAwarePolicy is a Policy which also takes the records currently
+// managed by the plan into account, e.g. to limit changes relative to them.
+type CurrentAwarePolicy interface {
+	Policy
+	ApplyWithCurrent(changes *Changes, current []*endpoint.Endpoint) *Changes
+}
+
 // Policies is a registry of available policies.
 var Policies = map[string]Policy{
 	"sync":        &SyncPolicy{},
@@ -57,3 +95,259 @@ func (p *CreateOnlyPolicy) Apply(changes *Changes) *Changes {
 		Create: changes.Create,
 	}
 }
+
+// SafetyPolicy wraps another policy and holds back deletions and updates when
+// their number in a single sync exceeds the configured thresholds, e.g. because
+// a source temporarily returned no endpoints. Creations are never held back.
+// A threshold of 0 disables the corresponding check. Only records owned by
+// OwnerID are counted, as the registry leaves the others alone.
+type SafetyPolicy struct {
+	// Policy is applied before the thresholds are checked
+	Policy Policy
+	// OwnerID is the owner id of the registry applying the changes
+	OwnerID string
+	// MaxDeletes is the maximum number of deletions per sync
+	MaxDeletes int
+	// MaxDeletePercentage is the maximum number of deletions per sync relative to the current records
+	MaxDeletePercentage int
+	// MaxUpdates is the maximum number of updates per sync
+	MaxUpdates int
+	// MaxUpdatePercentage is the maximum number of updates per sync relative to the current records
+	MaxUpdatePercentage int
+	// Override returns true if changes exceeding the thresholds may proceed
+	Override func() bool
+}
+
+// Apply applies the safety policy without relative thresholds.
+func (p *SafetyPolicy) Apply(changes *Changes) *Changes {
+	return p.ApplyWithCurrent(changes, nil)
+}
+
+// ApplyWithCurrent applies the safety policy which strips out deletions and
+// updates exceeding the thresholds unless overridden.
+func (p *SafetyPolicy) ApplyWithCurrent(changes *Changes, current []*endpoint.Endpoint) *Changes {
+	changes = applyPolicy(p.Policy, changes, current)
+
+	result := &Changes{
+		Create:    changes.Create,
+		UpdateOld: changes.UpdateOld,
+		UpdateNew: changes.UpdateNew,
+		Delete:    changes.Delete,
+	}
+	heldBackChanges.WithLabelValues("delete").Set(0)
+	heldBackChanges.WithLabelValues("update").Set(0)
+
+	deletes := p.countOwned(changes.Delete)
+	updates := p.countOwned(changes.UpdateOld)
+	owned := p.countOwned(current)
+	deletesExceeded := exceedsThreshold(deletes, owned, p.MaxDeletes, p.MaxDeletePercentage)
+	updatesExceeded := exceedsThreshold(updates, owned, p.MaxUpdates, p.MaxUpdatePercentage)
+	if !deletesExceeded && !updatesExceeded {
+		return result
+	}
+	if p.Override != nil && p.Override() {
+		log.Warnf("Safety override is active, applying %d deletions and %d updates", deletes, updates)
+		return result
+	}
+
+	if deletesExceeded {
+		log.Warnf("Holding back %d deletions out of %d records, which exceeds the safety thresholds (max %d, max %d%%)", deletes, owned, p.MaxDeletes, p.MaxDeletePercentage)
+		heldBackChanges.WithLabelValues("delete").Set(float64(deletes))
+		result.Delete = nil
+	}
+	if updatesExceeded {
+		log.Warnf("Holding back %d updates out of %d records, which exceeds the safety thresholds (max %d, max %d%%)", updates, owned, p.MaxUpdates, p.MaxUpdatePercentage)
+		heldBackChanges.WithLabelValues("update").Set(float64(updates))
+		result.UpdateOld = nil
+		result.UpdateNew = nil
+	}
+	return result
+}
+
+// countOwned returns the number of records owned by the owner id, including the records handed over
+// from the previous owner. Orphaned ownership records don't hold a record and aren't counted.
+func (p *SafetyPolicy) countOwned(endpoints []*endpoint.Endpoint) int {
+	count := 0
+	for _, ep := range endpoints {
+		if _, ok := ep.Labels[endpoint.OrphanedOwnershipLabelKey]; ok {
+			continue
+		}
+		if _, ok := ep.Labels[endpoint.PreviousOwnerLabelKey]; ok || ep.Labels[endpoint.OwnerLabelKey] == p.OwnerID {
+			count++
+		}
+	}
+	return count
+}
+
+// applyPolicy applies a wrapped policy, passing on the current records if it can use them
+func applyPolicy(policy Policy, changes *Changes, current []*endpoint.Endpoint) *Changes {
+	if cp, ok := policy.(CurrentAwarePolicy); ok {
+		return cp.ApplyWithCurrent(changes, current)
+	}
+	if policy != nil {
+		return policy.Apply(changes)
+	}
+	return changes
+}
+
+// exceedsThreshold returns true if count exceeds the absolute maximum or the
+// maximum percentage of total; zero maximums are ignored
+func exceedsThreshold(count, total, max, maxPercentage int) bool {
+	if max > 0 && count > max {
+		return true
+	}
+	if maxPercentage > 0 && total > 0 && count*100 > total*maxPercentage {
+		return true
+	}
+	return false
+}
+
+// SafetyOverrideFile returns a function for SafetyPolicy.Override which is true
+// while the file at path contains "true", or SafetyOverrideAnnotationKey set to
+// "true" as written by the downward API for pod annotations. Empty files, like the
+// annotations of a pod without any, don't override the thresholds.
+func SafetyOverrideFile(path string) func() bool {
+	return func() bool {
+		f, err := os.Open(path)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.Trim(line, "\"") == "true" {
+				return true
+			}
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) == 2 && kv[0] == SafetyOverrideAnnotationKey && strings.Trim(kv[1], "\"") == "true" {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// DelayedDeletionPolicy wraps another policy and only deletes a record once it
+// has been missing from the sources for longer than the grace period, which
+// avoids DNS flapping while resources are being replaced, e.g. during rollouts.
+//
+// When a record first goes missing, the time is recorded in its
+// endpoint.MissingSinceLabelKey label by updating the record, so that registries
+// persisting labels, like the TXT registry, keep it across restarts. The label is
+// removed again once the record reappears in the sources.
+type DelayedDeletionPolicy struct {
+	// Policy is applied before deletions are delayed
+	Policy Policy
+	// GracePeriod is how long a record has to be missing before it is deleted
+	GracePeriod time.Duration
+
+	now          func() time.Time
+	mu           sync.Mutex
+	missingSince map[string]time.Time
+}
+
+// NewDelayedDeletionPolicy returns a DelayedDeletionPolicy wrapping the given policy.
+func NewDelayedDeletionPolicy(policy Policy, gracePeriod time.Duration) *DelayedDeletionPolicy {
+	return &DelayedDeletionPolicy{
+		Policy:       policy,
+		GracePeriod:  gracePeriod,
+		now:          time.Now,
+		missingSince: map[string]time.Time{},
+	}
+}
+
+// Apply applies the delayed deletion policy without clearing the label of reappeared records.
+func (p *DelayedDeletionPolicy) Apply(changes *Changes) *Changes {
+	return p.ApplyWithCurrent(changes, nil)
+}
+
+// ApplyWithCurrent applies the delayed deletion policy which holds back deletions
+// of records missing for less than the grace period.
+func (p *DelayedDeletionPolicy) ApplyWithCurrent(changes *Changes, current []*endpoint.Endpoint) *Changes {
+	changes = applyPolicy(p.Policy, changes, current)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	result := &Changes{
+		Create:    changes.Create,
+		UpdateOld: changes.UpdateOld,
+		UpdateNew: changes.UpdateNew,
+	}
+
+	missingSince := map[string]time.Time{}
+	for _, ep := range changes.Delete {
+		// the record of an orphaned ownership record is gone already, there is nothing to wait for
+		if _, ok := ep.Labels[endpoint.OrphanedOwnershipLabelKey]; ok {
+			result.Delete = append(result.Delete, ep)
+			continue
+		}
+		key := missingKey(ep)
+		since, known := p.missingSince[key]
+		if label, ok := ep.Labels[endpoint.MissingSinceLabelKey]; ok {
+			if t, err := time.Parse(time.RFC3339, label); err == nil {
+				since, known = t, true
+			} else {
+				log.Warnf("Ignoring invalid %s label %q of %s", endpoint.MissingSinceLabelKey, label, ep.DNSName)
+			}
+		}
+		if !known {
+			since = now
+		}
+		missingSince[key] = since
+
+		if now.Sub(since) >= p.GracePeriod {
+			result.Delete = append(result.Delete, ep)
+			continue
+		}
+		log.Debugf("Delaying deletion of %s (%s) until %s", ep.DNSName, ep.RecordType, since.Add(p.GracePeriod).Format(time.RFC3339))
+		if !known {
+			result.UpdateOld = append(result.UpdateOld, ep)
+			result.UpdateNew = append(result.UpdateNew, withMissingSince(ep, since.UTC().Format(time.RFC3339)))
+		}
+	}
+
+	// records updated by the plan lose the label anyway
+	updated := map[string]bool{}
+	for _, ep := range changes.UpdateOld {
+		updated[missingKey(ep)] = true
+	}
+	for _, ep := range current {
+		key := missingKey(ep)
+		if _, ok := ep.Labels[endpoint.MissingSinceLabelKey]; !ok || updated[key] {
+			continue
+		}
+		if _, ok := missingSince[key]; ok {
+			continue
+		}
+		log.Infof("%s (%s) reappeared, cancelling its deletion", ep.DNSName, ep.RecordType)
+		result.UpdateOld = append(result.UpdateOld, ep)
+		result.UpdateNew = append(result.UpdateNew, withMissingSince(ep, ""))
+	}
+
+	p.missingSince = missingSince
+	return result
+}
+
+// missingKey identifies a record tracked by the DelayedDeletionPolicy
+func missingKey(ep *endpoint.Endpoint) string {
+	return normalizeDNSName(ep.DNSName) + "/" + ep.SetIdentifier + "/" + ep.RecordType
+}
+
+// withMissingSince returns a copy of the endpoint with the missing since label
+// set to the given value, or removed if the value is empty
+func withMissingSince(ep *endpoint.Endpoint, value string) *endpoint.Endpoint {
+	updated := ep.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = endpoint.NewLabels()
+	}
+	if value == "" {
+		delete(updated.Labels, endpoint.MissingSinceLabelKey)
+	} else {
+		updated.Labels[endpoint.MissingSinceLabelKey] = value
+	}
+	return updated
+}
@@ -17,8 +17,15 @@ limitations under the License.
 package plan
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -86,3 +93,243 @@ func validatePolicy(t *testing.T, policy, expected Policy) {
 		t.Errorf("expected %q to match %q", policyType, expectedType)
 	}
 }
+
+// TestSafetyPolicy tests that deletions and updates exceeding the thresholds are held back.
+func TestSafetyPolicy(t *testing.T) {
+	empty := []*endpoint.Endpoint{}
+	fooV1 := []*endpoint.Endpoint{{DNSName: "foo", Targets: endpoint.Targets{"v1"}}}
+	fooV2 := []*endpoint.Endpoint{{DNSName: "foo", Targets: endpoint.Targets{"v2"}}}
+	bar := []*endpoint.Endpoint{{DNSName: "bar", Targets: endpoint.Targets{"v1"}}}
+	baz := []*endpoint.Endpoint{{DNSName: "baz", Targets: endpoint.Targets{"v1"}}}
+	barBaz := append(append([]*endpoint.Endpoint{}, bar...), baz...)
+	current := append(append(append([]*endpoint.Endpoint{}, fooV1...), barBaz...), &endpoint.Endpoint{DNSName: "qux", Targets: endpoint.Targets{"v1"}})
+	owned := []*endpoint.Endpoint{
+		{DNSName: "foo", Targets: endpoint.Targets{"v1"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}},
+		{DNSName: "bar", Targets: endpoint.Targets{"v1"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}},
+	}
+	others := []*endpoint.Endpoint{
+		{DNSName: "baz", Targets: endpoint.Targets{"v1"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "other"}},
+		{DNSName: "qux", Targets: endpoint.Targets{"v1"}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "other"}},
+		{DNSName: "quux", Targets: endpoint.Targets{"v1"}},
+	}
+	orphan := &endpoint.Endpoint{DNSName: "txt.gone", Targets: endpoint.Targets{"\"heritage=external-dns\""}, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.OrphanedOwnershipLabelKey: "gone"}}
+
+	for _, tc := range []struct {
+		title    string
+		policy   *SafetyPolicy
+		current  []*endpoint.Endpoint
+		changes  *Changes
+		expected *Changes
+	}{
+		{
+			title:    "below thresholds",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, MaxDeletes: 2, MaxUpdates: 1},
+			changes:  &Changes{Create: baz, UpdateOld: fooV1, UpdateNew: fooV2, Delete: barBaz},
+			expected: &Changes{Create: baz, UpdateOld: fooV1, UpdateNew: fooV2, Delete: barBaz},
+		},
+		{
+			title:    "too many deletions",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, MaxDeletes: 1},
+			changes:  &Changes{Create: baz, UpdateOld: fooV1, UpdateNew: fooV2, Delete: barBaz},
+			expected: &Changes{Create: baz, UpdateOld: fooV1, UpdateNew: fooV2, Delete: empty},
+		},
+		{
+			title:    "too many updates",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, MaxDeletes: 5, MaxUpdates: 0, MaxUpdatePercentage: 10},
+			current:  current,
+			changes:  &Changes{Create: baz, UpdateOld: fooV1, UpdateNew: fooV2, Delete: barBaz},
+			expected: &Changes{Create: baz, UpdateOld: empty, UpdateNew: empty, Delete: barBaz},
+		},
+		{
+			title:    "deletion percentage exceeded",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, MaxDeletePercentage: 25},
+			current:  current,
+			changes:  &Changes{Delete: barBaz},
+			expected: &Changes{Delete: empty},
+		},
+		{
+			title:    "deletion percentage not exceeded",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, MaxDeletePercentage: 50},
+			current:  current,
+			changes:  &Changes{Delete: barBaz},
+			expected: &Changes{Delete: barBaz},
+		},
+		{
+			title:    "percentage ignored without current records",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, MaxDeletePercentage: 25},
+			changes:  &Changes{Delete: barBaz},
+			expected: &Changes{Delete: barBaz},
+		},
+		{
+			title:    "percentage relative to owned records",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, OwnerID: "owner", MaxDeletePercentage: 50},
+			current:  append(append([]*endpoint.Endpoint{}, owned...), others...),
+			changes:  &Changes{Delete: owned},
+			expected: &Changes{Delete: empty},
+		},
+		{
+			title:    "records of other owners and orphaned ownership records not counted",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, OwnerID: "owner", MaxDeletes: 1},
+			current:  append(append([]*endpoint.Endpoint{}, owned...), others...),
+			changes:  &Changes{Delete: append([]*endpoint.Endpoint{owned[0], orphan}, others...)},
+			expected: &Changes{Delete: append([]*endpoint.Endpoint{owned[0], orphan}, others...)},
+		},
+		{
+			title:    "override",
+			policy:   &SafetyPolicy{Policy: &SyncPolicy{}, MaxDeletes: 1, Override: func() bool { return true }},
+			changes:  &Changes{Delete: barBaz},
+			expected: &Changes{Delete: barBaz},
+		},
+		{
+			title:    "wrapped policy is applied first",
+			policy:   &SafetyPolicy{Policy: &UpsertOnlyPolicy{}, MaxDeletes: 1, MaxUpdates: 1},
+			changes:  &Changes{Create: baz, UpdateOld: fooV1, UpdateNew: fooV2, Delete: barBaz},
+			expected: &Changes{Create: baz, UpdateOld: fooV1, UpdateNew: fooV2, Delete: empty},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			changes := tc.policy.ApplyWithCurrent(tc.changes, tc.current)
+
+			validateEntries(t, changes.Create, tc.expected.Create)
+			validateEntries(t, changes.UpdateOld, tc.expected.UpdateOld)
+			validateEntries(t, changes.UpdateNew, tc.expected.UpdateNew)
+			validateEntries(t, changes.Delete, tc.expected.Delete)
+		})
+	}
+}
+
+func TestSafetyOverrideFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "safety-override")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "override")
+	override := SafetyOverrideFile(path)
+	assert.False(t, override())
+
+	// an empty file, like the annotations of a pod without any, doesn't override the thresholds
+	require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+	assert.False(t, override())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("true\n"), 0644))
+	assert.True(t, override())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("app=\"external-dns\"\n"), 0644))
+	assert.False(t, override())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("app=\"external-dns\"\n"+SafetyOverrideAnnotationKey+"=\"true\"\n"), 0644))
+	assert.True(t, override())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(SafetyOverrideAnnotationKey+"=\"false\"\n"), 0644))
+	assert.False(t, override())
+}
+
+func TestDelayedDeletionPolicy(t *testing.T) {
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	empty := []*endpoint.Endpoint{}
+	bar := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: endpoint.RecordTypeA,
+		Labels:     endpoint.Labels{endpoint.OwnerLabelKey: "owner"},
+	}
+	barMissing := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: endpoint.RecordTypeA,
+		Labels: endpoint.Labels{
+			endpoint.OwnerLabelKey:        "owner",
+			endpoint.MissingSinceLabelKey: start.Format(time.RFC3339),
+		},
+	}
+
+	policy := NewDelayedDeletionPolicy(&SyncPolicy{}, 5*time.Minute)
+	policy.now = func() time.Time { return start }
+
+	// the deletion is turned into an update recording when the record went missing
+	changes := policy.ApplyWithCurrent(&Changes{Delete: []*endpoint.Endpoint{bar}}, []*endpoint.Endpoint{bar})
+	validateEntries(t, changes.Delete, empty)
+	validateEntries(t, changes.UpdateOld, []*endpoint.Endpoint{bar})
+	require.Len(t, changes.UpdateNew, 1)
+	assert.Equal(t, start.Format(time.RFC3339), changes.UpdateNew[0].Labels[endpoint.MissingSinceLabelKey])
+	assert.Equal(t, "owner", changes.UpdateNew[0].Labels[endpoint.OwnerLabelKey])
+	assert.NotContains(t, bar.Labels, endpoint.MissingSinceLabelKey)
+
+	// within the grace period nothing happens
+	policy.now = func() time.Time { return start.Add(time.Minute) }
+	changes = policy.ApplyWithCurrent(&Changes{Delete: []*endpoint.Endpoint{barMissing}}, []*endpoint.Endpoint{barMissing})
+	validateEntries(t, changes.Delete, empty)
+	validateEntries(t, changes.UpdateOld, empty)
+	validateEntries(t, changes.UpdateNew, empty)
+
+	// the label survives a restart and the record is deleted after the grace period
+	restarted := NewDelayedDeletionPolicy(&SyncPolicy{}, 5*time.Minute)
+	restarted.now = func() time.Time { return start.Add(5 * time.Minute) }
+	changes = restarted.ApplyWithCurrent(&Changes{Delete: []*endpoint.Endpoint{barMissing}}, []*endpoint.Endpoint{barMissing})
+	validateEntries(t, changes.Delete, []*endpoint.Endpoint{barMissing})
+	validateEntries(t, changes.UpdateOld, empty)
+	validateEntries(t, changes.UpdateNew, empty)
+}
+
+func TestDelayedDeletionPolicyReappeared(t *testing.T) {
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	barMissing := &endpoint.Endpoint{
+		DNSName:    "bar",
+		Targets:    endpoint.Targets{"v1"},
+		RecordType: endpoint.RecordTypeA,
+		Labels: endpoint.Labels{
+			endpoint.OwnerLabelKey:        "owner",
+			endpoint.MissingSinceLabelKey: start.Format(time.RFC3339),
+		},
+	}
+
+	policy := NewDelayedDeletionPolicy(&SyncPolicy{}, 5*time.Minute)
+	policy.now = func() time.Time { return start.Add(time.Minute) }
+
+	changes := policy.ApplyWithCurrent(&Changes{}, []*endpoint.Endpoint{barMissing})
+	assert.Empty(t, changes.Delete)
+	validateEntries(t, changes.UpdateOld, []*endpoint.Endpoint{barMissing})
+	require.Len(t, changes.UpdateNew, 1)
+	assert.NotContains(t, changes.UpdateNew[0].Labels, endpoint.MissingSinceLabelKey)
+	assert.Equal(t, "owner", changes.UpdateNew[0].Labels[endpoint.OwnerLabelKey])
+}
+
+func TestDelayedDeletionPolicyWithoutPersistedLabels(t *testing.T) {
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	empty := []*endpoint.Endpoint{}
+	bar := []*endpoint.Endpoint{{DNSName: "bar", Targets: endpoint.Targets{"v1"}, RecordType: endpoint.RecordTypeA}}
+
+	policy := NewDelayedDeletionPolicy(&SyncPolicy{}, 5*time.Minute)
+	policy.now = func() time.Time { return start }
+	changes := policy.ApplyWithCurrent(&Changes{Delete: bar}, bar)
+	validateEntries(t, changes.Delete, empty)
+	assert.Len(t, changes.UpdateNew, 1)
+
+	// the label is only written once, the time is remembered in memory
+	policy.now = func() time.Time { return start.Add(time.Minute) }
+	changes = policy.ApplyWithCurrent(&Changes{Delete: bar}, bar)
+	validateEntries(t, changes.Delete, empty)
+	validateEntries(t, changes.UpdateNew, empty)
+
+	policy.now = func() time.Time { return start.Add(6 * time.Minute) }
+	changes = policy.ApplyWithCurrent(&Changes{Delete: bar}, bar)
+	validateEntries(t, changes.Delete, bar)
+}
+
+func TestDelayedDeletionPolicyOrphanedOwnership(t *testing.T) {
+	orphan := []*endpoint.Endpoint{{
+		DNSName:    "txt.bar",
+		Targets:    endpoint.Targets{"\"heritage=external-dns,external-dns/owner=owner\""},
+		RecordType: endpoint.RecordTypeTXT,
+		Labels: endpoint.Labels{
+			endpoint.OwnerLabelKey:             "owner",
+			endpoint.OrphanedOwnershipLabelKey: "bar",
+		},
+	}}
+
+	// orphaned ownership records are deleted without waiting for the grace period
+	policy := NewDelayedDeletionPolicy(&SyncPolicy{}, 5*time.Minute)
+	changes := policy.ApplyWithCurrent(&Changes{Delete: orphan}, []*endpoint.Endpoint{})
+	validateEntries(t, changes.Delete, orphan)
+	assert.Empty(t, changes.UpdateNew)
+}
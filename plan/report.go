@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Report is a stable, machine readable representation of a set of changes.
+type Report struct {
+	// HasChanges is true if applying the changes would modify any record
+	HasChanges bool           `json:"hasChanges" yaml:"hasChanges"`
+	Create     []ReportRecord `json:"create" yaml:"create"`
+	UpdateOld  []ReportRecord `json:"updateOld" yaml:"updateOld"`
+	UpdateNew  []ReportRecord `json:"updateNew" yaml:"updateNew"`
+	Delete     []ReportRecord `json:"delete" yaml:"delete"`
+}
+
+// ReportRecord describes a single record of a Report.
+type ReportRecord struct {
+	DNSName       string   `json:"dnsName" yaml:"dnsName"`
+	RecordType    string   `json:"recordType" yaml:"recordType"`
+	SetIdentifier string   `json:"setIdentifier,omitempty" yaml:"setIdentifier,omitempty"`
+	Targets       []string `json:"targets" yaml:"targets"`
+	TTL           int64    `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// Owner is the owner id of the record
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	// Resource is the kubernetes resource which caused the change
+	Resource         string            `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	ProviderSpecific map[string]string `json:"providerSpecific,omitempty" yaml:"providerSpecific,omitempty"`
+}
+
+// NewReport returns a Report of the given changes. Records are sorted so that
+// the same changes always result in the same report.
+func NewReport(changes *Changes) *Report {
+	return &Report{
+		HasChanges: changes.HasChanges(),
+		Create:     newReportRecords(changes.Create),
+		UpdateOld:  newReportRecords(changes.UpdateOld),
+		UpdateNew:  newReportRecords(changes.UpdateNew),
+		Delete:     newReportRecords(changes.Delete),
+	}
+}
+
+func newReportRecords(endpoints []*endpoint.Endpoint) []ReportRecord {
+	records := make([]ReportRecord, 0, len(endpoints))
+	for _, ep := range endpoints {
+		targets := append([]string{}, ep.Targets...)
+		sort.Strings(targets)
+
+		record := ReportRecord{
+			DNSName:       ep.DNSName,
+			RecordType:    ep.RecordType,
+			SetIdentifier: ep.SetIdentifier,
+			Targets:       targets,
+			TTL:           int64(ep.RecordTTL),
+			Owner:         ep.Labels[endpoint.OwnerLabelKey],
+			Resource:      ep.Labels[endpoint.ResourceLabelKey],
+		}
+		for k, v := range ep.Labels {
+			if k == endpoint.OwnerLabelKey || k == endpoint.ResourceLabelKey {
+				continue
+			}
+			if record.Labels == nil {
+				record.Labels = map[string]string{}
+			}
+			record.Labels[k] = v
+		}
+		for _, ps := range ep.ProviderSpecific {
+			if record.ProviderSpecific == nil {
+				record.ProviderSpecific = map[string]string{}
+			}
+			record.ProviderSpecific[ps.Name] = ps.Value
+		}
+		records = append(records, record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].DNSName != records[j].DNSName {
+			return records[i].DNSName < records[j].DNSName
+		}
+		if records[i].RecordType != records[j].RecordType {
+			return records[i].RecordType < records[j].RecordType
+		}
+		return records[i].SetIdentifier < records[j].SetIdentifier
+	})
+	return records
+}
+
+// Write writes the report to w in the given format, either json or yaml.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		out, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
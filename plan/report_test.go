@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestReport(t *testing.T) {
+	changes := &Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("new.example.org", endpoint.RecordTypeCNAME, 300, "lb.example.com").
+				WithProviderSpecific("alias", "true"),
+			{
+				DNSName:    "b.example.org",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"5.6.7.8", "1.2.3.4"},
+				Labels: endpoint.Labels{
+					endpoint.OwnerLabelKey:    "owner",
+					endpoint.ResourceLabelKey: "service/default/b",
+				},
+			},
+		},
+		Delete: []*endpoint.Endpoint{
+			{
+				DNSName:    "old.example.org",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"1.1.1.1"},
+				Labels: endpoint.Labels{
+					endpoint.OwnerLabelKey:        "owner",
+					endpoint.ResourceLabelKey:     "ingress/default/old",
+					endpoint.MissingSinceLabelKey: "2021-03-01T12:00:00Z",
+				},
+			},
+		},
+	}
+
+	report := NewReport(changes)
+	assert.True(t, report.HasChanges)
+	assert.Equal(t, []ReportRecord{
+		{
+			DNSName:    "b.example.org",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    []string{"1.2.3.4", "5.6.7.8"},
+			Owner:      "owner",
+			Resource:   "service/default/b",
+		},
+		{
+			DNSName:          "new.example.org",
+			RecordType:       endpoint.RecordTypeCNAME,
+			Targets:          []string{"lb.example.com"},
+			TTL:              300,
+			ProviderSpecific: map[string]string{"alias": "true"},
+		},
+	}, report.Create)
+	assert.Empty(t, report.UpdateOld)
+	assert.Empty(t, report.UpdateNew)
+	assert.Equal(t, []ReportRecord{
+		{
+			DNSName:    "old.example.org",
+			RecordType: endpoint.RecordTypeA,
+			Targets:    []string{"1.1.1.1"},
+			Owner:      "owner",
+			Resource:   "ingress/default/old",
+			Labels:     map[string]string{endpoint.MissingSinceLabelKey: "2021-03-01T12:00:00Z"},
+		},
+	}, report.Delete)
+
+	assert.False(t, NewReport(&Changes{}).HasChanges)
+}
+
+func TestReportWrite(t *testing.T) {
+	report := NewReport(&Changes{
+		Delete: []*endpoint.Endpoint{
+			{
+				DNSName:    "old.example.org",
+				RecordType: endpoint.RecordTypeA,
+				Targets:    endpoint.Targets{"1.1.1.1"},
+				Labels:     endpoint.Labels{endpoint.OwnerLabelKey: "owner"},
+			},
+		},
+	})
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out, "json"))
+	assert.JSONEq(t, `{
+		"hasChanges": true,
+		"create": [],
+		"updateOld": [],
+		"updateNew": [],
+		"delete": [{"dnsName": "old.example.org", "recordType": "A", "targets": ["1.1.1.1"], "owner": "owner"}]
+	}`, out.String())
+
+	out.Reset()
+	require.NoError(t, report.Write(&out, "yaml"))
+	assert.Equal(t, `hasChanges: true
+create: []
+updateOld: []
+updateNew: []
+delete:
+- dnsName: old.example.org
+  recordType: A
+  targets:
+  - 1.1.1.1
+  owner: owner
+`, out.String())
+
+	assert.Error(t, report.Write(&out, "xml"))
+}
@@ -287,6 +287,19 @@ func (p *AWSProvider) Zones(ctx context.Context) (map[string]*route53.HostedZone
 	return zones, nil
 }
 
+// ZoneIDNames returns the names of the hosted zones by their id.
+func (p *AWSProvider) ZoneIDNames(ctx context.Context) (provider.ZoneIDName, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zoneIDNames := provider.ZoneIDName{}
+	for id, zone := range zones {
+		zoneIDNames.Add(id, strings.TrimSuffix(aws.StringValue(zone.Name), "."))
+	}
+	return zoneIDNames, nil
+}
+
 // wildcardUnescape converts \\052.abc back to *.abc
 // Route53 stores wildcards escaped: http://docs.aws.amazon.com/Route53/latest/DeveloperGuide/DomainNameFormat.html?shortFooter=true#domain-name-format-asterisk
 func wildcardUnescape(s string) string {
@@ -333,7 +346,8 @@ func (p *AWSProvider) records(ctx context.Context, zones map[string]*route53.Hos
 				newEndpoints = append(newEndpoints, endpoint.NewEndpointWithTTL(wildcardUnescape(aws.StringValue(r.Name)), aws.StringValue(r.Type), ttl, targets...))
 			}
 
-			if r.AliasTarget != nil {
+			// AAAA alias records are managed together with the A alias record of dualstack endpoints
+			if r.AliasTarget != nil && aws.StringValue(r.Type) != route53.RRTypeAaaa {
 				// Alias records don't have TTLs so provide the default to match the TXT generation
 				if ttl == 0 {
 					ttl = recordTTL
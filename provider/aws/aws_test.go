@@ -313,6 +313,18 @@ func TestAWSZones(t *testing.T) {
 	}
 }
 
+func TestAWSZoneIDNames(t *testing.T) {
+	provider, _ := newAWSProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.teapot.zalan.do."}), provider.NewZoneIDFilter([]string{}), provider.NewZoneTypeFilter(""), false, false, []*endpoint.Endpoint{})
+
+	zones, err := provider.ZoneIDNames(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, zones, 3)
+	zoneID, zoneName := zones.FindZone("foo.zone-1.ext-dns-test-2.teapot.zalan.do")
+	assert.Equal(t, "/hostedzone/zone-1.ext-dns-test-2.teapot.zalan.do.", zoneID)
+	assert.Equal(t, "zone-1.ext-dns-test-2.teapot.zalan.do", zoneName)
+}
+
 func TestAWSRecordsFilter(t *testing.T) {
 	provider, _ := newAWSProvider(t, endpoint.DomainFilter{}, provider.ZoneIDFilter{}, provider.ZoneTypeFilter{}, false, false, nil)
 	domainFilter := provider.GetDomainFilter()
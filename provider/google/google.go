@@ -202,6 +202,19 @@ func (p *GoogleProvider) Zones(ctx context.Context) (map[string]*dns.ManagedZone
 	return zones, nil
 }
 
+// ZoneIDNames returns the DNS names of the managed zones by their name.
+func (p *GoogleProvider) ZoneIDNames(ctx context.Context) (provider.ZoneIDName, error) {
+	zones, err := p.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zoneIDNames := provider.ZoneIDName{}
+	for name, zone := range zones {
+		zoneIDNames.Add(name, strings.TrimSuffix(zone.DnsName, "."))
+	}
+	return zoneIDNames, nil
+}
+
 // Records returns the list of records in all relevant zones.
 func (p *GoogleProvider) Records(ctx context.Context) (endpoints []*endpoint.Endpoint, _ error) {
 	zones, err := p.Zones(ctx)
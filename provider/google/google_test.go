@@ -228,6 +228,18 @@ func TestGoogleZones(t *testing.T) {
 	})
 }
 
+func TestGoogleZoneIDNames(t *testing.T) {
+	provider := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), false, []*endpoint.Endpoint{})
+
+	zones, err := provider.ZoneIDNames(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, zones, 3)
+	zoneID, zoneName := zones.FindZone("foo.zone-2.ext-dns-test-2.gcp.zalan.do")
+	assert.Equal(t, "zone-2-ext-dns-test-2-gcp-zalan-do", zoneID)
+	assert.Equal(t, "zone-2.ext-dns-test-2.gcp.zalan.do", zoneName)
+}
+
 func TestGoogleRecords(t *testing.T) {
 	originalEndpoints := []*endpoint.Endpoint{
 		endpoint.NewEndpointWithTTL("list-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, endpoint.TTL(1), "1.2.3.4"),
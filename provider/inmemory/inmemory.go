@@ -134,7 +134,10 @@ func (im *InMemoryProvider) Records(ctx context.Context) ([]*endpoint.Endpoint,
 
 		for _, record := range records {
 			ep := endpoint.NewEndpoint(record.Name, record.Type, record.Target).WithSetIdentifier(record.SetIdentifier)
-			ep.Labels = record.Labels
+			// copy the labels so that callers adding labels, like the registries, don't modify the stored records
+			for k, v := range record.Labels {
+				ep.Labels[k] = v
+			}
 			endpoints = append(endpoints, ep)
 		}
 	}
@@ -34,6 +34,13 @@ type Provider interface {
 	GetDomainFilter() endpoint.DomainFilterInterface
 }
 
+// ZoneIDNameLister is implemented by providers which can list the zones they manage. It allows the
+// controller to apply the changes of every zone separately, so that a failing zone doesn't keep the
+// other zones from converging.
+type ZoneIDNameLister interface {
+	ZoneIDNames(ctx context.Context) (ZoneIDName, error)
+}
+
 type BaseProvider struct {
 }
 
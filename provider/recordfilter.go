@@ -17,10 +17,11 @@ limitations under the License.
 package provider
 
 // SupportedRecordType returns true only for supported record types.
-// Currently A, CNAME, SRV, TXT and NS record types are supported.
+// Currently A, AAAA, CNAME, SRV, TXT and NS record types are supported.
+// AAAA records are only managed when added to --managed-record-types.
 func SupportedRecordType(recordType string) bool {
 	switch recordType {
-	case "A", "CNAME", "SRV", "TXT", "NS":
+	case "A", "AAAA", "CNAME", "SRV", "TXT", "NS":
 		return true
 	default:
 		return false
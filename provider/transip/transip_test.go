@@ -231,7 +231,7 @@ func TestProviderRecords(t *testing.T) {
 		case strings.HasSuffix(req.Endpoint, "/dns"):
 			// return list of DNS entries
 			// also some unsupported types
-			data = []byte(`{"dnsEntries":[{"name":"www", "expire":1234, "type":"CNAME", "content":"@"},{"type":"MX"},{"type":"AAAA"}]}`)
+			data = []byte(`{"dnsEntries":[{"name":"www", "expire":1234, "type":"CNAME", "content":"@"},{"type":"MX"},{"type":"CAA"}]}`)
 		}
 
 		// unmarshal the prepared return data into the given destination type
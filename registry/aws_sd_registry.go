@@ -95,6 +95,30 @@ func (sdr *AWSSDRegistry) updateLabels(endpoints []*endpoint.Endpoint) {
 	}
 }
 
+// ClaimOwnership stores the labels of records which already exist in the Description field of their SD Service
+func (sdr *AWSSDRegistry) ClaimOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error {
+	changes := &plan.Changes{}
+	for _, ep := range endpoints {
+		changes.UpdateOld = append(changes.UpdateOld, ep)
+		claimed := ep.DeepCopy()
+		sdr.updateLabels([]*endpoint.Endpoint{claimed})
+		changes.UpdateNew = append(changes.UpdateNew, claimed)
+	}
+	return sdr.provider.ApplyChanges(ctx, changes)
+}
+
+// ReleaseOwnership clears the Description field of the SD Services of the given records
+func (sdr *AWSSDRegistry) ReleaseOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error {
+	changes := &plan.Changes{}
+	for _, ep := range endpoints {
+		changes.UpdateOld = append(changes.UpdateOld, ep)
+		released := ep.DeepCopy()
+		released.Labels = endpoint.Labels{endpoint.AWSSDDescriptionLabel: ""}
+		changes.UpdateNew = append(changes.UpdateNew, released)
+	}
+	return sdr.provider.ApplyChanges(ctx, changes)
+}
+
 func (sdr *AWSSDRegistry) PropertyValuesEqual(name string, previous string, current string) bool {
 	return sdr.provider.PropertyValuesEqual(name, previous, current)
 }
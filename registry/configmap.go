@@ -0,0 +1,339 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	// configMapOwnershipKey is the ConfigMap data key holding the ownership records
+	configMapOwnershipKey = "ownership.json"
+	// configMapMaxOwnershipSize is the size of the encoded ownership records above which the ConfigMap isn't
+	// updated anymore, it leaves room for the metadata below the 1 MiB limit of Kubernetes objects
+	configMapMaxOwnershipSize = 1000 * 1024
+)
+
+// ConfigMapRegistry implements registry interface with ownership stored in a Kubernetes ConfigMap
+// instead of the DNS provider, so that no additional records are created in the zones
+type ConfigMapRegistry struct {
+	provider  provider.Provider
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	ownerID   string //refers to the owner id of the current instance
+}
+
+// ownershipRecord holds the labels of a DNS record, identified by its name, type and set identifier
+type ownershipRecord struct {
+	DNSName       string          `json:"dnsName"`
+	RecordType    string          `json:"recordType"`
+	SetIdentifier string          `json:"setIdentifier,omitempty"`
+	Labels        endpoint.Labels `json:"labels"`
+}
+
+// NewConfigMapRegistry returns new ConfigMapRegistry object storing ownership in the given ConfigMap
+func NewConfigMapRegistry(provider provider.Provider, client kubernetes.Interface, namespace, name, ownerID string) (*ConfigMapRegistry, error) {
+	if ownerID == "" {
+		return nil, errors.New("owner id cannot be empty")
+	}
+	if client == nil {
+		return nil, errors.New("configmap registry requires a kubernetes client")
+	}
+	if name == "" {
+		return nil, errors.New("configmap name cannot be empty")
+	}
+
+	return &ConfigMapRegistry{
+		provider:  provider,
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		ownerID:   ownerID,
+	}, nil
+}
+
+func (im *ConfigMapRegistry) GetDomainFilter() endpoint.DomainFilterInterface {
+	return im.provider.GetDomainFilter()
+}
+
+// Records returns the current records from the provider with the labels stored in the ConfigMap
+func (im *ConfigMapRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records, err := im.provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ownership, err := im.ownership(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, record := range records {
+		// copy the labels, the provider may hand out its own maps
+		labels := endpoint.NewLabels()
+		for k, v := range record.Labels {
+			labels[k] = v
+		}
+		if owned, ok := ownership[ownershipKey(record)]; ok {
+			for k, v := range owned.Labels {
+				labels[k] = v
+			}
+		}
+		record.Labels = labels
+		found[ownershipKey(record)] = true
+	}
+	im.pruneOwnership(ctx, ownership, found)
+
+	return records, nil
+}
+
+// pruneOwnership releases the ownership of this owner's records which don't exist in the provider anymore,
+// e.g. because they were deleted by hand
+func (im *ConfigMapRegistry) pruneOwnership(ctx context.Context, ownership map[string]ownershipRecord, found map[string]bool) {
+	var stale []*endpoint.Endpoint
+	for key, owned := range ownership {
+		if found[key] || owned.Labels[endpoint.OwnerLabelKey] != im.ownerID || !im.provider.GetDomainFilter().Match(owned.DNSName) {
+			continue
+		}
+		stale = append(stale, &endpoint.Endpoint{DNSName: owned.DNSName, RecordType: owned.RecordType, SetIdentifier: owned.SetIdentifier})
+	}
+	if len(stale) == 0 {
+		return
+	}
+	log.Infof("Releasing the ownership of %d records which don't exist anymore", len(stale))
+	if err := im.updateOwnership(ctx, nil, stale); err != nil {
+		log.Warnf("Failed to release the ownership of records which don't exist anymore: %v", err)
+	}
+}
+
+// ApplyChanges updates dns provider with the changes and keeps the ownership in the ConfigMap in sync.
+// Created and updated records are only claimed once the provider applied the changes, so that records which
+// couldn't be created, e.g. because a foreign record exists, are never owned. Deleted records, and updated
+// records whose type changed, are released at the same time.
+func (im *ConfigMapRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	filteredChanges := &plan.Changes{
+		Create:    changes.Create,
+		UpdateNew: filterOwnedRecords(im.ownerID, changes.UpdateNew),
+		UpdateOld: filterOwnedRecords(im.ownerID, changes.UpdateOld),
+		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
+	}
+	for _, r := range filteredChanges.Create {
+		if r.Labels == nil {
+			r.Labels = make(map[string]string)
+		}
+		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
+	}
+
+	claimed := append(append([]*endpoint.Endpoint{}, filteredChanges.Create...), filteredChanges.UpdateNew...)
+	released := append([]*endpoint.Endpoint{}, filteredChanges.Delete...)
+	updated := map[string]bool{}
+	for _, r := range filteredChanges.UpdateNew {
+		updated[ownershipKey(r)] = true
+	}
+	for _, r := range filteredChanges.UpdateOld {
+		if !updated[ownershipKey(r)] {
+			released = append(released, r)
+		}
+	}
+	if len(claimed) == 0 && len(released) == 0 {
+		return im.provider.ApplyChanges(ctx, filteredChanges)
+	}
+
+	// records which couldn't be owned afterwards aren't created in the first place
+	if err := im.checkOwnershipSize(ctx, claimed, released); err != nil {
+		return err
+	}
+	if err := im.provider.ApplyChanges(ctx, filteredChanges); err != nil {
+		return err
+	}
+	return im.updateOwnership(ctx, claimed, released)
+}
+
+// ClaimOwnership stores the labels of records which already exist in the provider
+func (im *ConfigMapRegistry) ClaimOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error {
+	return im.updateOwnership(ctx, endpoints, nil)
+}
+
+// ReleaseOwnership removes the labels of the given records
+func (im *ConfigMapRegistry) ReleaseOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error {
+	return im.updateOwnership(ctx, nil, endpoints)
+}
+
+// PropertyValuesEqual compares two attribute values for equality
+func (im *ConfigMapRegistry) PropertyValuesEqual(name string, previous string, current string) bool {
+	return im.provider.PropertyValuesEqual(name, previous, current)
+}
+
+// AdjustEndpoints modifies the endpoints as needed by the specific provider
+func (im *ConfigMapRegistry) AdjustEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	return im.provider.AdjustEndpoints(endpoints)
+}
+
+/**
+  ConfigMap registry specific private methods
+*/
+
+// ownership reads the ownership records from the ConfigMap, which may not exist yet
+func (im *ConfigMapRegistry) ownership(ctx context.Context) (map[string]ownershipRecord, error) {
+	cm, err := im.client.CoreV1().ConfigMaps(im.namespace).Get(ctx, im.name, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return map[string]ownershipRecord{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ownership configmap %s/%s: %v", im.namespace, im.name, err)
+	}
+	return decodeOwnership(cm)
+}
+
+// checkOwnershipSize returns an error if the ownership doesn't fit into the ConfigMap after the update
+func (im *ConfigMapRegistry) checkOwnershipSize(ctx context.Context, claimed, released []*endpoint.Endpoint) error {
+	ownership, err := im.ownership(ctx)
+	if err != nil {
+		return err
+	}
+	changeOwnership(ownership, claimed, released)
+	return encodeOwnership(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: im.namespace, Name: im.name}}, ownership)
+}
+
+// updateOwnership stores the labels of the claimed records and removes the released records
+func (im *ConfigMapRegistry) updateOwnership(ctx context.Context, claimed, released []*endpoint.Endpoint) error {
+	configMaps := im.client.CoreV1().ConfigMaps(im.namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, im.name, metav1.GetOptions{})
+		create := kerrors.IsNotFound(err)
+		if create {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: im.namespace,
+					Name:      im.name,
+				},
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to get ownership configmap %s/%s: %v", im.namespace, im.name, err)
+		}
+
+		ownership, err := decodeOwnership(cm)
+		if err != nil {
+			return err
+		}
+		changeOwnership(ownership, claimed, released)
+		if err := encodeOwnership(cm, ownership); err != nil {
+			return err
+		}
+
+		if create {
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		} else {
+			_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			log.Debugf("Failed to update ownership configmap %s/%s: %v", im.namespace, im.name, err)
+		}
+		return err
+	})
+}
+
+// changeOwnership removes the released records and adds the claimed records, the conflict resolution
+// labels aren't stored
+func changeOwnership(ownership map[string]ownershipRecord, claimed, released []*endpoint.Endpoint) {
+	for _, ep := range released {
+		delete(ownership, ownershipKey(ep))
+	}
+	for _, ep := range claimed {
+		ownership[ownershipKey(ep)] = ownershipRecord{
+			DNSName:       normalizeOwnershipName(ep.DNSName),
+			RecordType:    ep.RecordType,
+			SetIdentifier: ep.SetIdentifier,
+			Labels:        ownershipLabels(ep.Labels),
+		}
+	}
+}
+
+func decodeOwnership(cm *corev1.ConfigMap) (map[string]ownershipRecord, error) {
+	ownership := map[string]ownershipRecord{}
+	data, ok := cm.Data[configMapOwnershipKey]
+	if !ok || data == "" {
+		return ownership, nil
+	}
+
+	var records []ownershipRecord
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		return nil, fmt.Errorf("failed to decode ownership configmap %s/%s: %v", cm.Namespace, cm.Name, err)
+	}
+	for _, r := range records {
+		ownership[ownershipKey(&endpoint.Endpoint{DNSName: r.DNSName, RecordType: r.RecordType, SetIdentifier: r.SetIdentifier})] = r
+	}
+	return ownership, nil
+}
+
+// encodeOwnership stores the ownership records sorted so that unchanged ownership results in unchanged data
+func encodeOwnership(cm *corev1.ConfigMap, ownership map[string]ownershipRecord) error {
+	keys := make([]string, 0, len(ownership))
+	for key := range ownership {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	records := make([]ownershipRecord, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, ownership[key])
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if len(data) > configMapMaxOwnershipSize {
+		return fmt.Errorf("ownership of %d records takes %d bytes, which exceeds the limit of %d bytes of configmap %s/%s", len(records), len(data), configMapMaxOwnershipSize, cm.Namespace, cm.Name)
+	}
+	if len(data) > configMapMaxOwnershipSize*9/10 {
+		log.Warnf("Ownership configmap %s/%s takes %d bytes and is close to the limit of %d bytes", cm.Namespace, cm.Name, len(data), configMapMaxOwnershipSize)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[configMapOwnershipKey] = string(data)
+	return nil
+}
+
+// ownershipKey identifies a record by its name, type and set identifier
+func ownershipKey(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s::%s::%s", normalizeOwnershipName(ep.DNSName), ep.RecordType, ep.SetIdentifier)
+}
+
+func normalizeOwnershipName(dnsName string) string {
+	return strings.TrimSuffix(strings.ToLower(dnsName), ".")
+}
@@ -0,0 +1,349 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func TestConfigMapRegistry(t *testing.T) {
+	t.Run("TestNewConfigMapRegistry", testConfigMapRegistryNew)
+	t.Run("TestApplyChanges", testConfigMapRegistryApplyChanges)
+	t.Run("TestRecords", testConfigMapRegistryRecords)
+	t.Run("TestProviderError", testConfigMapRegistryProviderError)
+	t.Run("TestTypeChange", testConfigMapRegistryTypeChange)
+	t.Run("TestSizeLimit", testConfigMapRegistrySizeLimit)
+	t.Run("TestPrune", testConfigMapRegistryPrune)
+	t.Run("TestConflictResolutionLabels", testConfigMapRegistryConflictResolutionLabels)
+}
+
+func testConfigMapRegistryNew(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	client := fake.NewSimpleClientset()
+
+	_, err := NewConfigMapRegistry(p, client, "default", "external-dns-ownership", "")
+	require.Error(t, err)
+
+	_, err = NewConfigMapRegistry(p, nil, "default", "external-dns-ownership", "owner")
+	require.Error(t, err)
+
+	_, err = NewConfigMapRegistry(p, client, "default", "", "owner")
+	require.Error(t, err)
+
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns-ownership", "owner")
+	require.NoError(t, err)
+	assert.Equal(t, p, r.provider)
+}
+
+func testConfigMapRegistryApplyChanges(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	client := fake.NewSimpleClientset()
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns-ownership", "owner")
+	require.NoError(t, err)
+
+	// records are created without any TXT records
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.Create, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/foo"),
+			newEndpointWithOwnerResource("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/bar"),
+			newEndpointWithOwnerResource("bar.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "owner", "service/default/bar"),
+		}))
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "", "ingress/default/foo"),
+			newEndpointWithOwnerResource("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "service/default/bar"),
+			newEndpointWithOwnerResource("bar.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "", "service/default/bar"),
+		},
+	}))
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/foo"),
+		newEndpointWithOwnerResource("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/bar"),
+		newEndpointWithOwnerResource("bar.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "owner", "service/default/bar"),
+	}))
+
+	// updates change the stored labels, deletions release the ownership
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/foo"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "new.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/foo-2"),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("bar.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "owner", "service/default/bar"),
+		},
+	}))
+
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "new.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/foo-2"),
+		newEndpointWithOwnerResource("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/bar"),
+	}))
+
+	ownership, err := r.ownership(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ownership, 2)
+}
+
+func testConfigMapRegistryRecords(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("other.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("unowned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("multiple.test-zone.example.org", "lb1.loadbalancer.com", endpoint.RecordTypeCNAME, "").WithSetIdentifier("test-set-1"),
+			newEndpointWithOwner("multiple.test-zone.example.org", "lb2.loadbalancer.com", endpoint.RecordTypeCNAME, "").WithSetIdentifier("test-set-2"),
+		},
+	}))
+
+	client := fake.NewSimpleClientset()
+	_, err := client.CoreV1().ConfigMaps("kube-system").Create(ctx, newOwnershipConfigMap(t, "kube-system", "dns-ownership", []ownershipRecord{
+		{DNSName: "foo.test-zone.example.org.", RecordType: endpoint.RecordTypeCNAME, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}},
+		{DNSName: "other.test-zone.example.org", RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "other"}},
+		{DNSName: "multiple.test-zone.example.org", RecordType: endpoint.RecordTypeCNAME, SetIdentifier: "test-set-1", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}},
+	}), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	r, err := NewConfigMapRegistry(p, client, "kube-system", "dns-ownership", "owner")
+	require.NoError(t, err)
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+		newEndpointWithOwner("other.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "other"),
+		newEndpointWithOwner("unowned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("multiple.test-zone.example.org", "lb1.loadbalancer.com", endpoint.RecordTypeCNAME, "owner").WithSetIdentifier("test-set-1"),
+		newEndpointWithOwner("multiple.test-zone.example.org", "lb2.loadbalancer.com", endpoint.RecordTypeCNAME, "").WithSetIdentifier("test-set-2"),
+	}))
+
+	// records of other owners and without owner are never changed
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.Delete, []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+		}))
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+			newEndpointWithOwner("other.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "other"),
+			newEndpointWithOwner("unowned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}))
+}
+
+func testConfigMapRegistryProviderError(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	}))
+	client := fake.NewSimpleClientset()
+	_, err := client.CoreV1().ConfigMaps("default").Create(ctx, newOwnershipConfigMap(t, "default", "external-dns-ownership", []ownershipRecord{
+		{DNSName: "foo.test-zone.example.org", RecordType: endpoint.RecordTypeCNAME, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}},
+	}), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	r, err := NewConfigMapRegistry(&errorProvider{p}, client, "default", "external-dns-ownership", "owner")
+	require.NoError(t, err)
+
+	// the ownership of records which could not be deleted is kept
+	require.Error(t, r.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+		},
+	}))
+	ownership, err := r.ownership(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ownership, 1)
+
+	// records which couldn't be created, e.g. because a foreign record exists, aren't claimed
+	require.Error(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}))
+	ownership, err = r.ownership(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ownership, 1)
+	assert.NotContains(t, ownership, ownershipKey(newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "")))
+}
+
+func testConfigMapRegistryTypeChange(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	client := fake.NewSimpleClientset()
+	// the in-memory provider doesn't support changing the type of a record
+	r, err := NewConfigMapRegistry(&acceptingProvider{p}, client, "default", "external-dns-ownership", "owner")
+	require.NoError(t, err)
+
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "", "ingress/default/foo"),
+		},
+	}))
+
+	// the ownership of the old record type is released
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner", "ingress/default/foo"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "ingress/default/foo"),
+		},
+	}))
+
+	ownership, err := r.ownership(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ownership, 1)
+	assert.Contains(t, ownership, ownershipKey(newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "")))
+}
+
+func testConfigMapRegistrySizeLimit(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	client := fake.NewSimpleClientset()
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns-ownership", "owner")
+	require.NoError(t, err)
+
+	// records are neither created nor owned once the ownership doesn't fit into the configmap
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		t.Error("changes must not be applied")
+	}
+	resource := strings.Repeat("r", configMapMaxOwnershipSize)
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", resource),
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the limit")
+
+	ownership, err := r.ownership(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ownership)
+}
+
+func testConfigMapRegistryPrune(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}))
+	client := fake.NewSimpleClientset()
+	_, err := client.CoreV1().ConfigMaps("default").Create(ctx, newOwnershipConfigMap(t, "default", "external-dns-ownership", []ownershipRecord{
+		{DNSName: "foo.test-zone.example.org", RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}},
+		{DNSName: "deleted.test-zone.example.org", RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "owner"}},
+		{DNSName: "other.test-zone.example.org", RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.OwnerLabelKey: "other"}},
+	}), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns-ownership", "owner")
+	require.NoError(t, err)
+	_, err = r.Records(ctx)
+	require.NoError(t, err)
+
+	// the ownership of records which were deleted by hand is released, other owners are left alone
+	ownership, err := r.ownership(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ownership, 2)
+	assert.NotContains(t, ownership, ownershipKey(newEndpointWithOwner("deleted.test-zone.example.org", "", endpoint.RecordTypeA, "")))
+}
+
+func testConfigMapRegistryConflictResolutionLabels(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	client := fake.NewSimpleClientset()
+	r, err := NewConfigMapRegistry(p, client, "default", "external-dns-ownership", "owner")
+	require.NoError(t, err)
+
+	ep := newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "service/default/foo")
+	ep.Labels[endpoint.OwnershipPriorityLabelKey] = "10"
+	ep.Labels[endpoint.CreationTimestampLabelKey] = "2021-01-01T00:00:00Z"
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{ep}}))
+
+	ownership, err := r.ownership(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, endpoint.Labels{endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "service/default/foo"}, ownership[ownershipKey(ep)].Labels)
+}
+
+func newOwnershipConfigMap(t *testing.T, namespace, name string, records []ownershipRecord) *corev1.ConfigMap {
+	ownership := map[string]ownershipRecord{}
+	for _, r := range records {
+		ownership[ownershipKey(&endpoint.Endpoint{DNSName: r.DNSName, RecordType: r.RecordType, SetIdentifier: r.SetIdentifier})] = r
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+	require.NoError(t, encodeOwnership(cm, ownership))
+	return cm
+}
+
+// errorProvider fails to apply any changes
+type errorProvider struct {
+	*inmemory.InMemoryProvider
+}
+
+func (p *errorProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	return errors.New("failed to apply changes")
+}
+
+// acceptingProvider accepts any changes without applying them
+type acceptingProvider struct {
+	*inmemory.InMemoryProvider
+}
+
+func (p *acceptingProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	return nil
+}
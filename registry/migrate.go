@@ -0,0 +1,205 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// OwnershipStore is implemented by registries which can record and remove the ownership of records
+// already present in the provider without changing the records themselves
+type OwnershipStore interface {
+	Registry
+	// ClaimOwnership stores the labels of the given records, Records must have been called before
+	ClaimOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error
+	// ReleaseOwnership removes the ownership of the given records, Records must have been called before
+	ReleaseOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error
+}
+
+// Migration moves the ownership of records from one registry configuration to another
+type Migration struct {
+	From Registry
+	To   Registry
+	// OwnerID is the owner whose records are migrated
+	OwnerID string
+	// AdoptAll migrates all managed records, for source registries which don't track ownership like the noop registry
+	AdoptAll bool
+	// ManagedRecordTypes limits the migration to records of these types
+	ManagedRecordTypes []string
+	// Cleanup removes the ownership from the source registry once the migration is verified
+	Cleanup bool
+	// DryRun only reports what would be migrated
+	DryRun bool
+}
+
+// MigrationResult lists the records handled by a migration
+type MigrationResult struct {
+	// Migrated records were claimed in the target registry
+	Migrated []*endpoint.Endpoint
+	// Skipped records were already owned in the target registry
+	Skipped []*endpoint.Endpoint
+	// Conflicts are owned by another owner in the target registry and are left alone
+	Conflicts []*endpoint.Endpoint
+	// CleanedUp records had their ownership removed from the source registry
+	CleanedUp []*endpoint.Endpoint
+}
+
+// Run migrates the ownership, verifies that the target registry reports all migrated records
+// as owned and then optionally cleans up the source registry
+func (m *Migration) Run(ctx context.Context) (*MigrationResult, error) {
+	// without a target storing the ownership, cleaning up would leave the records without any owner
+	if _, ok := m.To.(OwnershipStore); !ok && m.Cleanup && !m.AdoptAll {
+		return nil, errors.New("the target registry does not store ownership, the source registry can't be cleaned up")
+	}
+	fromRecords, err := m.From.Records(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read records of the source registry: %v", err)
+	}
+	toRecords, err := m.To.Records(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read records of the target registry: %v", err)
+	}
+	toOwners := map[string]string{}
+	for _, ep := range toRecords {
+		toOwners[ownershipKey(ep)] = ep.Labels[endpoint.OwnerLabelKey]
+	}
+
+	result := &MigrationResult{}
+	var owned []*endpoint.Endpoint
+	for _, ep := range fromRecords {
+		if !m.migrates(ep) {
+			continue
+		}
+		owned = append(owned, ep)
+
+		migrated := ep.DeepCopy()
+		migrated.Labels = migratedLabels(ep.Labels, m.OwnerID)
+		switch owner := toOwners[ownershipKey(ep)]; owner {
+		case "":
+			result.Migrated = append(result.Migrated, migrated)
+		case m.OwnerID:
+			result.Skipped = append(result.Skipped, migrated)
+		default:
+			log.Warnf("Skipping %s %s, it is owned by %q in the target registry", ep.DNSName, ep.RecordType, owner)
+			result.Conflicts = append(result.Conflicts, migrated)
+		}
+	}
+
+	if m.DryRun {
+		return result, nil
+	}
+
+	if len(result.Migrated) > 0 {
+		store, ok := m.To.(OwnershipStore)
+		if !ok {
+			log.Infof("The target registry does not store ownership, skipping %d records", len(result.Migrated))
+		} else {
+			if err := store.ClaimOwnership(ctx, result.Migrated); err != nil {
+				return result, fmt.Errorf("failed to claim ownership in the target registry: %v", err)
+			}
+			if err := m.verify(ctx, append(append([]*endpoint.Endpoint{}, result.Migrated...), result.Skipped...)); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if !m.Cleanup || m.AdoptAll {
+		return result, nil
+	}
+	store, ok := m.From.(OwnershipStore)
+	if !ok {
+		return result, nil
+	}
+	// records which are owned by somebody else in the target registry keep their current ownership
+	conflicts := map[string]bool{}
+	for _, ep := range result.Conflicts {
+		conflicts[ownershipKey(ep)] = true
+	}
+	for _, ep := range owned {
+		if !conflicts[ownershipKey(ep)] {
+			result.CleanedUp = append(result.CleanedUp, ep)
+		}
+	}
+	if len(result.CleanedUp) == 0 {
+		return result, nil
+	}
+	if err := store.ReleaseOwnership(ctx, result.CleanedUp); err != nil {
+		return result, fmt.Errorf("failed to clean up the source registry: %v", err)
+	}
+	return result, nil
+}
+
+// verify reads the target registry again and checks that it reports the records as owned
+func (m *Migration) verify(ctx context.Context, expected []*endpoint.Endpoint) error {
+	records, err := m.To.Records(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify the target registry: %v", err)
+	}
+	owners := map[string]string{}
+	for _, ep := range records {
+		owners[ownershipKey(ep)] = ep.Labels[endpoint.OwnerLabelKey]
+	}
+
+	var missing []string
+	for _, ep := range expected {
+		if owners[ownershipKey(ep)] != m.OwnerID {
+			missing = append(missing, fmt.Sprintf("%s %s", ep.DNSName, ep.RecordType))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("target registry does not report %d migrated records as owned: %s", len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// migrates returns whether the record is migrated
+func (m *Migration) migrates(ep *endpoint.Endpoint) bool {
+	if !m.From.GetDomainFilter().Match(ep.DNSName) {
+		return false
+	}
+	managed := false
+	for _, t := range m.ManagedRecordTypes {
+		if ep.RecordType == t {
+			managed = true
+			break
+		}
+	}
+	if !managed {
+		return false
+	}
+	return m.AdoptAll || ep.Labels[endpoint.OwnerLabelKey] == m.OwnerID
+}
+
+// migratedLabels copies the labels of a record without the registry specific ones
+func migratedLabels(labels endpoint.Labels, ownerID string) endpoint.Labels {
+	migrated := endpoint.NewLabels()
+	for k, v := range labels {
+		if k == endpoint.AWSSDDescriptionLabel {
+			continue
+		}
+		migrated[k] = v
+	}
+	migrated[endpoint.OwnerLabelKey] = ownerID
+	return migrated
+}
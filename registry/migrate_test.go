@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+var migrationRecordTypes = []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME}
+
+func newMigrationProvider(t *testing.T) *inmemory.InMemoryProvider {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/foo\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=other\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("unowned.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, ""),
+		},
+	}))
+	return p
+}
+
+func TestMigrationTXTToTXT(t *testing.T) {
+	ctx := context.Background()
+	p := newMigrationProvider(t)
+	from, err := NewTXTRegistry(p, "", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+	to, err := NewTXTRegistry(p, "new-", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+
+	m := &Migration{From: from, To: to, OwnerID: "owner", ManagedRecordTypes: migrationRecordTypes, Cleanup: true}
+	result, err := m.Run(ctx)
+	require.NoError(t, err)
+	assert.Len(t, result.Migrated, 2)
+	assert.Len(t, result.CleanedUp, 2)
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, ""),
+		newEndpointWithOwner("new-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/foo\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		newEndpointWithOwner("bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=other\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("unowned.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, ""),
+	}))
+
+	// running the migration again finds everything migrated already
+	from, _ = NewTXTRegistry(p, "", "", "owner", 0, "", TXTRegistryOptions{})
+	m.From = from
+	result, err = m.Run(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.Migrated)
+	assert.Empty(t, result.CleanedUp)
+}
+
+func TestMigrationTXTToConfigMap(t *testing.T) {
+	ctx := context.Background()
+	p := newMigrationProvider(t)
+	from, err := NewTXTRegistry(p, "", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+	to, err := NewConfigMapRegistry(p, fake.NewSimpleClientset(), "default", "external-dns-ownership", "owner")
+	require.NoError(t, err)
+
+	m := &Migration{From: from, To: to, OwnerID: "owner", ManagedRecordTypes: migrationRecordTypes}
+	result, err := m.Run(ctx)
+	require.NoError(t, err)
+	assert.Len(t, result.Migrated, 2)
+	assert.Empty(t, result.CleanedUp)
+
+	ownership, err := to.ownership(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ownership, 2)
+	assert.Equal(t, "service/default/foo", ownership["foo.test-zone.example.org::AAAA::"].Labels[endpoint.ResourceLabelKey])
+
+	// without cleanup the TXT records are kept
+	fromRecords, err := from.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range fromRecords {
+		if ep.DNSName == "foo.test-zone.example.org" {
+			assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey])
+		}
+	}
+}
+
+func TestMigrationNoopToTXT(t *testing.T) {
+	ctx := context.Background()
+	p := newMigrationProvider(t)
+	from, err := NewNoopRegistry(p)
+	require.NoError(t, err)
+	to, err := NewTXTRegistry(p, "new-", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+
+	m := &Migration{From: from, To: to, OwnerID: "owner", AdoptAll: true, ManagedRecordTypes: migrationRecordTypes, Cleanup: true}
+	result, err := m.Run(ctx)
+	require.NoError(t, err)
+	assert.Len(t, result.Migrated, 4)
+	assert.Empty(t, result.CleanedUp)
+
+	records, err := to.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range records {
+		if ep.RecordType != endpoint.RecordTypeTXT {
+			assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey], ep.DNSName)
+		}
+	}
+}
+
+func TestMigrationConflicts(t *testing.T) {
+	ctx := context.Background()
+	p := newMigrationProvider(t)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("new-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=other\"", endpoint.RecordTypeTXT, ""),
+		},
+	}))
+	from, err := NewTXTRegistry(p, "", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+	to, err := NewTXTRegistry(p, "new-", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+
+	m := &Migration{From: from, To: to, OwnerID: "owner", ManagedRecordTypes: migrationRecordTypes, Cleanup: true}
+	result, err := m.Run(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.Migrated)
+	assert.Len(t, result.Conflicts, 2)
+	assert.Empty(t, result.CleanedUp)
+
+	// the records keep their current ownership
+	records, err := from.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range records {
+		if ep.DNSName == "foo.test-zone.example.org" {
+			assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey])
+		}
+	}
+}
+
+func TestMigrationDryRun(t *testing.T) {
+	ctx := context.Background()
+	p := newMigrationProvider(t)
+	before, err := p.Records(ctx)
+	require.NoError(t, err)
+
+	from, err := NewTXTRegistry(p, "", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+	to, err := NewTXTRegistry(p, "", "-new", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+
+	m := &Migration{From: from, To: to, OwnerID: "owner", ManagedRecordTypes: migrationRecordTypes, Cleanup: true, DryRun: true}
+	result, err := m.Run(ctx)
+	require.NoError(t, err)
+	assert.Len(t, result.Migrated, 2)
+
+	after, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(before, after))
+}
+
+func TestMigrationVerificationFailure(t *testing.T) {
+	ctx := context.Background()
+	p := newMigrationProvider(t)
+	from, err := NewTXTRegistry(p, "", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+	noop, err := NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	m := &Migration{From: from, To: &forgetfulStore{noop}, OwnerID: "owner", ManagedRecordTypes: migrationRecordTypes, Cleanup: true}
+	_, err = m.Run(ctx)
+	require.Error(t, err)
+
+	// the source registry is not cleaned up
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 6)
+}
+
+func TestMigrationCleanupWithoutOwnershipStore(t *testing.T) {
+	ctx := context.Background()
+	p := newMigrationProvider(t)
+	from, err := NewTXTRegistry(p, "", "", "owner", 0, "", TXTRegistryOptions{})
+	require.NoError(t, err)
+	to, err := NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	m := &Migration{From: from, To: to, OwnerID: "owner", ManagedRecordTypes: migrationRecordTypes, Cleanup: true}
+	_, err = m.Run(ctx)
+	require.Error(t, err)
+
+	// the source registry is not cleaned up
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 6)
+}
+
+// forgetfulStore accepts ownership claims without storing them
+type forgetfulStore struct {
+	*NoopRegistry
+}
+
+func (s *forgetfulStore) ClaimOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error {
+	return nil
+}
+
+func (s *forgetfulStore) ReleaseOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error {
+	return nil
+}
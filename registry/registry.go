@@ -49,3 +49,67 @@ func filterOwnedRecords(ownerID string, eps []*endpoint.Endpoint) []*endpoint.En
 	}
 	return filtered
 }
+
+// OwnedChanges returns the changes a registry with the given owner id would
+// apply: updates and deletions are limited to owned records and created
+// records are labeled with the owner id.
+func OwnedChanges(ownerID string, changes *plan.Changes) *plan.Changes {
+	updateOld, updateNew, claimedOld, claimedNew := splitOwnershipClaims(ownerID, changes)
+	owned := &plan.Changes{
+		UpdateNew: append(filterOwnedRecords(ownerID, updateNew), claimedNew...),
+		UpdateOld: append(filterOwnedRecords(ownerID, updateOld), claimedOld...),
+		Delete:    filterOwnedRecords(ownerID, changes.Delete),
+	}
+	for _, ep := range changes.Create {
+		ep = ep.DeepCopy()
+		if ep.Labels == nil {
+			ep.Labels = endpoint.NewLabels()
+		}
+		ep.Labels[endpoint.OwnerLabelKey] = ownerID
+		owned.Create = append(owned.Create, ep)
+	}
+	return owned
+}
+
+// BlockedUpdates returns the old and new versions of the updates a registry with the given owner id
+// skips, because the records are owned by someone else and not claimed from the previous owner or adopted.
+func BlockedUpdates(ownerID string, changes *plan.Changes) (blockedOld, blockedNew []*endpoint.Endpoint) {
+	updateOld, updateNew, _, _ := splitOwnershipClaims(ownerID, changes)
+	if len(updateOld) != len(updateNew) {
+		return nil, nil
+	}
+	for i, old := range updateOld {
+		if old.Labels[endpoint.OwnerLabelKey] != ownerID {
+			blockedOld = append(blockedOld, old)
+			blockedNew = append(blockedNew, updateNew[i])
+		}
+	}
+	return blockedOld, blockedNew
+}
+
+// splitOwnershipClaims separates the updates which hand over a record from the previous owner or adopt
+// a record without owner from the other updates, the new versions of claimed records are labeled with the owner id
+func splitOwnershipClaims(ownerID string, changes *plan.Changes) (updateOld, updateNew, claimedOld, claimedNew []*endpoint.Endpoint) {
+	// the planner adds the old and new version of an update at the same position
+	if len(changes.UpdateOld) != len(changes.UpdateNew) {
+		return changes.UpdateOld, changes.UpdateNew, nil, nil
+	}
+	for i, old := range changes.UpdateOld {
+		updated := changes.UpdateNew[i]
+		_, handover := old.Labels[endpoint.PreviousOwnerLabelKey]
+		adoption := old.Labels[endpoint.OwnerLabelKey] == "" && updated.Labels[endpoint.AdoptLabelKey] == "true"
+		if !handover && !adoption {
+			updateOld = append(updateOld, old)
+			updateNew = append(updateNew, updated)
+			continue
+		}
+		updated = updated.DeepCopy()
+		if updated.Labels == nil {
+			updated.Labels = endpoint.NewLabels()
+		}
+		updated.Labels[endpoint.OwnerLabelKey] = ownerID
+		claimedOld = append(claimedOld, old)
+		claimedNew = append(claimedNew, updated)
+	}
+	return updateOld, updateNew, claimedOld, claimedNew
+}
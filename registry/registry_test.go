@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestOwnedChanges(t *testing.T) {
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("owned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("foreign.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "other"),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwner("owned.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("foreign.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "other"),
+		},
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwner("unowned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}
+
+	owned := OwnedChanges("owner", changes)
+	assert.True(t, testutils.SameEndpoints(owned.Create, []*endpoint.Endpoint{
+		newEndpointWithOwner("new.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+	}))
+	assert.True(t, testutils.SameEndpoints(owned.UpdateOld, []*endpoint.Endpoint{
+		newEndpointWithOwner("owned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+	}))
+	assert.True(t, testutils.SameEndpoints(owned.UpdateNew, []*endpoint.Endpoint{
+		newEndpointWithOwner("owned.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "owner"),
+	}))
+	assert.Empty(t, owned.Delete)
+	// the given changes are left untouched
+	assert.Equal(t, "", changes.Create[0].Labels[endpoint.OwnerLabelKey])
+}
+
+func TestOwnedChangesOwnershipClaims(t *testing.T) {
+	changes := &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("foreign.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "other"),
+			newEndpointWithOwner("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwnerAndLabels("previous.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "old", endpoint.Labels{endpoint.PreviousOwnerLabelKey: "old"}),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwner("foreign.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "other"),
+			newEndpointWithOwnerAndLabels("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", endpoint.Labels{endpoint.AdoptLabelKey: "true"}),
+			newEndpointWithOwner("previous.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "old"),
+		},
+	}
+
+	// adopted and handed over records are claimed by the owner
+	owned := OwnedChanges("owner", changes)
+	assert.True(t, testutils.SameEndpoints(owned.UpdateOld, []*endpoint.Endpoint{
+		newEndpointWithOwner("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("previous.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "old"),
+	}))
+	assert.True(t, testutils.SameEndpoints(owned.UpdateNew, []*endpoint.Endpoint{
+		newEndpointWithOwner("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		newEndpointWithOwner("previous.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+	}))
+	assert.Equal(t, "", changes.UpdateNew[1].Labels[endpoint.OwnerLabelKey])
+}
+
+func TestBlockedUpdates(t *testing.T) {
+	changes := &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("owned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("foreign.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "other"),
+			newEndpointWithOwner("unowned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwner("owned.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "owner"),
+			newEndpointWithOwner("foreign.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "other"),
+			newEndpointWithOwner("unowned.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, ""),
+			newEndpointWithOwnerAndLabels("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", endpoint.Labels{endpoint.AdoptLabelKey: "true"}),
+		},
+	}
+
+	// adopted records aren't blocked
+	blockedOld, blockedNew := BlockedUpdates("owner", changes)
+	assert.True(t, testutils.SameEndpoints(blockedOld, []*endpoint.Endpoint{
+		newEndpointWithOwner("foreign.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "other"),
+		newEndpointWithOwner("unowned.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+	}))
+	assert.True(t, testutils.SameEndpoints(blockedNew, []*endpoint.Endpoint{
+		newEndpointWithOwner("foreign.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "other"),
+		newEndpointWithOwner("unowned.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, ""),
+	}))
+}
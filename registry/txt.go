@@ -23,6 +23,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -30,6 +31,48 @@ import (
 	"sigs.k8s.io/external-dns/provider"
 )
 
+var (
+	orphanedOwnershipRecords = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "registry",
+			Name:      "orphaned_ownership_records",
+			Help:      "Number of TXT ownership records of this owner without a matching record.",
+		},
+	)
+	orphanedOwnershipRecordsDeleted = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "registry",
+			Name:      "orphaned_ownership_records_deleted_total",
+			Help:      "Number of orphaned TXT ownership records passed to the provider for deletion.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(orphanedOwnershipRecords)
+	prometheus.MustRegister(orphanedOwnershipRecordsDeleted)
+}
+
+// conflictResolutionLabelKeys are set by the sources to resolve conflicts in the plan, they aren't stored with the ownership
+var conflictResolutionLabelKeys = map[string]bool{
+	endpoint.OwnershipPriorityLabelKey: true,
+	endpoint.CreationTimestampLabelKey: true,
+	endpoint.AdoptLabelKey:             true,
+}
+
+// ownershipLabels returns the labels stored with the ownership of a record, without the conflict resolution labels
+func ownershipLabels(labels endpoint.Labels) endpoint.Labels {
+	stored := endpoint.NewLabels()
+	for k, v := range labels {
+		if !conflictResolutionLabelKeys[k] {
+			stored[k] = v
+		}
+	}
+	return stored
+}
+
 // TXTRegistry implements registry interface with ownership implemented via associated TXT records
 type TXTRegistry struct {
 	provider provider.Provider
@@ -41,23 +84,64 @@ type TXTRegistry struct {
 	recordsCacheRefreshTime time.Time
 	cacheInterval           time.Duration
 
+	// record types sharing each TXT record, keyed by txtKey, so that records of different types
+	// with the same name, like A and AAAA, only create and delete the TXT record once
+	txtRecordTypes map[string]map[string]bool
+
+	// TXT records of this owner without a matching record, keyed by orphanKey, which are
+	// returned by Records to be deleted when deleteOrphans is enabled
+	orphans       map[string]*endpoint.Endpoint
+	deleteOrphans bool
+
+	// records of this owner id are handed over to ownerID when they are updated
+	previousOwnerID string
+
+	// optionally encrypts and/or signs the labels stored in the TXT records
+	codec *endpoint.LabelsCodec
+	// values of TXT records, keyed by txtKey, which differ from the serialization of their labels,
+	// e.g. plaintext ones read while switching over to the codec
+	txtTargets map[string]string
+
+	// records, keyed by recordKey, whose ownership is stored in a TXT record shared by all record types
+	// with their name while the mapper names TXT records by record type
+	sharedTXTRecords map[string]bool
+
 	// optional string to use to replace the asterisk in wildcard entries - without using this,
 	// registry TXT records corresponding to wildcard records will be invalid (and rejected by most providers), due to
 	// having a '*' appear (not as the first character) - see https://tools.ietf.org/html/rfc1034#section-4.3.3
 	wildcardReplacement string
 }
 
+// TXTRegistryOptions holds the optional settings of a TXTRegistry, the zero value disables all of them
+type TXTRegistryOptions struct {
+	// DeleteOrphans deletes the TXT records of this owner without a matching record
+	DeleteOrphans bool
+	// PreviousOwnerID is the owner id whose records are handed over when they are updated
+	PreviousOwnerID string
+	// LabelsCodec encrypts and/or signs the labels stored in the TXT records
+	LabelsCodec *endpoint.LabelsCodec
+	// RecordTypeNames names the TXT records after the type of the records they own
+	RecordTypeNames bool
+}
+
 // NewTXTRegistry returns new TXTRegistry object
-func NewTXTRegistry(provider provider.Provider, txtPrefix, txtSuffix, ownerID string, cacheInterval time.Duration, txtWildcardReplacement string) (*TXTRegistry, error) {
+func NewTXTRegistry(provider provider.Provider, txtPrefix, txtSuffix, ownerID string, cacheInterval time.Duration, txtWildcardReplacement string, opts TXTRegistryOptions) (*TXTRegistry, error) {
 	if ownerID == "" {
 		return nil, errors.New("owner id cannot be empty")
 	}
 
+	if opts.PreviousOwnerID == ownerID {
+		return nil, errors.New("previous owner id must differ from the owner id")
+	}
+
 	if len(txtPrefix) > 0 && len(txtSuffix) > 0 {
 		return nil, errors.New("txt-prefix and txt-suffix are mutual exclusive")
 	}
 
-	mapper := newaffixNameMapper(txtPrefix, txtSuffix, txtWildcardReplacement)
+	var mapper nameMapper = newaffixNameMapper(txtPrefix, txtSuffix, txtWildcardReplacement)
+	if opts.RecordTypeNames {
+		mapper = newRecordTypeNameMapper(txtPrefix, txtSuffix, txtWildcardReplacement)
+	}
 
 	return &TXTRegistry{
 		provider:            provider,
@@ -65,6 +149,9 @@ func NewTXTRegistry(provider provider.Provider, txtPrefix, txtSuffix, ownerID st
 		mapper:              mapper,
 		cacheInterval:       cacheInterval,
 		wildcardReplacement: txtWildcardReplacement,
+		deleteOrphans:       opts.DeleteOrphans,
+		previousOwnerID:     opts.PreviousOwnerID,
+		codec:               opts.LabelsCodec,
 	}, nil
 }
 
@@ -91,6 +178,14 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 	endpoints := []*endpoint.Endpoint{}
 
 	labelMap := map[string]endpoint.Labels{}
+	// orphanKey of the TXT record holding each entry of labelMap
+	labelTXTRecords := map[string]string{}
+	txtRecordTypes := map[string]map[string]bool{}
+	txtTargets := map[string]string{}
+	// TXT records of this owner in the order they were found, removed once a matching record is found
+	var ownedKeys []string
+	ownedTXTRecords := map[string]*endpoint.Endpoint{}
+	im.sharedTXTRecords = map[string]bool{}
 
 	for _, record := range records {
 		if record.RecordType != endpoint.RecordTypeTXT {
@@ -98,7 +193,8 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 			continue
 		}
 		// We simply assume that TXT records for the registry will always have only one target.
-		labels, err := endpoint.NewLabelsFromString(record.Targets[0])
+		labelsRecord := im.labelsRecord(record.DNSName, record.SetIdentifier)
+		labels, err := endpoint.NewLabelsFromStringWithCodec(record.Targets[0], im.codec, labelsRecord)
 		if err == endpoint.ErrInvalidHeritage {
 			//if no heritage is found or it is invalid
 			//case when value of txt record cannot be identified
@@ -106,11 +202,41 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 			endpoints = append(endpoints, record)
 			continue
 		}
+		if err == endpoint.ErrMissingLabelsKey || err == endpoint.ErrUnprotectedLabels || err == endpoint.ErrInvalidLabelsSignature {
+			// ownership records which can't be trusted are treated like foreign TXT records
+			log.Warnf("Ignoring ownership record %s: %v", record.DNSName, err)
+			endpoints = append(endpoints, record)
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
-		key := fmt.Sprintf("%s::%s", im.mapper.toEndpointName(record.DNSName), record.SetIdentifier)
-		labelMap[key] = labels
+		endpointName, recordType := im.mapper.toEndpointName(record.DNSName)
+		recordKey := im.orphanKey(record)
+		labelMap[txtLabelsKey(endpointName, record.SetIdentifier, recordType)] = labels
+		labelTXTRecords[txtLabelsKey(endpointName, record.SetIdentifier, recordType)] = recordKey
+		if recordType != "" {
+			// the TXT record might as well be shared by the records of a name which starts like a record type
+			sharedName := withRecordTypePrefix(endpointName, recordType)
+			if _, ok := labelMap[txtLabelsKey(sharedName, record.SetIdentifier, "")]; !ok {
+				labelMap[txtLabelsKey(sharedName, record.SetIdentifier, "")] = labels
+				labelTXTRecords[txtLabelsKey(sharedName, record.SetIdentifier, "")] = recordKey
+			}
+		}
+		if im.codec != nil && labels.SerializeWithCodec(true, im.codec, labelsRecord) != record.Targets[0] {
+			txtTargets[recordKey] = record.Targets[0]
+		}
+		// TXT records which don't match the name mapping may belong to another configuration and are never orphaned
+		if endpointName != "" && labels[endpoint.OwnerLabelKey] == im.ownerID {
+			orphan := record.DeepCopy()
+			orphan.Labels = endpoint.NewLabels()
+			for k, v := range labels {
+				orphan.Labels[k] = v
+			}
+			orphan.Labels[endpoint.OrphanedOwnershipLabelKey] = endpointName
+			ownedKeys = append(ownedKeys, recordKey)
+			ownedTXTRecords[recordKey] = orphan
+		}
 	}
 
 	for _, ep := range endpoints {
@@ -122,14 +248,52 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 		if im.wildcardReplacement != "" && dnsNameSplit[0] == "*" {
 			dnsNameSplit[0] = im.wildcardReplacement
 		}
-		dnsName := strings.Join(dnsNameSplit, ".")
-		key := fmt.Sprintf("%s::%s", dnsName, ep.SetIdentifier)
-		if labels, ok := labelMap[key]; ok {
+		dnsName := strings.ToLower(strings.Join(dnsNameSplit, "."))
+		// a TXT record of the record type takes precedence over one shared by all record types
+		key := txtLabelsKey(dnsName, ep.SetIdentifier, ep.RecordType)
+		labels, ok := labelMap[key]
+		if !ok {
+			key = txtLabelsKey(dnsName, ep.SetIdentifier, "")
+			if labels, ok = labelMap[key]; ok {
+				im.sharedTXTRecords[ownershipKey(ep)] = true
+			}
+		}
+		if ok {
 			for k, v := range labels {
 				ep.Labels[k] = v
 			}
+			if im.previousOwnerID != "" && ep.Labels[endpoint.OwnerLabelKey] == im.previousOwnerID {
+				ep.Labels[endpoint.PreviousOwnerLabelKey] = im.previousOwnerID
+			}
+			txtRecordTypes[im.txtKey(ep)] = addRecordType(txtRecordTypes[im.txtKey(ep)], ep.RecordType)
+			delete(ownedTXTRecords, labelTXTRecords[key])
 		}
 	}
+	im.txtRecordTypes = txtRecordTypes
+	im.txtTargets = txtTargets
+
+	// ownership records without a matching record, e.g. because the record was deleted by hand, are
+	// returned so that the plan deletes them
+	orphans := map[string]*endpoint.Endpoint{}
+	for _, key := range ownedKeys {
+		orphan, ok := ownedTXTRecords[key]
+		if !ok {
+			continue
+		}
+		delete(ownedTXTRecords, key)
+		orphans[im.orphanKey(orphan)] = orphan
+		if !im.deleteOrphans {
+			// list the orphans which would be deleted, once per orphan to keep the log readable
+			if _, known := im.orphans[im.orphanKey(orphan)]; !known {
+				log.Infof("Found orphaned ownership record %s of %s, deletion is disabled", orphan.DNSName, orphan.Labels[endpoint.OrphanedOwnershipLabelKey])
+			}
+			continue
+		}
+		log.Debugf("Found orphaned ownership record %s of %s", orphan.DNSName, orphan.Labels[endpoint.OrphanedOwnershipLabelKey])
+		endpoints = append(endpoints, orphan)
+	}
+	im.orphans = orphans
+	orphanedOwnershipRecords.Set(float64(len(orphans)))
 
 	// Update the cache.
 	if im.cacheInterval > 0 {
@@ -143,46 +307,163 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 // ApplyChanges updates dns provider with the changes
 // for each created/deleted record it will also take into account TXT records for creation/deletion
 func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	updateOld, updateNew, claimedOld, claimedNew := splitOwnershipClaims(im.ownerID, changes)
 	filteredChanges := &plan.Changes{
 		Create:    changes.Create,
-		UpdateNew: filterOwnedRecords(im.ownerID, changes.UpdateNew),
-		UpdateOld: filterOwnedRecords(im.ownerID, changes.UpdateOld),
+		UpdateNew: filterOwnedRecords(im.ownerID, updateNew),
+		UpdateOld: filterOwnedRecords(im.ownerID, updateOld),
 		Delete:    filterOwnedRecords(im.ownerID, changes.Delete),
 	}
+	if im.txtRecordTypes == nil {
+		im.txtRecordTypes = map[string]map[string]bool{}
+	}
+	// orphaned ownership records are deleted as they are, they have no record to take care of
+	var orphans, takenOverOld, takenOverNew []*endpoint.Endpoint
+	filteredChanges.Delete, orphans = splitOrphans(filteredChanges.Delete)
+
+	created := map[string]bool{}
 	for _, r := range filteredChanges.Create {
 		if r.Labels == nil {
 			r.Labels = make(map[string]string)
 		}
 		r.Labels[endpoint.OwnerLabelKey] = im.ownerID
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
-		filteredChanges.Create = append(filteredChanges.Create, txt)
+		key := im.txtKey(r)
+		// another record with the same name, e.g. A next to AAAA, already has the TXT record
+		if !created[key] && len(im.txtRecordTypes[key]) == 0 {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.txtTarget(r)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			if orphan, ok := im.orphans[key]; ok {
+				// the record is created again, so our orphaned ownership record is taken over instead
+				takenOverOld = append(takenOverOld, orphan)
+				takenOverNew = append(takenOverNew, txt)
+				delete(im.orphans, key)
+				if im.cacheInterval > 0 {
+					im.removeFromCache(orphan)
+				}
+			} else {
+				filteredChanges.Create = append(filteredChanges.Create, txt)
+			}
+			created[key] = true
+		}
 
 		if im.cacheInterval > 0 {
 			im.addToCache(r)
 		}
 	}
+	for _, r := range changes.Create {
+		im.txtRecordTypes[im.txtKey(r)] = addRecordType(im.txtRecordTypes[im.txtKey(r)], r.RecordType)
+	}
 
-	for _, r := range filteredChanges.Delete {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
+	// records handed over from the previous owner have their TXT record updated like any other update,
+	// adopted records get a TXT record created
+	var adoptedOld, adoptedNew []*endpoint.Endpoint
+	for i, old := range claimedOld {
+		r := claimedNew[i]
+		if previousOwner, ok := old.Labels[endpoint.PreviousOwnerLabelKey]; ok {
+			log.Infof("Taking over %s %s from owner %q", old.DNSName, old.RecordType, previousOwner)
+			filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, old)
+			filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, r)
+			continue
+		}
+		log.Infof("Adopting %s %s", old.DNSName, old.RecordType)
+		key := im.txtKey(r)
+		if !created[key] && len(im.txtRecordTypes[key]) == 0 {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.txtTarget(r)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			filteredChanges.Create = append(filteredChanges.Create, txt)
+			created[key] = true
+		}
+		im.txtRecordTypes[key] = addRecordType(im.txtRecordTypes[key], r.RecordType)
+		adoptedOld = append(adoptedOld, old)
+		adoptedNew = append(adoptedNew, r)
+		if im.cacheInterval > 0 {
+			im.removeFromCache(old)
+			im.addToCache(r)
+		}
+	}
+
+	for _, orphan := range orphans {
+		key := im.orphanKey(orphan)
+		if created[key] {
+			continue
+		}
+		log.Infof("Deleting orphaned ownership record %s of %s", orphan.DNSName, orphan.Labels[endpoint.OrphanedOwnershipLabelKey])
+		filteredChanges.Delete = append(filteredChanges.Delete, orphan)
+		orphanedOwnershipRecordsDeleted.Inc()
+		delete(im.orphans, key)
+		if im.cacheInterval > 0 {
+			im.removeFromCache(orphan)
+		}
+	}
 
-		// when we delete TXT records for which value has changed (due to new label) this would still work because
-		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
-		filteredChanges.Delete = append(filteredChanges.Delete, txt)
+	for _, r := range filteredChanges.Delete {
+		if _, ok := r.Labels[endpoint.OrphanedOwnershipLabelKey]; ok {
+			continue
+		}
+		delete(im.txtRecordTypes[im.txtKey(r)], r.RecordType)
+	}
+	deleted := map[string]bool{}
+	for _, r := range filteredChanges.Delete {
+		if _, ok := r.Labels[endpoint.OrphanedOwnershipLabelKey]; ok {
+			continue
+		}
+		key := im.txtKey(r)
+		// keep the TXT record while other records with the same name remain
+		if !deleted[key] && len(im.txtRecordTypes[key]) == 0 {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.existingTXTTarget(r, r.Labels)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			delete(im.txtTargets, key)
+
+			// when we delete TXT records for which value has changed (due to new label) this would still work because
+			// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
+			filteredChanges.Delete = append(filteredChanges.Delete, txt)
+			deleted[key] = true
+		}
+		delete(im.sharedTXTRecords, ownershipKey(r))
 
 		if im.cacheInterval > 0 {
 			im.removeFromCache(r)
 		}
 	}
 
+	// records which are the only ones left in a TXT record shared by all record types move to a TXT record
+	// of their own as they are updated, when the mapper names TXT records by record type
+	moved := map[string]bool{}
+	for _, r := range filteredChanges.UpdateNew {
+		if im.sharedTXTRecords[ownershipKey(r)] && im.mapper.toTXTName(r.DNSName, r.RecordType) != im.txtName(r) && len(im.txtRecordTypes[im.txtKey(r)]) == 1 {
+			moved[ownershipKey(r)] = true
+		}
+	}
+
 	// make sure TXT records are consistently updated as well
+	updatedOld := map[string]bool{}
 	for _, r := range filteredChanges.UpdateOld {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
-		// when we updateOld TXT records for which value has changed (due to new label) this would still work because
-		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
-		filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, txt)
+		labels := r.Labels
+		if _, ok := labels[endpoint.PreviousOwnerLabelKey]; ok {
+			// the label isn't part of the TXT record of the previous owner
+			labels = endpoint.NewLabels()
+			for k, v := range r.Labels {
+				if k != endpoint.PreviousOwnerLabelKey {
+					labels[k] = v
+				}
+			}
+		}
+		if key := im.txtKey(r); moved[ownershipKey(r)] {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.existingTXTTarget(r, labels)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			log.Debugf("Moving the ownership of %s %s from %s to %s", r.DNSName, r.RecordType, txt.DNSName, im.mapper.toTXTName(r.DNSName, r.RecordType))
+			delete(im.txtTargets, key)
+			delete(im.txtRecordTypes, key)
+			delete(im.sharedTXTRecords, ownershipKey(r))
+			filteredChanges.Delete = append(filteredChanges.Delete, txt)
+		} else if !updatedOld[key] {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.existingTXTTarget(r, labels)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			// when we updateOld TXT records for which value has changed (due to new label) this would still work because
+			// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
+			filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, txt)
+			updatedOld[key] = true
+		}
 		// remove old version of record from cache
 		if im.cacheInterval > 0 {
 			im.removeFromCache(r)
@@ -190,16 +471,29 @@ func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes)
 	}
 
 	// make sure TXT records are consistently updated as well
+	updatedNew := map[string]bool{}
 	for _, r := range filteredChanges.UpdateNew {
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true)).WithSetIdentifier(r.SetIdentifier)
-		txt.ProviderSpecific = r.ProviderSpecific
-		filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, txt)
+		if key := im.txtKey(r); moved[ownershipKey(r)] {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.txtTarget(r)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			im.txtRecordTypes[key] = addRecordType(im.txtRecordTypes[key], r.RecordType)
+			filteredChanges.Create = append(filteredChanges.Create, txt)
+		} else if !updatedNew[key] {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.txtTarget(r)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			delete(im.txtTargets, key)
+			filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, txt)
+			updatedNew[key] = true
+		}
 		// add new version of record to cache
 		if im.cacheInterval > 0 {
 			im.addToCache(r)
 		}
 	}
 
+	filteredChanges.UpdateOld = append(append(filteredChanges.UpdateOld, takenOverOld...), adoptedOld...)
+	filteredChanges.UpdateNew = append(append(filteredChanges.UpdateNew, takenOverNew...), adoptedNew...)
+
 	// when caching is enabled, disable the provider from using the cache
 	if im.cacheInterval > 0 {
 		ctx = context.WithValue(ctx, provider.RecordsContextKey, nil)
@@ -207,6 +501,58 @@ func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes)
 	return im.provider.ApplyChanges(ctx, filteredChanges)
 }
 
+// ClaimOwnership creates the TXT records holding the ownership of records which already exist in the provider
+func (im *TXTRegistry) ClaimOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error {
+	if im.txtRecordTypes == nil {
+		im.txtRecordTypes = map[string]map[string]bool{}
+	}
+
+	changes := &plan.Changes{}
+	created := map[string]bool{}
+	for _, r := range endpoints {
+		key := im.txtKey(r)
+		if !created[key] && len(im.txtRecordTypes[key]) == 0 {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.txtTarget(r)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			changes.Create = append(changes.Create, txt)
+			created[key] = true
+		}
+	}
+	if err := im.provider.ApplyChanges(ctx, changes); err != nil {
+		return err
+	}
+
+	for _, r := range endpoints {
+		im.txtRecordTypes[im.txtKey(r)] = addRecordType(im.txtRecordTypes[im.txtKey(r)], r.RecordType)
+	}
+	im.recordsCache = nil
+	return nil
+}
+
+// ReleaseOwnership deletes the TXT records holding the ownership of the given records, a TXT record
+// shared by several record types is kept while any of the other types remain owned
+func (im *TXTRegistry) ReleaseOwnership(ctx context.Context, endpoints []*endpoint.Endpoint) error {
+	for _, r := range endpoints {
+		delete(im.txtRecordTypes[im.txtKey(r)], r.RecordType)
+	}
+
+	changes := &plan.Changes{}
+	deleted := map[string]bool{}
+	for _, r := range endpoints {
+		key := im.txtKey(r)
+		if !deleted[key] && len(im.txtRecordTypes[key]) == 0 {
+			txt := endpoint.NewEndpoint(im.txtName(r), endpoint.RecordTypeTXT, im.existingTXTTarget(r, r.Labels)).WithSetIdentifier(r.SetIdentifier)
+			txt.ProviderSpecific = r.ProviderSpecific
+			delete(im.txtTargets, key)
+			changes.Delete = append(changes.Delete, txt)
+			deleted[key] = true
+		}
+		delete(im.sharedTXTRecords, ownershipKey(r))
+	}
+	im.recordsCache = nil
+	return im.provider.ApplyChanges(ctx, changes)
+}
+
 // PropertyValuesEqual compares two attribute values for equality
 func (im *TXTRegistry) PropertyValuesEqual(name string, previous string, current string) bool {
 	return im.provider.PropertyValuesEqual(name, previous, current)
@@ -227,8 +573,12 @@ func (im *TXTRegistry) AdjustEndpoints(endpoints []*endpoint.Endpoint) []*endpoi
 */
 
 type nameMapper interface {
-	toEndpointName(string) string
-	toTXTName(string) string
+	// toEndpointName returns the name of the records owned by the TXT record, and their record type
+	// unless the TXT record is shared by all record types with the name
+	toEndpointName(txtDNSName string) (endpointName, recordType string)
+	// toTXTName returns the name of the TXT record owning the records with the name and record type,
+	// the TXT record is shared by all record types if the record type is empty
+	toTXTName(endpointDNSName, recordType string) string
 }
 
 type affixNameMapper struct {
@@ -243,7 +593,11 @@ func newaffixNameMapper(prefix string, suffix string, wildcardReplacement string
 	return affixNameMapper{prefix: strings.ToLower(prefix), suffix: strings.ToLower(suffix), wildcardReplacement: strings.ToLower(wildcardReplacement)}
 }
 
-func (pr affixNameMapper) toEndpointName(txtDNSName string) string {
+func (pr affixNameMapper) toEndpointName(txtDNSName string) (string, string) {
+	return pr.trimAffix(txtDNSName), ""
+}
+
+func (pr affixNameMapper) trimAffix(txtDNSName string) string {
 	lowerDNSName := strings.ToLower(txtDNSName)
 	if strings.HasPrefix(lowerDNSName, pr.prefix) && len(pr.suffix) == 0 {
 		return strings.TrimPrefix(lowerDNSName, pr.prefix)
@@ -258,13 +612,21 @@ func (pr affixNameMapper) toEndpointName(txtDNSName string) string {
 	return ""
 }
 
-func (pr affixNameMapper) toTXTName(endpointDNSName string) string {
-	DNSName := strings.SplitN(endpointDNSName, ".", 2)
+func (pr affixNameMapper) toTXTName(endpointDNSName, recordType string) string {
+	return pr.addAffix(pr.replaceWildcard(endpointDNSName))
+}
 
-	// If specified, replace a leading asterisk in the generated txt record name with some other string
+// replaceWildcard replaces a leading asterisk, if specified, in the generated txt record name with some other string
+func (pr affixNameMapper) replaceWildcard(endpointDNSName string) string {
+	DNSName := strings.SplitN(endpointDNSName, ".", 2)
 	if pr.wildcardReplacement != "" && DNSName[0] == "*" {
 		DNSName[0] = pr.wildcardReplacement
 	}
+	return strings.Join(DNSName, ".")
+}
+
+func (pr affixNameMapper) addAffix(endpointDNSName string) string {
+	DNSName := strings.SplitN(endpointDNSName, ".", 2)
 
 	if len(DNSName) < 2 {
 		return pr.prefix + DNSName[0] + pr.suffix
@@ -272,6 +634,72 @@ func (pr affixNameMapper) toTXTName(endpointDNSName string) string {
 	return pr.prefix + DNSName[0] + pr.suffix + "." + DNSName[1]
 }
 
+// txtName returns the name of the TXT record holding the ownership of an endpoint
+func (im *TXTRegistry) txtName(ep *endpoint.Endpoint) string {
+	if im.sharedTXTRecords[ownershipKey(ep)] {
+		return im.mapper.toTXTName(ep.DNSName, "")
+	}
+	return im.mapper.toTXTName(ep.DNSName, ep.RecordType)
+}
+
+// txtKey identifies the TXT record holding the ownership of an endpoint
+func (im *TXTRegistry) txtKey(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s::%s", strings.ToLower(im.txtName(ep)), ep.SetIdentifier)
+}
+
+// txtLabelsKey identifies the labels read from a TXT record, the record type is empty for TXT records
+// shared by all record types with the name
+func txtLabelsKey(endpointName, setIdentifier, recordType string) string {
+	return fmt.Sprintf("%s::%s::%s", endpointName, setIdentifier, recordType)
+}
+
+// txtTarget returns the value of the TXT record holding the labels of the endpoint, without the labels
+// which are only used to resolve conflicts between resources
+func (im *TXTRegistry) txtTarget(ep *endpoint.Endpoint) string {
+	return ownershipLabels(ep.Labels).SerializeWithCodec(true, im.codec, im.labelsRecord(im.txtName(ep), ep.SetIdentifier))
+}
+
+// existingTXTTarget returns the value of the existing TXT record holding the labels of the endpoint
+func (im *TXTRegistry) existingTXTTarget(ep *endpoint.Endpoint, labels endpoint.Labels) string {
+	if target, ok := im.txtTargets[im.txtKey(ep)]; ok {
+		return target
+	}
+	return labels.SerializeWithCodec(true, im.codec, im.labelsRecord(im.txtName(ep), ep.SetIdentifier))
+}
+
+// labelsRecord identifies the TXT record the labels are stored in, which encrypted and signed labels are bound to.
+// The record type is the one in the name of the TXT record, if any.
+func (im *TXTRegistry) labelsRecord(txtName, setIdentifier string) endpoint.LabelsRecord {
+	_, recordType := im.mapper.toEndpointName(txtName)
+	return endpoint.LabelsRecord{DNSName: txtName, RecordType: recordType, SetIdentifier: setIdentifier}
+}
+
+// orphanKey identifies an orphaned TXT record, it matches the txtKey of the records it belonged to
+func (im *TXTRegistry) orphanKey(txt *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s::%s", strings.ToLower(txt.DNSName), txt.SetIdentifier)
+}
+
+// splitOrphans separates orphaned ownership records from the other records
+func splitOrphans(endpoints []*endpoint.Endpoint) (records, orphans []*endpoint.Endpoint) {
+	records = []*endpoint.Endpoint{}
+	for _, ep := range endpoints {
+		if _, ok := ep.Labels[endpoint.OrphanedOwnershipLabelKey]; ok {
+			orphans = append(orphans, ep)
+			continue
+		}
+		records = append(records, ep)
+	}
+	return records, orphans
+}
+
+func addRecordType(recordTypes map[string]bool, recordType string) map[string]bool {
+	if recordTypes == nil {
+		recordTypes = map[string]bool{}
+	}
+	recordTypes[recordType] = true
+	return recordTypes
+}
+
 func (im *TXTRegistry) addToCache(ep *endpoint.Endpoint) {
 	if im.recordsCache != nil {
 		im.recordsCache = append(im.recordsCache, ep)
@@ -292,3 +720,42 @@ func (im *TXTRegistry) removeFromCache(ep *endpoint.Endpoint) {
 		}
 	}
 }
+
+// namedRecordTypes are the record types recognized in the names of TXT records
+var namedRecordTypes = []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME, endpoint.RecordTypeNS, endpoint.RecordTypeSRV, endpoint.RecordTypeTXT}
+
+// recordTypeNameMapper names TXT records by the record type in addition to the affix, e.g. a-foo.example.org
+// and cname-foo.example.org, so that the ownership of records of different types with the same name is stored
+// separately and TXT records don't share their name with a CNAME. TXT records shared by all record types,
+// as named by affixNameMapper, are still recognized.
+type recordTypeNameMapper struct {
+	affixNameMapper
+}
+
+var _ nameMapper = recordTypeNameMapper{}
+
+func newRecordTypeNameMapper(prefix string, suffix string, wildcardReplacement string) recordTypeNameMapper {
+	return recordTypeNameMapper{newaffixNameMapper(prefix, suffix, wildcardReplacement)}
+}
+
+func (pr recordTypeNameMapper) toEndpointName(txtDNSName string) (string, string) {
+	endpointName := pr.trimAffix(txtDNSName)
+	for _, recordType := range namedRecordTypes {
+		if prefix := strings.ToLower(recordType) + "-"; strings.HasPrefix(endpointName, prefix) {
+			return strings.TrimPrefix(endpointName, prefix), recordType
+		}
+	}
+	return endpointName, ""
+}
+
+func (pr recordTypeNameMapper) toTXTName(endpointDNSName, recordType string) string {
+	if recordType == "" {
+		return pr.affixNameMapper.toTXTName(endpointDNSName, "")
+	}
+	return pr.addAffix(withRecordTypePrefix(pr.replaceWildcard(endpointDNSName), recordType))
+}
+
+// withRecordTypePrefix prefixes the first label of the name with the record type
+func withRecordTypePrefix(dnsName, recordType string) string {
+	return strings.ToLower(recordType) + "-" + dnsName
+}
@@ -40,24 +40,28 @@ func TestTXTRegistry(t *testing.T) {
 	t.Run("TestNewTXTRegistry", testTXTRegistryNew)
 	t.Run("TestRecords", testTXTRegistryRecords)
 	t.Run("TestApplyChanges", testTXTRegistryApplyChanges)
+	t.Run("TestOrphans", testTXTRegistryOrphans)
+	t.Run("TestOwnershipClaims", testTXTRegistryOwnershipClaims)
+	t.Run("TestProtectedLabels", testTXTRegistryProtectedLabels)
+	t.Run("TestRecordTypeNames", testTXTRegistryRecordTypeNames)
 }
 
 func testTXTRegistryNew(t *testing.T) {
 	p := inmemory.NewInMemoryProvider()
-	_, err := NewTXTRegistry(p, "txt", "", "", time.Hour, "")
+	_, err := NewTXTRegistry(p, "txt", "", "", time.Hour, "", TXTRegistryOptions{})
 	require.Error(t, err)
 
-	_, err = NewTXTRegistry(p, "", "txt", "", time.Hour, "")
+	_, err = NewTXTRegistry(p, "", "txt", "", time.Hour, "", TXTRegistryOptions{})
 	require.Error(t, err)
 
-	r, err := NewTXTRegistry(p, "txt", "", "owner", time.Hour, "")
+	r, err := NewTXTRegistry(p, "txt", "", "owner", time.Hour, "", TXTRegistryOptions{})
 	require.NoError(t, err)
 	assert.Equal(t, p, r.provider)
 
-	r, err = NewTXTRegistry(p, "", "txt", "owner", time.Hour, "")
+	r, err = NewTXTRegistry(p, "", "txt", "owner", time.Hour, "", TXTRegistryOptions{})
 	require.NoError(t, err)
 
-	_, err = NewTXTRegistry(p, "txt", "txt", "owner", time.Hour, "")
+	_, err = NewTXTRegistry(p, "txt", "txt", "owner", time.Hour, "", TXTRegistryOptions{})
 	require.Error(t, err)
 
 	_, ok := r.mapper.(affixNameMapper)
@@ -65,7 +69,7 @@ func testTXTRegistryNew(t *testing.T) {
 	assert.Equal(t, "owner", r.ownerID)
 	assert.Equal(t, p, r.provider)
 
-	r, err = NewTXTRegistry(p, "", "", "owner", time.Hour, "")
+	r, err = NewTXTRegistry(p, "", "", "owner", time.Hour, "", TXTRegistryOptions{})
 	require.NoError(t, err)
 
 	_, ok = r.mapper.(affixNameMapper)
@@ -181,13 +185,13 @@ func testTXTRegistryRecordsPrefixed(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour, "wc")
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour, "wc", TXTRegistryOptions{})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 
 	// Ensure prefix is case-insensitive
-	r, _ = NewTXTRegistry(p, "TxT.", "", "owner", time.Hour, "")
+	r, _ = NewTXTRegistry(p, "TxT.", "", "owner", time.Hour, "", TXTRegistryOptions{})
 	records, _ = r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpointLabels(records, expectedRecords))
@@ -286,13 +290,13 @@ func testTXTRegistryRecordsSuffixed(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "", "-txt", "owner", time.Hour, "")
+	r, _ := NewTXTRegistry(p, "", "-txt", "owner", time.Hour, "", TXTRegistryOptions{})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
 
 	// Ensure prefix is case-insensitive
-	r, _ = NewTXTRegistry(p, "", "-TxT", "owner", time.Hour, "")
+	r, _ = NewTXTRegistry(p, "", "-TxT", "owner", time.Hour, "", TXTRegistryOptions{})
 	records, _ = r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpointLabels(records, expectedRecords))
@@ -367,7 +371,7 @@ func testTXTRegistryRecordsNoPrefix(t *testing.T) {
 		},
 	}
 
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "")
+	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", TXTRegistryOptions{})
 	records, _ := r.Records(ctx)
 
 	assert.True(t, testutils.SameEndpoints(records, expectedRecords))
@@ -377,6 +381,57 @@ func testTXTRegistryApplyChanges(t *testing.T) {
 	t.Run("With Prefix", testTXTRegistryApplyChangesWithPrefix)
 	t.Run("With Suffix", testTXTRegistryApplyChangesWithSuffix)
 	t.Run("No prefix", testTXTRegistryApplyChangesNoPrefix)
+	t.Run("Dual stack", testTXTRegistryApplyChangesDualStack)
+}
+
+func testTXTRegistryApplyChangesDualStack(t *testing.T) {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	ctx := context.Background()
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{})
+
+	// A and AAAA records of the same name share a single TXT record
+	err := r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("dual.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "service/default/dual"),
+			newEndpointWithOwnerResource("dual.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "", "service/default/dual"),
+		},
+	})
+	require.NoError(t, err)
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("dual.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/dual"),
+		newEndpointWithOwnerResource("dual.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "owner", "service/default/dual"),
+	}))
+
+	// the TXT record is kept while the AAAA record remains
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.Delete, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("dual.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/dual"),
+		}))
+	}
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("dual.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/dual"),
+		},
+	})
+	require.NoError(t, err)
+
+	// and deleted together with the last record
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.Delete, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("dual.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "owner", "service/default/dual"),
+			newEndpointWithOwner("txt.dual.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/dual\"", endpoint.RecordTypeTXT, ""),
+		}))
+	}
+	err = r.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("dual.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, "owner", "service/default/dual"),
+		},
+	})
+	require.NoError(t, err)
 }
 
 func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
@@ -404,7 +459,7 @@ func testTXTRegistryApplyChangesWithPrefix(t *testing.T) {
 			newEndpointWithOwner("txt.multiple.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour, "")
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", time.Hour, "", TXTRegistryOptions{})
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -498,7 +553,7 @@ func testTXTRegistryApplyChangesWithSuffix(t *testing.T) {
 			newEndpointWithOwner("multiple-txt.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "").WithSetIdentifier("test-set-2"),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "", "-txt", "owner", time.Hour, "wildcard")
+	r, _ := NewTXTRegistry(p, "", "-txt", "owner", time.Hour, "wildcard", TXTRegistryOptions{})
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -591,7 +646,7 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 			newEndpointWithOwner("foobar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
 		},
 	})
-	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "")
+	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", TXTRegistryOptions{})
 
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
@@ -642,6 +697,520 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func testTXTRegistryOrphans(t *testing.T) {
+	t.Run("Detection", testTXTRegistryOrphansDetection)
+	t.Run("Deletion", testTXTRegistryOrphansDeletion)
+	t.Run("Take over", testTXTRegistryOrphansTakeOver)
+}
+
+func newOrphanProvider(t *testing.T) *inmemory.InMemoryProvider {
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("txt.foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("txt.other.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=other\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("unrelated.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	}))
+	return p
+}
+
+func testTXTRegistryOrphansDetection(t *testing.T) {
+	ctx := context.Background()
+	p := newOrphanProvider(t)
+
+	// orphans are only reported when their deletion is enabled
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{})
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpointLabels(records, []*endpoint.Endpoint{
+		newEndpointWithOwner("bar.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"),
+		newEndpointWithOwner("txt.foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("txt.bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("txt.other.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=other\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("unrelated.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+	}))
+	assert.Len(t, r.orphans, 1)
+
+	// TXT records of other owners and which don't match the prefix are never orphaned
+	r, _ = NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{DeleteOrphans: true})
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+	orphan := records[5]
+	assert.True(t, testutils.SameEndpoint(orphan, newEndpointWithOwner("txt.foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "owner")))
+	assert.Equal(t, endpoint.Labels{
+		endpoint.OwnerLabelKey:             "owner",
+		endpoint.OrphanedOwnershipLabelKey: "foo.test-zone.example.org",
+	}, orphan.Labels)
+}
+
+func testTXTRegistryOrphansDeletion(t *testing.T) {
+	ctx := context.Background()
+	p := newOrphanProvider(t)
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{DeleteOrphans: true})
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+
+	var orphans []*endpoint.Endpoint
+	for _, ep := range records {
+		if _, ok := ep.Labels[endpoint.OrphanedOwnershipLabelKey]; ok {
+			orphans = append(orphans, ep)
+		}
+	}
+	require.Len(t, orphans, 1)
+
+	// the orphan is deleted without looking for another TXT record
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.Empty(t, got.Create)
+		assert.True(t, testutils.SameEndpoints(got.Delete, []*endpoint.Endpoint{
+			newEndpointWithOwner("txt.foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "owner"),
+		}))
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Delete: orphans}))
+
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {}
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 4)
+}
+
+func testTXTRegistryOrphansTakeOver(t *testing.T) {
+	ctx := context.Background()
+	p := newOrphanProvider(t)
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{DeleteOrphans: true})
+	_, err := r.Records(ctx)
+	require.NoError(t, err)
+
+	// a record created again takes over its orphaned TXT record instead of creating a conflicting one
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.Create, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "owner", "service/default/foo"),
+		}))
+		assert.True(t, testutils.SameEndpoints(got.UpdateOld, []*endpoint.Endpoint{
+			newEndpointWithOwner("txt.foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, "owner"),
+		}))
+		assert.True(t, testutils.SameEndpoints(got.UpdateNew, []*endpoint.Endpoint{
+			newEndpointWithOwner("txt.foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/foo\"", endpoint.RecordTypeTXT, ""),
+		}))
+		assert.Empty(t, got.Delete)
+	}
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "", "service/default/foo"),
+		},
+	}))
+	assert.Empty(t, r.orphans)
+}
+
+func testTXTRegistryOwnershipClaims(t *testing.T) {
+	t.Run("Adoption", testTXTRegistryAdoption)
+	t.Run("Handover", testTXTRegistryHandover)
+	t.Run("Conflict resolution labels", testTXTRegistryConflictResolutionLabels)
+}
+
+// syncTXTRegistry calculates the plan for the desired records and applies it like the controller
+func syncTXTRegistry(t *testing.T, r *TXTRegistry, desired []*endpoint.Endpoint, adoptionDomainFilter endpoint.DomainFilterInterface) *plan.Changes {
+	ctx := context.Background()
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	changes := (&plan.Plan{
+		Policies:             []plan.Policy{&plan.SyncPolicy{}},
+		Current:              records,
+		Desired:              desired,
+		ManagedRecords:       []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		AdoptionDomainFilter: adoptionDomainFilter,
+	}).Calculate().Changes
+	if changes.HasChanges() {
+		require.NoError(t, r.ApplyChanges(ctx, changes))
+	}
+	return changes
+}
+
+func testTXTRegistryAdoption(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		},
+	}))
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{})
+	desired := []*endpoint.Endpoint{
+		newEndpointWithOwnerAndLabels("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", endpoint.Labels{
+			endpoint.ResourceLabelKey: "service/default/legacy",
+			endpoint.AdoptLabelKey:    "true",
+		}),
+	}
+
+	// records without owner are left alone unless their domain allows the adoption
+	changes := syncTXTRegistry(t, r, desired, nil)
+	assert.False(t, changes.HasChanges())
+
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.Create, []*endpoint.Endpoint{
+			newEndpointWithOwner("txt.legacy.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/legacy\"", endpoint.RecordTypeTXT, ""),
+		}))
+		assert.True(t, testutils.SameEndpoints(got.UpdateNew, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("legacy.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/legacy"),
+		}))
+	}
+	changes = syncTXTRegistry(t, r, desired, endpoint.NewDomainFilter([]string{testZone}))
+	assert.True(t, changes.HasChanges())
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range records {
+		if ep.RecordType == endpoint.RecordTypeA {
+			assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey])
+		}
+	}
+
+	// once adopted the record is up to date
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {}
+	changes = syncTXTRegistry(t, r, desired, endpoint.NewDomainFilter([]string{testZone}))
+	assert.False(t, changes.HasChanges())
+}
+
+func testTXTRegistryConflictResolutionLabels(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{})
+	desired := []*endpoint.Endpoint{
+		newEndpointWithOwnerAndLabels("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", endpoint.Labels{
+			endpoint.ResourceLabelKey:          "service/default/foo",
+			endpoint.OwnershipPriorityLabelKey: "10",
+			endpoint.CreationTimestampLabelKey: "2020-01-01T00:00:00Z",
+		}),
+	}
+
+	// the labels are only used to resolve conflicts and aren't stored in the TXT record
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.Create, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/foo"),
+			newEndpointWithOwner("txt.foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/foo\"", endpoint.RecordTypeTXT, ""),
+		}))
+	}
+	changes := syncTXTRegistry(t, r, desired, nil)
+	assert.True(t, changes.HasChanges())
+
+	// the record is up to date as the labels don't take part in the comparison
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {}
+	changes = syncTXTRegistry(t, r, desired, nil)
+	assert.False(t, changes.HasChanges())
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/foo"),
+	}))
+}
+
+func testTXTRegistryHandover(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	oldLabels := endpoint.Labels{endpoint.OwnerLabelKey: "old", endpoint.ResourceLabelKey: "service/default/foo"}
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.foo.test-zone.example.org", oldLabels.Serialize(true), endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.bar.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=old\"", endpoint.RecordTypeTXT, ""),
+		},
+	}))
+
+	_, err := NewTXTRegistry(p, "txt.", "", "new", 0, "", TXTRegistryOptions{PreviousOwnerID: "new"})
+	require.Error(t, err)
+
+	r, err := NewTXTRegistry(p, "txt.", "", "new", 0, "", TXTRegistryOptions{PreviousOwnerID: "old"})
+	require.NoError(t, err)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range records {
+		if ep.RecordType == endpoint.RecordTypeA {
+			assert.Equal(t, "old", ep.Labels[endpoint.PreviousOwnerLabelKey], ep.DNSName)
+		}
+	}
+
+	// only the desired records are handed over, the others are left to the previous owner
+	desired := []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "service/default/foo"),
+	}
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.UpdateOld, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "old", "service/default/foo"),
+			newEndpointWithOwner("txt.foo.test-zone.example.org", oldLabels.Serialize(true), endpoint.RecordTypeTXT, ""),
+		}))
+		assert.True(t, testutils.SameEndpoints(got.UpdateNew, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "new", "service/default/foo"),
+			newEndpointWithOwner("txt.foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=new,external-dns/resource=service/default/foo\"", endpoint.RecordTypeTXT, ""),
+		}))
+		assert.Empty(t, got.Delete)
+	}
+	changes := syncTXTRegistry(t, r, desired, nil)
+	assert.True(t, changes.HasChanges())
+
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range records {
+		switch ep.DNSName {
+		case "foo.test-zone.example.org":
+			assert.Equal(t, "new", ep.Labels[endpoint.OwnerLabelKey])
+			assert.NotContains(t, ep.Labels, endpoint.PreviousOwnerLabelKey)
+		case "bar.test-zone.example.org":
+			assert.Equal(t, "old", ep.Labels[endpoint.OwnerLabelKey])
+		}
+	}
+
+	// the records of the previous owner are never changed
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.Empty(t, got.Create)
+		assert.Empty(t, got.UpdateNew)
+		assert.Empty(t, got.Delete)
+	}
+	syncTXTRegistry(t, r, desired, nil)
+}
+
+func testTXTRegistryProtectedLabels(t *testing.T) {
+	t.Run("Encrypted", testTXTRegistryEncrypted)
+	t.Run("Switch over", testTXTRegistryProtectedSwitchOver)
+	t.Run("Copied to another name", testTXTRegistryProtectedCopied)
+}
+
+func newTestLabelsCodec(t *testing.T, allowUnprotected bool) *endpoint.LabelsCodec {
+	codec, err := endpoint.NewLabelsCodec([]byte("0123456789abcdef0123456789abcdef"), true, true, allowUnprotected)
+	require.NoError(t, err)
+	return codec
+}
+
+func testTXTRegistryEncrypted(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	codec := newTestLabelsCodec(t, false)
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{LabelsCodec: codec})
+
+	desired := []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "service/default/foo"),
+	}
+	changes := syncTXTRegistry(t, r, desired, nil)
+	require.Len(t, changes.Create, 1)
+
+	// the TXT record doesn't reveal the resource
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range records {
+		if ep.RecordType == endpoint.RecordTypeTXT {
+			assert.NotContains(t, ep.Targets[0], "service/default/foo")
+		}
+	}
+
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/foo"),
+	}))
+
+	// the encrypted TXT record is reconstructed to update the record
+	desired[0].Targets = endpoint.Targets{"5.6.7.8"}
+	changes = syncTXTRegistry(t, r, desired, nil)
+	assert.Len(t, changes.UpdateNew, 1)
+
+	// readers without the key treat the TXT record as a foreign one
+	r, _ = NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{})
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	for _, ep := range records {
+		assert.Empty(t, ep.Labels[endpoint.OwnerLabelKey], ep.DNSName)
+	}
+}
+
+func testTXTRegistryProtectedSwitchOver(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	plaintext := "\"heritage=external-dns,external-dns/owner=owner,external-dns/resource=service/default/foo\""
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.foo.test-zone.example.org", plaintext, endpoint.RecordTypeTXT, ""),
+		},
+	}))
+	desired := []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "", "service/default/foo"),
+	}
+
+	// TXT records in plaintext can't be trusted once the labels are protected
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{LabelsCodec: newTestLabelsCodec(t, false)})
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.Empty(t, got.UpdateNew)
+	}
+	syncTXTRegistry(t, r, desired, nil)
+
+	// unless they are accepted while switching over, the existing TXT record is replaced as the record is updated
+	r, _ = NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{LabelsCodec: newTestLabelsCodec(t, true)})
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		assert.True(t, testutils.SameEndpoints(got.UpdateOld, []*endpoint.Endpoint{
+			newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner", "service/default/foo"),
+			newEndpointWithOwner("txt.foo.test-zone.example.org", plaintext, endpoint.RecordTypeTXT, ""),
+		}))
+		require.Len(t, got.UpdateNew, 2)
+		assert.NotEqual(t, plaintext, got.UpdateNew[1].Targets[0])
+	}
+	changes := syncTXTRegistry(t, r, desired, nil)
+	assert.True(t, changes.HasChanges())
+
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {}
+	r, _ = NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{LabelsCodec: newTestLabelsCodec(t, false)})
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	assert.True(t, testutils.SameEndpoints(records, []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, "owner", "service/default/foo"),
+	}))
+}
+
+func testTXTRegistryProtectedCopied(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{LabelsCodec: newTestLabelsCodec(t, false)})
+	desired := []*endpoint.Endpoint{
+		newEndpointWithOwnerResource("foo.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, "", "service/default/foo"),
+	}
+	syncTXTRegistry(t, r, desired, nil)
+
+	// someone able to write TXT records copies the ownership record to a record of another name
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	var owned string
+	for _, ep := range records {
+		if ep.RecordType == endpoint.RecordTypeTXT {
+			owned = ep.Targets[0]
+		}
+	}
+	require.NotEmpty(t, owned)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("bar.test-zone.example.org", "5.6.7.8", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.bar.test-zone.example.org", owned, endpoint.RecordTypeTXT, ""),
+		},
+	}))
+
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range records {
+		if ep.DNSName == "bar.test-zone.example.org" {
+			assert.Empty(t, ep.Labels[endpoint.OwnerLabelKey])
+		}
+		if ep.DNSName == "foo.test-zone.example.org" {
+			assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey])
+		}
+	}
+}
+
+func testTXTRegistryRecordTypeNames(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	owned := "\"heritage=external-dns,external-dns/owner=owner\""
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "1.1.1.1", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "2001:db8::1", endpoint.RecordTypeAAAA, ""),
+			newEndpointWithOwner("txt.foo.test-zone.example.org", owned, endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "bar.example.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("txt.bar.test-zone.example.org", owned, endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("a-team.test-zone.example.org", "3.3.3.3", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.a-team.test-zone.example.org", owned, endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("baz.test-zone.example.org", "4.4.4.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.a-baz.test-zone.example.org", owned, endpoint.RecordTypeTXT, ""),
+		},
+	}))
+	r, _ := NewTXTRegistry(p, "txt.", "", "owner", 0, "", TXTRegistryOptions{DeleteOrphans: true, RecordTypeNames: true})
+
+	// TXT records shared by all record types, even those of names which start like a record type, are still recognized
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	for _, ep := range records {
+		assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey], ep.DNSName)
+		assert.NotContains(t, ep.Labels, endpoint.OrphanedOwnershipLabelKey, ep.DNSName)
+	}
+
+	desired := []*endpoint.Endpoint{
+		newEndpointWithOwner("foo.test-zone.example.org", "1.1.1.2", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("bar.test-zone.example.org", "bar.example.net", endpoint.RecordTypeCNAME, ""),
+		newEndpointWithOwner("a-team.test-zone.example.org", "3.3.3.3", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("new.test-zone.example.org", "5.5.5.5", endpoint.RecordTypeA, ""),
+	}
+	txtNames := func(endpoints []*endpoint.Endpoint) []string {
+		names := []string{}
+		for _, ep := range endpoints {
+			if ep.RecordType == endpoint.RecordTypeTXT {
+				names = append(names, ep.DNSName)
+			}
+		}
+		return names
+	}
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {
+		// new records get a TXT record of their own, so do records which are the only ones left in a shared TXT record
+		assert.ElementsMatch(t, []string{"txt.a-new.test-zone.example.org", "txt.cname-bar.test-zone.example.org"}, txtNames(got.Create))
+		assert.ElementsMatch(t, []string{"txt.a-baz.test-zone.example.org", "txt.bar.test-zone.example.org"}, txtNames(got.Delete))
+		// the TXT record shared with the AAAA record is kept
+		assert.ElementsMatch(t, []string{"txt.foo.test-zone.example.org"}, txtNames(got.UpdateOld))
+		assert.ElementsMatch(t, []string{"txt.foo.test-zone.example.org"}, txtNames(got.UpdateNew))
+	}
+	changes := syncTXTRegistry(t, r, desired, nil)
+	assert.True(t, changes.HasChanges())
+
+	p.OnApplyChanges = func(ctx context.Context, got *plan.Changes) {}
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	for _, ep := range records {
+		assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey], ep.DNSName)
+	}
+	changes = syncTXTRegistry(t, r, desired, nil)
+	assert.False(t, changes.HasChanges())
+}
+
+func TestRecordTypeNameMapper(t *testing.T) {
+	for _, tc := range []struct {
+		title        string
+		mapper       nameMapper
+		dnsName      string
+		recordType   string
+		txtName      string
+		endpointName string
+	}{
+		{"prefix", newRecordTypeNameMapper("txt.", "", ""), "foo.example.org", endpoint.RecordTypeA, "txt.a-foo.example.org", "foo.example.org"},
+		{"suffix", newRecordTypeNameMapper("", "-txt", ""), "foo.example.org", endpoint.RecordTypeCNAME, "cname-foo-txt.example.org", "foo.example.org"},
+		{"no affix", newRecordTypeNameMapper("", "", ""), "foo.example.org", endpoint.RecordTypeAAAA, "aaaa-foo.example.org", "foo.example.org"},
+		{"wildcard", newRecordTypeNameMapper("", "", "any"), "*.example.org", endpoint.RecordTypeA, "a-any.example.org", "any.example.org"},
+		{"shared", newRecordTypeNameMapper("txt.", "", ""), "foo.example.org", "", "txt.foo.example.org", "foo.example.org"},
+		{"affix", newaffixNameMapper("txt.", "", ""), "a-foo.example.org", endpoint.RecordTypeA, "txt.a-foo.example.org", "a-foo.example.org"},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			txtName := tc.mapper.toTXTName(tc.dnsName, tc.recordType)
+			assert.Equal(t, tc.txtName, txtName)
+			endpointName, recordType := tc.mapper.toEndpointName(txtName)
+			assert.Equal(t, tc.endpointName, endpointName)
+			if _, ok := tc.mapper.(affixNameMapper); ok {
+				assert.Empty(t, recordType)
+			} else {
+				assert.Equal(t, tc.recordType, recordType)
+			}
+		})
+	}
+}
+
 func TestCacheMethods(t *testing.T) {
 	cache := []*endpoint.Endpoint{
 		newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner"),
@@ -63,7 +63,7 @@ func legacyEndpointsFromMateService(svc *v1.Service) []*endpoint.Endpoint {
 	// Create a corresponding endpoint for each configured external entrypoint.
 	for _, lb := range svc.Status.LoadBalancer.Ingress {
 		if lb.IP != "" {
-			endpoints = append(endpoints, endpoint.NewEndpoint(hostname, endpoint.RecordTypeA, lb.IP))
+			endpoints = append(endpoints, endpoint.NewEndpoint(hostname, suitableType(lb.IP), lb.IP))
 		}
 		if lb.Hostname != "" {
 			endpoints = append(endpoints, endpoint.NewEndpoint(hostname, endpoint.RecordTypeCNAME, lb.Hostname))
@@ -95,7 +95,7 @@ func legacyEndpointsFromMoleculeService(svc *v1.Service) []*endpoint.Endpoint {
 		// Create a corresponding endpoint for each configured external entrypoint.
 		for _, lb := range svc.Status.LoadBalancer.Ingress {
 			if lb.IP != "" {
-				endpoints = append(endpoints, endpoint.NewEndpoint(hostname, endpoint.RecordTypeA, lb.IP))
+				endpoints = append(endpoints, endpoint.NewEndpoint(hostname, suitableType(lb.IP), lb.IP))
 			}
 			if lb.Hostname != "" {
 				endpoints = append(endpoints, endpoint.NewEndpoint(hostname, endpoint.RecordTypeCNAME, lb.Hostname))
@@ -158,10 +158,10 @@ func legacyEndpointsFromDNSControllerNodePortService(svc *v1.Service, sc *servic
 			}
 			for _, address := range node.Status.Addresses {
 				if address.Type == v1.NodeExternalIP && isExternal {
-					endpoints = append(endpoints, endpoint.NewEndpoint(hostname, endpoint.RecordTypeA, address.Address))
+					endpoints = append(endpoints, endpoint.NewEndpoint(hostname, suitableType(address.Address), address.Address))
 				}
 				if address.Type == v1.NodeInternalIP && isInternal {
-					endpoints = append(endpoints, endpoint.NewEndpoint(hostname, endpoint.RecordTypeA, address.Address))
+					endpoints = append(endpoints, endpoint.NewEndpoint(hostname, suitableType(address.Address), address.Address))
 				}
 			}
 		}
@@ -194,7 +194,7 @@ func legacyEndpointsFromDNSControllerLoadBalancerService(svc *v1.Service) []*end
 		// Create a corresponding endpoint for each configured external entrypoint.
 		for _, lb := range svc.Status.LoadBalancer.Ingress {
 			if lb.IP != "" {
-				endpoints = append(endpoints, endpoint.NewEndpoint(hostname, endpoint.RecordTypeA, lb.IP))
+				endpoints = append(endpoints, endpoint.NewEndpoint(hostname, suitableType(lb.IP), lb.IP))
 			}
 			if lb.Hostname != "" {
 				endpoints = append(endpoints, endpoint.NewEndpoint(hostname, endpoint.RecordTypeCNAME, lb.Hostname))
@@ -19,7 +19,9 @@ package source
 import (
 	"context"
 	"encoding/gob"
+	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -37,8 +39,15 @@ type connectorSource struct {
 	remoteServer string
 }
 
-// NewConnectorSource creates a new connectorSource with the given config.
-func NewConnectorSource(remoteServer string) (Source, error) {
+// NewConnectorSource creates a new connector source with the given config. A remote server given as http(s) URL
+// serves the HTTP protocol, see package connector; otherwise it's the host and port of a legacy tcp server.
+func NewConnectorSource(remoteServer, caFile, certFile, keyFile, tokenFile string) (Source, error) {
+	if strings.HasPrefix(remoteServer, "http://") || strings.HasPrefix(remoteServer, "https://") {
+		return newHTTPConnectorSource(remoteServer, caFile, certFile, keyFile, tokenFile)
+	}
+	if caFile != "" || certFile != "" || keyFile != "" || tokenFile != "" {
+		return nil, fmt.Errorf("connector server %s doesn't support authentication, use an http or https URL", remoteServer)
+	}
 	return &connectorSource{
 		remoteServer: remoteServer,
 	}, nil
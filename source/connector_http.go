@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/connector"
+	"sigs.k8s.io/external-dns/pkg/tlsutils"
+)
+
+const (
+	// connectorPollTimeout is how long the server holds a long-polling request when nothing changes
+	connectorPollTimeout = 60 * time.Second
+	// connectorRetryInterval is how long to wait before polling again after a failure or a poll without changes
+	connectorRetryInterval = 5 * time.Second
+)
+
+// httpConnectorSource is an implementation of Source that gets the endpoints from a remote server
+// speaking the HTTP protocol of package connector. Changes are watched by long-polling.
+type httpConnectorSource struct {
+	endpointsURL string
+	tokenFile    string
+	client       *http.Client
+}
+
+func newHTTPConnectorSource(remoteServer, caFile, certFile, keyFile, tokenFile string) (Source, error) {
+	if tokenFile != "" && !strings.HasPrefix(remoteServer, "https://") {
+		return nil, fmt.Errorf("connector server %s would receive the token in cleartext, use an https URL", remoteServer)
+	}
+	tlsConfig, err := tlsutils.NewTLSConfig(certFile, keyFile, caFile, "", false, tls.VersionTLS12)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &httpConnectorSource{
+		endpointsURL: strings.TrimSuffix(remoteServer, "/") + connector.EndpointsPath,
+		tokenFile:    tokenFile,
+		client:       &http.Client{Transport: transport},
+	}, nil
+}
+
+// Endpoints returns endpoint objects.
+func (cs *httpConnectorSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	response, err := cs.get(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+	for _, ep := range response.Endpoints {
+		if ep == nil {
+			continue
+		}
+		if ep.Labels == nil {
+			ep.Labels = endpoint.NewLabels()
+		}
+		endpoints = append(endpoints, ep)
+	}
+
+	log.Debugf("Received endpoints of version %s: %#v", response.Version, endpoints)
+
+	return endpoints, nil
+}
+
+// AddEventHandler calls the handler whenever the version of the endpoints changes.
+func (cs *httpConnectorSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for connector")
+
+	go cs.watch(ctx, handler)
+}
+
+func (cs *httpConnectorSource) watch(ctx context.Context, handler func()) {
+	var version *string
+	for {
+		response, err := cs.get(ctx, version)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err != nil:
+			log.Warnf("Failed to watch the endpoints of connector server %s: %v", cs.endpointsURL, err)
+		case version == nil:
+			// the first response only tells the version to wait for changes of
+			version = &response.Version
+			continue
+		case response != nil && response.Version != *version:
+			version = &response.Version
+			handler()
+			continue
+		}
+		// wait before polling again after failures and long-polls which returned without a change, so that
+		// a server not holding the requests isn't polled in a tight loop
+		select {
+		case <-time.After(connectorRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// get gets the endpoints. If a version is given, the request long-polls until the endpoints have another
+// version and returns nil if they don't.
+func (cs *httpConnectorSource) get(ctx context.Context, version *string) (*connector.EndpointsResponse, error) {
+	timeout := dialTimeout
+	query := url.Values{}
+	if version != nil {
+		timeout += connectorPollTimeout
+		query.Set(connector.WaitParameter, *version)
+		query.Set(connector.TimeoutParameter, strconv.Itoa(int(connectorPollTimeout.Seconds())))
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	endpointsURL := cs.endpointsURL
+	if len(query) > 0 {
+		endpointsURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpointsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if cs.tokenFile != "" {
+		// the token is read every time, so that it can be rotated
+		token, err := ioutil.ReadFile(cs.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token from file (%s): %v", cs.tokenFile, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && version != nil {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get endpoints from %s, got: %s", cs.endpointsURL, resp.Status)
+	}
+
+	response := &connector.EndpointsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, fmt.Errorf("failed to decode endpoints from %s: %v", cs.endpointsURL, err)
+	}
+	return response, nil
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/connector"
+)
+
+func TestHTTPConnectorSourceEndpoints(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 180},
+		{DNSName: "xyz.example.org", Targets: endpoint.Targets{"abc.example.org"}, RecordType: endpoint.RecordTypeCNAME, RecordTTL: 180},
+	}
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600))
+	wrongTokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(wrongTokenFile, []byte("other"), 0600))
+
+	for _, ti := range []struct {
+		title       string
+		token       string
+		tokenFile   string
+		endpoints   []*endpoint.Endpoint
+		expectError bool
+	}{
+		{
+			title:     "endpoints",
+			endpoints: endpoints,
+		},
+		{
+			title:     "no endpoints",
+			endpoints: []*endpoint.Endpoint{},
+		},
+		{
+			title:       "endpoints not set yet",
+			expectError: true,
+		},
+		{
+			title:     "token",
+			token:     "secret",
+			tokenFile: tokenFile,
+			endpoints: endpoints,
+		},
+		{
+			title:       "wrong token",
+			token:       "secret",
+			tokenFile:   wrongTokenFile,
+			endpoints:   endpoints,
+			expectError: true,
+		},
+		{
+			title:       "missing token",
+			token:       "secret",
+			endpoints:   endpoints,
+			expectError: true,
+		},
+	} {
+		t.Run(ti.title, func(t *testing.T) {
+			server := connector.NewServer(ti.token, 0)
+			if ti.endpoints != nil {
+				server.SetEndpoints(ti.endpoints)
+			}
+			// tokens are only sent over https
+			httpServer := httptest.NewTLSServer(server)
+			defer httpServer.Close()
+
+			cs, err := NewConnectorSource(httpServer.URL+"/", writeServerCA(t, httpServer), "", "", ti.tokenFile)
+			require.NoError(t, err)
+
+			received, err := cs.Endpoints(context.Background())
+			if ti.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			validateEndpoints(t, received, ti.endpoints)
+		})
+	}
+}
+
+func TestHTTPConnectorSourceTLS(t *testing.T) {
+	server := connector.NewServer("", 0)
+	server.SetEndpoints([]*endpoint.Endpoint{{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA}})
+	httpServer := httptest.NewTLSServer(server)
+	defer httpServer.Close()
+
+	caFile := writeServerCA(t, httpServer)
+
+	// the certificate of the server isn't trusted without the CA
+	cs, err := NewConnectorSource(httpServer.URL, "", "", "", "")
+	require.NoError(t, err)
+	_, err = cs.Endpoints(context.Background())
+	assert.Error(t, err)
+
+	cs, err = NewConnectorSource(httpServer.URL, caFile, "", "", "")
+	require.NoError(t, err)
+	received, err := cs.Endpoints(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, received, 1)
+
+	_, err = NewConnectorSource(httpServer.URL, caFile, "client.crt", "", "")
+	assert.Error(t, err, "a client certificate requires a key")
+}
+
+func TestConnectorSourceAuthenticationRequiresHTTP(t *testing.T) {
+	_, err := NewConnectorSource("localhost:8080", "", "", "", "/etc/connector/token")
+	assert.Error(t, err)
+
+	_, err = NewConnectorSource("http://localhost:8080", "", "", "", "/etc/connector/token")
+	assert.Error(t, err, "the token must not be sent in cleartext")
+}
+
+func TestHTTPConnectorSourceAddEventHandler(t *testing.T) {
+	server := connector.NewServer("", 0)
+	server.SetEndpoints(nil)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	cs, err := NewConnectorSource(httpServer.URL, "", "", "", "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan struct{}, 1)
+	cs.AddEventHandler(ctx, func() {
+		select {
+		case handled <- struct{}{}:
+		default:
+		}
+	})
+
+	// the endpoints are changed until the handler is called, since changes before the watch started aren't noticed
+	timeout := time.After(10 * time.Second)
+	for i := 1; ; i++ {
+		server.SetEndpoints([]*endpoint.Endpoint{{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: endpoint.TTL(i)}})
+		select {
+		case <-handled:
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("event handler wasn't called")
+		}
+	}
+}
+
+func TestHTTPConnectorSourceAddEventHandlerUnchanged(t *testing.T) {
+	// the server doesn't hold the requests and always returns the same version
+	requests := make(chan struct{}, 100)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- struct{}{}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(&connector.EndpointsResponse{Version: "1", Endpoints: []*endpoint.Endpoint{}}))
+	}))
+	defer httpServer.Close()
+
+	cs, err := NewConnectorSource(httpServer.URL, "", "", "", "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan struct{}, 1)
+	cs.AddEventHandler(ctx, func() {
+		select {
+		case handled <- struct{}{}:
+		default:
+		}
+	})
+
+	// the handler isn't called and the server isn't polled in a tight loop
+	select {
+	case <-handled:
+		t.Fatal("event handler was called without a change")
+	case <-time.After(time.Second):
+	}
+	assert.LessOrEqual(t, len(requests), 2)
+}
+
+// writeServerCA writes the certificate of a test server to a file to be trusted as CA
+func writeServerCA(t *testing.T, httpServer *httptest.Server) string {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: httpServer.Certificate().Raw}), 0600))
+	return caFile
+}
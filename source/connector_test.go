@@ -123,7 +123,7 @@ func testConnectorSourceEndpoints(t *testing.T) {
 			if ti.serverListenAddress != "" {
 				startServerToServeTargets(t, ti.serverListenAddress, ti.expected)
 			}
-			cs, _ := NewConnectorSource(ti.serverAddress)
+			cs, _ := NewConnectorSource(ti.serverAddress, "", "", "", "")
 
 			endpoints, err := cs.Endpoints(context.Background())
 			if ti.expectError {
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// countingSource is a Source that reports the number of endpoints returned by its wrapped source.
+type countingSource struct {
+	source Source
+	count  func(int)
+}
+
+// NewCountingSource creates a new countingSource wrapping the provided Source, which calls count
+// with the number of endpoints every time they are collected successfully.
+func NewCountingSource(source Source, count func(int)) Source {
+	return &countingSource{source: source, count: count}
+}
+
+// Endpoints collects endpoints from its wrapped source and reports their number.
+func (cs *countingSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	endpoints, err := cs.source.Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cs.count(len(endpoints))
+	return endpoints, nil
+}
+
+func (cs *countingSource) AddEventHandler(ctx context.Context, handler func()) {
+	cs.source.AddEventHandler(ctx, handler)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+)
+
+// Validates that countingSource is a Source
+var _ Source = &countingSource{}
+
+func TestCountingSource(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
+		{DNSName: "bar.example.org", Targets: endpoint.Targets{"4.5.6.7"}},
+	}
+	mockSource := new(testutils.MockSource)
+	mockSource.On("Endpoints").Return(endpoints, nil)
+
+	counted := -1
+	source := NewCountingSource(mockSource, func(count int) { counted = count })
+
+	collected, err := source.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, collected, endpoints)
+	assert.Equal(t, 2, counted)
+	mockSource.AssertExpectations(t)
+}
+
+func TestCountingSourceError(t *testing.T) {
+	mockSource := new(testutils.MockSource)
+	mockSource.On("Endpoints").Return([]*endpoint.Endpoint(nil), errors.New("source error"))
+
+	counted := -1
+	source := NewCountingSource(mockSource, func(count int) { counted = count })
+
+	_, err := source.Endpoints(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, -1, counted)
+}
@@ -20,16 +20,26 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -44,6 +54,12 @@ type crdSource struct {
 	codec            runtime.ParameterCodec
 	annotationFilter string
 	labelFilter      string
+	informer         cache.SharedIndexInformer
+
+	// generations of the DNSEndpoints the last endpoints were read from by namespace/name, which
+	// ReportResults writes to the status as observed together with the conditions
+	generationsLock sync.Mutex
+	generations     map[string]int64
 }
 
 func addKnownTypes(scheme *runtime.Scheme, groupVersion schema.GroupVersion) error {
@@ -104,44 +120,121 @@ func NewCRDClientForAPIVersionKind(client kubernetes.Interface, kubeConfig, apiS
 
 // NewCRDSource creates a new crdSource with the given config.
 func NewCRDSource(crdClient rest.Interface, namespace, kind string, annotationFilter string, labelFilter string, scheme *runtime.Scheme) (Source, error) {
-	return &crdSource{
+	cs := &crdSource{
 		crdResource:      strings.ToLower(kind) + "s",
 		namespace:        namespace,
 		annotationFilter: annotationFilter,
 		labelFilter:      labelFilter,
 		crdClient:        crdClient,
 		codec:            runtime.NewParameterCodec(scheme),
-	}, nil
+	}
+
+	// fail right away if the CRD can't be listed, instead of waiting for the cache to time out
+	if _, err := cs.List(context.TODO(), &metav1.ListOptions{LabelSelector: labelFilter, Limit: 1}); err != nil {
+		return nil, err
+	}
+
+	// Use shared informer to listen for add/update/delete of the CRD in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	cs.informer = cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = labelFilter
+				return cs.List(context.TODO(), &options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = labelFilter
+				return cs.Watch(context.TODO(), &options)
+			},
+		},
+		&endpoint.DNSEndpoint{},
+		0,
+		cache.Indexers{},
+	)
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	go cs.informer.Run(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err := poll(time.Second, 60*time.Second, func() (bool, error) {
+		return cs.informer.HasSynced(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync cache: %v", err)
+	}
+
+	return cs, nil
 }
 
 func (cs *crdSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for CRD")
+
+	// Right now there is no way to remove event handler from informer, see:
+	// https://github.com/kubernetes/kubernetes/issues/79610
+	cs.informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				handler()
+			},
+			UpdateFunc: func(old interface{}, new interface{}) {
+				// the status written after every synchronization doesn't change the endpoints
+				if endpointsMayChange(old, new) {
+					handler()
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				handler()
+			},
+		},
+	)
+}
+
+// endpointsMayChange returns whether an update of a DNSEndpoint may change its endpoints, which
+// updates of the status subresource don't since they change neither the generation nor the metadata
+func endpointsMayChange(old, new interface{}) bool {
+	oldEndpoint, ok := old.(*endpoint.DNSEndpoint)
+	if !ok {
+		return true
+	}
+	newEndpoint, ok := new.(*endpoint.DNSEndpoint)
+	if !ok {
+		return true
+	}
+	return oldEndpoint.Generation != newEndpoint.Generation ||
+		!reflect.DeepEqual(oldEndpoint.Labels, newEndpoint.Labels) ||
+		!reflect.DeepEqual(oldEndpoint.Annotations, newEndpoint.Annotations)
 }
 
 // Endpoints returns endpoint objects.
 func (cs *crdSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	endpoints := []*endpoint.Endpoint{}
 
-	var (
-		result *endpoint.DNSEndpointList
-		err    error
-	)
-
-	if cs.labelFilter != "" {
-		result, err = cs.List(ctx, &metav1.ListOptions{LabelSelector: cs.labelFilter})
-	} else {
-		result, err = cs.List(ctx, &metav1.ListOptions{})
-	}
-	if err != nil {
-		return nil, err
+	result := &endpoint.DNSEndpointList{}
+	for _, obj := range cs.informer.GetStore().List() {
+		dnsEndpoint, ok := obj.(*endpoint.DNSEndpoint)
+		if !ok {
+			continue
+		}
+		// the endpoints are labeled and the status is updated, which must not modify the cached objects
+		result.Items = append(result.Items, *dnsEndpoint.DeepCopy())
 	}
+	sort.Slice(result.Items, func(i, j int) bool {
+		if result.Items[i].Namespace != result.Items[j].Namespace {
+			return result.Items[i].Namespace < result.Items[j].Namespace
+		}
+		return result.Items[i].Name < result.Items[j].Name
+	})
 
-	result, err = cs.filterByAnnotations(result)
+	result, err := cs.filterByAnnotations(result)
 
 	if err != nil {
 		return nil, err
 	}
 
+	generations := map[string]int64{}
 	for _, dnsEndpoint := range result.Items {
+		generations[dnsEndpoint.Namespace+"/"+dnsEndpoint.Name] = dnsEndpoint.Generation
+
 		// Make sure that all endpoints have targets for A or CNAME type
 		crdEndpoints := []*endpoint.Endpoint{}
 		for _, ep := range dnsEndpoint.Spec.Endpoints {
@@ -171,25 +264,19 @@ func (cs *crdSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 
 		cs.setResourceLabel(&dnsEndpoint, crdEndpoints)
 		endpoints = append(endpoints, crdEndpoints...)
-
-		if dnsEndpoint.Status.ObservedGeneration == dnsEndpoint.Generation {
-			continue
-		}
-
-		dnsEndpoint.Status.ObservedGeneration = dnsEndpoint.Generation
-		// Update the ObservedGeneration
-		_, err = cs.UpdateStatus(ctx, &dnsEndpoint)
-		if err != nil {
-			log.Warnf("Could not update ObservedGeneration of the CRD: %v", err)
-		}
 	}
 
+	cs.generationsLock.Lock()
+	cs.generations = generations
+	cs.generationsLock.Unlock()
+
 	return endpoints, nil
 }
 
 func (cs *crdSource) setResourceLabel(crd *endpoint.DNSEndpoint, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("crd/%s/%s", crd.ObjectMeta.Namespace, crd.ObjectMeta.Name)
+		setConflictResolutionLabels(ep, crd.ObjectMeta)
 	}
 }
 
@@ -204,6 +291,15 @@ func (cs *crdSource) List(ctx context.Context, opts *metav1.ListOptions) (result
 	return
 }
 
+func (cs *crdSource) Watch(ctx context.Context, opts *metav1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return cs.crdClient.Get().
+		Namespace(cs.namespace).
+		Resource(cs.crdResource).
+		VersionedParams(opts, cs.codec).
+		Watch(ctx)
+}
+
 func (cs *crdSource) UpdateStatus(ctx context.Context, dnsEndpoint *endpoint.DNSEndpoint) (result *endpoint.DNSEndpoint, err error) {
 	result = &endpoint.DNSEndpoint{}
 	err = cs.crdClient.Put().
@@ -217,6 +313,139 @@ func (cs *crdSource) UpdateStatus(ctx context.Context, dnsEndpoint *endpoint.DNS
 	return
 }
 
+func (cs *crdSource) Get(ctx context.Context, namespace, name string) (result *endpoint.DNSEndpoint, err error) {
+	result = &endpoint.DNSEndpoint{}
+	err = cs.crdClient.Get().
+		Namespace(namespace).
+		Resource(cs.crdResource).
+		Name(name).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// ReportResults writes the conditions and the published records of the last synchronization
+// to the status of the DNSEndpoints, unless they are up to date. The generation the endpoints were
+// read from is written as observed at the same time, so that there is a single writer of the status.
+func (cs *crdSource) ReportResults(ctx context.Context, results []EndpointResult) {
+	byResource := map[string][]EndpointResult{}
+	for _, result := range results {
+		resource := result.Endpoint.Labels[endpoint.ResourceLabelKey]
+		if strings.HasPrefix(resource, "crd/") {
+			key := strings.TrimPrefix(resource, "crd/")
+			byResource[key] = append(byResource[key], result)
+		}
+	}
+	cs.generationsLock.Lock()
+	generations := cs.generations
+	cs.generationsLock.Unlock()
+	// DNSEndpoints without results, e.g. without valid endpoints, only get their observed generation updated
+	keys := map[string]bool{}
+	for key := range byResource {
+		keys[key] = true
+	}
+	for key := range generations {
+		keys[key] = true
+	}
+
+	now := metav1.Now()
+	for key := range keys {
+		// the cached DNSEndpoint may be outdated, its resource version makes the update fail in that case
+		obj, exists, err := cs.informer.GetStore().GetByKey(key)
+		if err != nil || !exists {
+			log.Debugf("Could not find crd/%s to update its status: %v", key, err)
+			continue
+		}
+		cached, ok := obj.(*endpoint.DNSEndpoint)
+		if !ok {
+			continue
+		}
+		status := *cached.Status.DeepCopy()
+		if results, ok := byResource[key]; ok {
+			status = newDNSEndpointStatus(cached.Status, results, now)
+		}
+		if generation, ok := generations[key]; ok {
+			status.ObservedGeneration = generation
+		}
+		if equality.Semantic.DeepEqual(status, cached.Status) {
+			continue
+		}
+		dnsEndpoint := cached.DeepCopy()
+		dnsEndpoint.Status = status
+		if _, err := cs.UpdateStatus(ctx, dnsEndpoint); err != nil {
+			if apierrors.IsConflict(err) {
+				log.Debugf("Status of crd/%s changed meanwhile, it is updated on the next synchronization", key)
+				continue
+			}
+			log.Warnf("Could not update the status of crd/%s: %v", key, err)
+		}
+	}
+}
+
+// newDNSEndpointStatus returns the status of a DNSEndpoint with the given results of its endpoints.
+// Conditions keep their transition time as long as their status doesn't change.
+func newDNSEndpointStatus(previous endpoint.DNSEndpointStatus, results []EndpointResult, now metav1.Time) endpoint.DNSEndpointStatus {
+	status := endpoint.DNSEndpointStatus{ObservedGeneration: previous.ObservedGeneration}
+
+	var conflicts, errs, pending []string
+	for _, result := range results {
+		ep := result.Endpoint
+		status.Endpoints = append(status.Endpoints, endpoint.DNSEndpointRecordStatus{
+			DNSName:       ep.DNSName,
+			RecordType:    ep.RecordType,
+			SetIdentifier: ep.SetIdentifier,
+			Targets:       result.Targets,
+		})
+		switch {
+		case result.Err != nil:
+			errs = append(errs, fmt.Sprintf("%s: %v", ep.DNSName, result.Err))
+		case result.Conflict != "":
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s", ep.DNSName, result.Conflict))
+		case !result.Targets.Same(ep.Targets):
+			pending = append(pending, ep.DNSName)
+		}
+	}
+	sort.Slice(status.Endpoints, func(i, j int) bool {
+		a, b := status.Endpoints[i], status.Endpoints[j]
+		if a.DNSName != b.DNSName {
+			return a.DNSName < b.DNSName
+		}
+		if a.RecordType != b.RecordType {
+			return a.RecordType < b.RecordType
+		}
+		return a.SetIdentifier < b.SetIdentifier
+	})
+
+	ready := endpoint.DNSEndpointCondition{Type: endpoint.DNSEndpointReady, Status: corev1.ConditionTrue, Reason: "RecordsPublished", Message: "All records are published"}
+	switch {
+	case len(errs) > 0:
+		ready.Status, ready.Reason, ready.Message = corev1.ConditionFalse, "ProviderError", "The DNS provider rejected the changes of records"
+	case len(conflicts) > 0:
+		ready.Status, ready.Reason, ready.Message = corev1.ConditionFalse, "Conflict", "Records are owned or claimed by someone else"
+	case len(pending) > 0:
+		ready.Status, ready.Reason, ready.Message = corev1.ConditionFalse, "Pending", "Records aren't published yet: "+strings.Join(pending, ", ")
+	}
+	conflict := endpoint.DNSEndpointCondition{Type: endpoint.DNSEndpointConflict, Status: corev1.ConditionFalse, Reason: "NoConflict"}
+	if len(conflicts) > 0 {
+		conflict.Status, conflict.Reason, conflict.Message = corev1.ConditionTrue, "RecordConflict", strings.Join(conflicts, "; ")
+	}
+	providerError := endpoint.DNSEndpointCondition{Type: endpoint.DNSEndpointProviderError, Status: corev1.ConditionFalse, Reason: "ChangesApplied"}
+	if len(errs) > 0 {
+		providerError.Status, providerError.Reason, providerError.Message = corev1.ConditionTrue, "ChangesRejected", strings.Join(errs, "; ")
+	}
+
+	for _, condition := range []endpoint.DNSEndpointCondition{ready, conflict, providerError} {
+		condition.LastTransitionTime = now
+		for _, prev := range previous.Conditions {
+			if prev.Type == condition.Type && prev.Status == condition.Status {
+				condition.LastTransitionTime = prev.LastTransitionTime
+			}
+		}
+		status.Conditions = append(status.Conditions, condition)
+	}
+	return status
+}
+
 // filterByAnnotations filters a list of dnsendpoints by a given annotation selector.
 func (cs *crdSource) filterByAnnotations(dnsendpoints *endpoint.DNSEndpointList) (*endpoint.DNSEndpointList, error) {
 	labelSelector, err := metav1.ParseToLabelSelector(cs.annotationFilter)
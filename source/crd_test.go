@@ -20,19 +20,24 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/rest/fake"
 
@@ -58,6 +63,12 @@ func objBody(codec runtime.Encoder, obj runtime.Object) io.ReadCloser {
 }
 
 func startCRDServerToServeTargets(endpoints []*endpoint.Endpoint, apiVersion, kind, namespace, name string, annotations map[string]string, labels map[string]string, t *testing.T) rest.Interface {
+	watchEvents, _ := io.Pipe()
+	return startCRDServerToWatchTargets(endpoints, apiVersion, kind, namespace, name, annotations, labels, watchEvents, t)
+}
+
+// startCRDServerToWatchTargets serves the targets like startCRDServerToServeTargets and streams the given watch events
+func startCRDServerToWatchTargets(endpoints []*endpoint.Endpoint, apiVersion, kind, namespace, name string, annotations map[string]string, labels map[string]string, watchEvents io.Reader, t *testing.T) rest.Interface {
 	groupVersion, _ := schema.ParseGroupVersion(apiVersion)
 	scheme := runtime.NewScheme()
 	addKnownTypes(scheme, groupVersion)
@@ -91,12 +102,16 @@ func startCRDServerToServeTargets(endpoints []*endpoint.Endpoint, apiVersion, ki
 		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
 			codec := codecFactory.LegacyCodec(groupVersion)
 			switch p, m := req.URL.Path, req.Method; {
+			case req.URL.Query().Get("watch") == "true" && m == http.MethodGet:
+				return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: ioutil.NopCloser(watchEvents)}, nil
 			case p == "/apis/"+apiVersion+"/"+strings.ToLower(kind)+"s" && m == http.MethodGet:
 				fallthrough
 			case p == "/apis/"+apiVersion+"/namespaces/"+namespace+"/"+strings.ToLower(kind)+"s" && m == http.MethodGet:
 				dnsEndpointList.Items = dnsEndpointList.Items[:0]
 				dnsEndpointList.Items = append(dnsEndpointList.Items, *dnsEndpoint)
 				return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: objBody(codec, &dnsEndpointList)}, nil
+			case p == "/apis/"+apiVersion+"/namespaces/"+namespace+"/"+strings.ToLower(kind)+"s/"+name && m == http.MethodGet:
+				return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: objBody(codec, dnsEndpoint)}, nil
 			case strings.HasPrefix(p, "/apis/"+apiVersion+"/namespaces/") && strings.HasSuffix(p, strings.ToLower(kind)+"s") && m == http.MethodGet:
 				return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: objBody(codec, &dnsEndpointList)}, nil
 			case p == "/apis/"+apiVersion+"/namespaces/"+namespace+"/"+strings.ToLower(kind)+"s/"+name+"/status" && m == http.MethodPut:
@@ -104,7 +119,7 @@ func startCRDServerToServeTargets(endpoints []*endpoint.Endpoint, apiVersion, ki
 
 				var body endpoint.DNSEndpoint
 				decoder.Decode(&body)
-				dnsEndpoint.Status.ObservedGeneration = body.Status.ObservedGeneration
+				dnsEndpoint.Status = body.Status
 				return &http.Response{StatusCode: http.StatusOK, Header: defaultHeader(), Body: objBody(codec, dnsEndpoint)}, nil
 			default:
 				return nil, fmt.Errorf("unexpected request: %#v\n%#v", req.URL, req)
@@ -119,6 +134,8 @@ func TestCRDSource(t *testing.T) {
 	suite.Run(t, new(CRDSuite))
 	t.Run("Interface", testCRDSourceImplementsSource)
 	t.Run("Endpoints", testCRDSourceEndpoints)
+	t.Run("ReportResults", testCRDSourceReportResults)
+	t.Run("AddEventHandler", testCRDSourceAddEventHandler)
 }
 
 // testCRDSourceImplementsSource tests that crdSource is a valid Source.
@@ -380,14 +397,15 @@ func testCRDSourceEndpoints(t *testing.T) {
 			scheme := runtime.NewScheme()
 			addKnownTypes(scheme, groupVersion)
 
-			cs, _ := NewCRDSource(restClient, ti.namespace, ti.kind, ti.annotationFilter, ti.labelFilter, scheme)
-
-			receivedEndpoints, err := cs.Endpoints(context.Background())
+			cs, err := NewCRDSource(restClient, ti.namespace, ti.kind, ti.annotationFilter, ti.labelFilter, scheme)
 			if ti.expectError {
 				require.Errorf(t, err, "Received err %v", err)
-			} else {
-				require.NoErrorf(t, err, "Received err %v", err)
+				return
 			}
+			require.NoErrorf(t, err, "Received err %v", err)
+
+			receivedEndpoints, err := cs.Endpoints(context.Background())
+			require.NoErrorf(t, err, "Received err %v", err)
 
 			if len(receivedEndpoints) == 0 && !ti.expectEndpoints {
 				return
@@ -405,6 +423,8 @@ func testCRDSourceEndpoints(t *testing.T) {
 
 func validateCRDResource(t *testing.T, src Source, expectError bool) {
 	cs := src.(*crdSource)
+	// the observed generation is written together with the conditions
+	cs.ReportResults(context.Background(), nil)
 	result, err := cs.List(context.Background(), &metav1.ListOptions{})
 	if expectError {
 		require.Errorf(t, err, "Received err %v", err)
@@ -418,3 +438,195 @@ func validateCRDResource(t *testing.T, src Source, expectError bool) {
 		}
 	}
 }
+
+// testCRDSourceAddEventHandler tests that the event handler is called for changes of the endpoints,
+// but not for updates of the status.
+func testCRDSourceAddEventHandler(t *testing.T) {
+	apiVersion := "test.k8s.io/v1alpha1"
+	endpoints := []*endpoint.Endpoint{
+		{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+	}
+	watchEvents, events := io.Pipe()
+	defer events.Close()
+	restClient := startCRDServerToWatchTargets(endpoints, apiVersion, "DNSEndpoint", "foo", "test", nil, nil, watchEvents, t)
+	groupVersion, err := schema.ParseGroupVersion(apiVersion)
+	require.NoError(t, err)
+	scheme := runtime.NewScheme()
+	addKnownTypes(scheme, groupVersion)
+	codec := serializer.NewCodecFactory(scheme).LegacyCodec(groupVersion)
+
+	cs, err := NewCRDSource(restClient, "foo", "DNSEndpoint", "", "", scheme)
+	require.NoError(t, err)
+
+	handled := make(chan struct{}, 10)
+	cs.AddEventHandler(context.Background(), func() {
+		handled <- struct{}{}
+	})
+	waitHandled := func() {
+		select {
+		case <-handled:
+		case <-time.After(10 * time.Second):
+			t.Fatal("event handler wasn't called")
+		}
+	}
+
+	sendEvent := func(eventType watch.EventType, resourceVersion string, generation int64, status endpoint.DNSEndpointStatus) {
+		dnsEndpoint := &endpoint.DNSEndpoint{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: apiVersion,
+				Kind:       "DNSEndpoint",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "test",
+				Namespace:       "foo",
+				ResourceVersion: resourceVersion,
+				Generation:      generation,
+			},
+			Spec:   endpoint.DNSEndpointSpec{Endpoints: endpoints},
+			Status: status,
+		}
+		_, err := fmt.Fprintf(events, `{"type":%q,"object":%s}`, eventType, runtime.EncodeOrDie(codec, dnsEndpoint))
+		require.NoError(t, err)
+	}
+
+	// the endpoint listed initially is added
+	waitHandled()
+
+	// the status written by a synchronization is ignored, the change of the spec isn't
+	sendEvent(watch.Modified, "2", 1, endpoint.DNSEndpointStatus{ObservedGeneration: 1})
+	sendEvent(watch.Modified, "3", 2, endpoint.DNSEndpointStatus{ObservedGeneration: 1})
+	waitHandled()
+	assert.Empty(t, handled, "event handler was called for the update of the status")
+
+	sendEvent(watch.Deleted, "4", 2, endpoint.DNSEndpointStatus{ObservedGeneration: 1})
+	waitHandled()
+}
+
+func TestEndpointsMayChange(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		old      *endpoint.DNSEndpoint
+		new      *endpoint.DNSEndpoint
+		expected bool
+	}{
+		{
+			title:    "status update",
+			old:      &endpoint.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Generation: 1}},
+			new:      &endpoint.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Generation: 1}, Status: endpoint.DNSEndpointStatus{ObservedGeneration: 1}},
+			expected: false,
+		},
+		{
+			title:    "spec update",
+			old:      &endpoint.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Generation: 1}},
+			new:      &endpoint.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Generation: 2}},
+			expected: true,
+		},
+		{
+			title:    "label update",
+			old:      &endpoint.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Generation: 1}},
+			new:      &endpoint.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Generation: 1, Labels: map[string]string{"test": "that"}}},
+			expected: true,
+		},
+		{
+			title:    "annotation update",
+			old:      &endpoint.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Generation: 1, Annotations: map[string]string{"test": "that"}}},
+			new:      &endpoint.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Generation: 1}},
+			expected: true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.expected, endpointsMayChange(tc.old, tc.new))
+		})
+	}
+}
+
+// testCRDSourceReportResults tests that the results of a synchronization are written to the status.
+func testCRDSourceReportResults(t *testing.T) {
+	apiVersion := "test.k8s.io/v1alpha1"
+	endpoints := []*endpoint.Endpoint{
+		{DNSName: "def.example.org", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: endpoint.RecordTypeA},
+		{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA},
+	}
+	restClient := startCRDServerToServeTargets(endpoints, apiVersion, "DNSEndpoint", "foo", "test", nil, nil, t)
+	groupVersion, err := schema.ParseGroupVersion(apiVersion)
+	require.NoError(t, err)
+	scheme := runtime.NewScheme()
+	addKnownTypes(scheme, groupVersion)
+	cs, err := NewCRDSource(restClient, "foo", "DNSEndpoint", "", "", scheme)
+	require.NoError(t, err)
+	reporter := cs.(ResultReporter)
+
+	received, err := cs.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, received, 2)
+	dnsEndpoint, err := cs.(*crdSource).Get(context.Background(), "foo", "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), dnsEndpoint.Status.ObservedGeneration, "only ReportResults writes the status")
+
+	reporter.ReportResults(context.Background(), []EndpointResult{
+		{Endpoint: received[0], Targets: endpoint.Targets{"9.9.9.9"}, Conflict: `owned by "other"`},
+		{Endpoint: received[1], Targets: endpoint.Targets{"1.2.3.4"}},
+	})
+	dnsEndpoint, err = cs.(*crdSource).Get(context.Background(), "foo", "test")
+	require.NoError(t, err)
+	status := dnsEndpoint.Status
+	assert.Equal(t, int64(1), status.ObservedGeneration)
+	assert.Equal(t, []endpoint.DNSEndpointRecordStatus{
+		{DNSName: "abc.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+		{DNSName: "def.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"9.9.9.9"}},
+	}, status.Endpoints)
+	require.Len(t, status.Conditions, 3)
+	assert.Equal(t, endpoint.DNSEndpointReady, status.Conditions[0].Type)
+	assert.Equal(t, corev1.ConditionFalse, status.Conditions[0].Status)
+	assert.Equal(t, "Conflict", status.Conditions[0].Reason)
+	assert.Equal(t, endpoint.DNSEndpointConflict, status.Conditions[1].Type)
+	assert.Equal(t, corev1.ConditionTrue, status.Conditions[1].Status)
+	assert.Equal(t, `def.example.org: owned by "other"`, status.Conditions[1].Message)
+	assert.Equal(t, endpoint.DNSEndpointProviderError, status.Conditions[2].Type)
+	assert.Equal(t, corev1.ConditionFalse, status.Conditions[2].Status)
+}
+
+func TestNewDNSEndpointStatus(t *testing.T) {
+	abc := &endpoint.Endpoint{DNSName: "abc.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA}
+	def := &endpoint.Endpoint{DNSName: "def.example.org", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: endpoint.RecordTypeA}
+	before := metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	now := metav1.NewTime(before.Add(time.Hour))
+
+	previous := endpoint.DNSEndpointStatus{
+		ObservedGeneration: 2,
+		Conditions: []endpoint.DNSEndpointCondition{
+			{Type: endpoint.DNSEndpointReady, Status: corev1.ConditionFalse, LastTransitionTime: before},
+			{Type: endpoint.DNSEndpointConflict, Status: corev1.ConditionFalse, LastTransitionTime: before},
+			{Type: endpoint.DNSEndpointProviderError, Status: corev1.ConditionFalse, LastTransitionTime: before},
+		},
+	}
+
+	status := newDNSEndpointStatus(previous, []EndpointResult{
+		{Endpoint: abc, Targets: endpoint.Targets{"1.2.3.4"}},
+		{Endpoint: def, Err: errors.New("throttled")},
+	}, now)
+	assert.Equal(t, int64(2), status.ObservedGeneration)
+	assert.Equal(t, "ProviderError", status.Conditions[0].Reason)
+	assert.Equal(t, before, status.Conditions[0].LastTransitionTime)
+	assert.Equal(t, before, status.Conditions[1].LastTransitionTime)
+	assert.Equal(t, corev1.ConditionTrue, status.Conditions[2].Status)
+	assert.Equal(t, "def.example.org: throttled", status.Conditions[2].Message)
+	assert.Equal(t, now, status.Conditions[2].LastTransitionTime)
+
+	status = newDNSEndpointStatus(status, []EndpointResult{
+		{Endpoint: abc, Targets: endpoint.Targets{"1.2.3.4"}},
+		{Endpoint: def},
+	}, now)
+	assert.Equal(t, "Pending", status.Conditions[0].Reason)
+	assert.Equal(t, "Records aren't published yet: def.example.org", status.Conditions[0].Message)
+
+	later := metav1.NewTime(now.Add(time.Hour))
+	status = newDNSEndpointStatus(status, []EndpointResult{
+		{Endpoint: abc, Targets: endpoint.Targets{"1.2.3.4"}},
+		{Endpoint: def, Targets: endpoint.Targets{"5.6.7.8"}},
+	}, later)
+	assert.Equal(t, corev1.ConditionTrue, status.Conditions[0].Status)
+	assert.Equal(t, later, status.Conditions[0].LastTransitionTime)
+	assert.Equal(t, corev1.ConditionFalse, status.Conditions[2].Status)
+	assert.Equal(t, later, status.Conditions[2].LastTransitionTime)
+}
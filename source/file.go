@@ -0,0 +1,272 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// fileWatchInterval is how often the files are checked for changes
+const fileWatchInterval = 5 * time.Second
+
+// fileRecords is the format of YAML and JSON files, which is the format of the endpoints of the CRD source
+type fileRecords struct {
+	Endpoints []fileRecord `json:"endpoints" yaml:"endpoints"`
+}
+
+// fileRecord is an endpoint in a YAML or JSON file
+type fileRecord struct {
+	DNSName          string                 `json:"dnsName" yaml:"dnsName"`
+	Targets          []string               `json:"targets" yaml:"targets"`
+	RecordType       string                 `json:"recordType,omitempty" yaml:"recordType,omitempty"`
+	SetIdentifier    string                 `json:"setIdentifier,omitempty" yaml:"setIdentifier,omitempty"`
+	RecordTTL        int64                  `json:"recordTTL,omitempty" yaml:"recordTTL,omitempty"`
+	ProviderSpecific []fileProviderSpecific `json:"providerSpecific,omitempty" yaml:"providerSpecific,omitempty"`
+}
+
+type fileProviderSpecific struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// fileSource is an implementation of Source that reads the endpoints from local files. Files ending
+// with .yaml, .yml or .json hold YAML or JSON, any other file is a zone file in the format of RFC 1035.
+type fileSource struct {
+	paths []string
+}
+
+// NewFileSource creates a new fileSource reading the given files. The files are read once right away,
+// so that invalid files are reported at startup.
+func NewFileSource(paths []string) (Source, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files given for the file source")
+	}
+	fs := &fileSource{paths: paths}
+	if _, err := fs.Endpoints(context.Background()); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Endpoints reads the endpoints from the files.
+func (fs *fileSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	endpoints := []*endpoint.Endpoint{}
+	for _, path := range fs.paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var fileEndpoints []*endpoint.Endpoint
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			fileEndpoints, err = parseRecordsFile(path, content)
+		default:
+			fileEndpoints, err = parseZoneFile(path, content)
+		}
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, fileEndpoints...)
+	}
+	return endpoints, nil
+}
+
+// AddEventHandler calls the handler whenever one of the files changes.
+func (fs *fileSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for file")
+
+	go fs.watch(ctx, handler, fileWatchInterval)
+}
+
+// watch polls the files since watching them for events misses the updates of mounted ConfigMaps,
+// which replace a symlink to the directory of the files
+func (fs *fileSource) watch(ctx context.Context, handler func(), interval time.Duration) {
+	states := fs.states()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			current := fs.states()
+			if current != states {
+				log.Debugf("Files of the file source changed")
+				states = current
+				handler()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// states describes the modification time and size of every file, or why it can't be read
+func (fs *fileSource) states() string {
+	var states strings.Builder
+	for _, path := range fs.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&states, "%s: %v\n", path, err)
+			continue
+		}
+		fmt.Fprintf(&states, "%s: %d %d\n", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return states.String()
+}
+
+// parseRecordsFile parses the endpoints of a YAML or JSON file
+func parseRecordsFile(path string, content []byte) ([]*endpoint.Endpoint, error) {
+	records := fileRecords{}
+	// JSON is parsed as YAML, too, so that errors tell the line
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&records); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	lines := recordLines(content)
+
+	endpoints := []*endpoint.Endpoint{}
+	for i, record := range records.Endpoints {
+		location := path
+		if i < len(lines) {
+			location = fmt.Sprintf("%s:%d", path, lines[i])
+		}
+		if record.DNSName == "" {
+			return nil, fmt.Errorf("%s: dnsName is required", location)
+		}
+		if len(record.Targets) == 0 {
+			return nil, fmt.Errorf("%s (%s): targets are required", location, record.DNSName)
+		}
+		if record.RecordTTL < 0 {
+			return nil, fmt.Errorf("%s (%s): recordTTL must not be negative", location, record.DNSName)
+		}
+
+		providerSpecific := endpoint.ProviderSpecific{}
+		for _, property := range record.ProviderSpecific {
+			providerSpecific = append(providerSpecific, endpoint.ProviderSpecificProperty{Name: property.Name, Value: property.Value})
+		}
+
+		// without a record type, it's derived from the targets like for the target annotation
+		if record.RecordType == "" {
+			endpoints = append(endpoints, endpointsForHostname(record.DNSName, record.Targets, endpoint.TTL(record.RecordTTL), providerSpecific, record.SetIdentifier)...)
+			continue
+		}
+		if !supportedFileRecordType(record.RecordType) {
+			return nil, fmt.Errorf("%s (%s): record type %s isn't supported", location, record.DNSName, record.RecordType)
+		}
+		ep := endpoint.NewEndpointWithTTL(record.DNSName, record.RecordType, endpoint.TTL(record.RecordTTL), record.Targets...).WithSetIdentifier(record.SetIdentifier)
+		ep.ProviderSpecific = providerSpecific
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, nil
+}
+
+// recordLines returns the line of every endpoint of a YAML or JSON file, so that errors can point at it
+func recordLines(content []byte) []int {
+	lines := []int{}
+	document := yaml.Node{}
+	if err := yaml.Unmarshal(content, &document); err != nil || len(document.Content) == 0 {
+		return lines
+	}
+	root := document.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "endpoints" {
+			continue
+		}
+		records := root.Content[i+1]
+		if records.Kind == yaml.AliasNode {
+			records = records.Alias
+		}
+		for _, record := range records.Content {
+			lines = append(lines, record.Line)
+		}
+	}
+	return lines
+}
+
+// parseZoneFile parses the endpoints of a zone file. The records of a name and type become one endpoint.
+func parseZoneFile(path string, content []byte) ([]*endpoint.Endpoint, error) {
+	endpoints := []*endpoint.Endpoint{}
+	byNameAndType := map[string]*endpoint.Endpoint{}
+
+	zp := dns.NewZoneParser(bytes.NewReader(content), "", path)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		header := rr.Header()
+		name := strings.TrimSuffix(header.Name, ".")
+		recordType := dns.TypeToString[header.Rrtype]
+
+		var target string
+		switch record := rr.(type) {
+		case *dns.A:
+			target = record.A.String()
+		case *dns.AAAA:
+			target = record.AAAA.String()
+		case *dns.CNAME:
+			target = strings.TrimSuffix(record.Target, ".")
+		case *dns.NS:
+			target = strings.TrimSuffix(record.Ns, ".")
+		case *dns.SRV:
+			target = fmt.Sprintf("%d %d %d %s", record.Priority, record.Weight, record.Port, strings.TrimSuffix(record.Target, "."))
+		case *dns.TXT:
+			target = fmt.Sprintf("\"%s\"", strings.Join(record.Txt, ""))
+		default:
+			return nil, fmt.Errorf("%s: record type %s of %s isn't supported", path, recordType, name)
+		}
+
+		key := name + "/" + recordType
+		ttl := endpoint.TTL(header.Ttl)
+		if ep, ok := byNameAndType[key]; ok {
+			ep.Targets = append(ep.Targets, target)
+			// all records of a name and type share the lowest TTL
+			if ttl < ep.RecordTTL {
+				ep.RecordTTL = ttl
+			}
+			continue
+		}
+		ep := endpoint.NewEndpointWithTTL(name, recordType, ttl, target)
+		byNameAndType[key] = ep
+		endpoints = append(endpoints, ep)
+	}
+	// the error tells the file and line
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// supportedFileRecordType returns whether records of the type can be read from files
+func supportedFileRecordType(recordType string) bool {
+	switch recordType {
+	case endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME, endpoint.RecordTypeTXT, endpoint.RecordTypeSRV, endpoint.RecordTypeNS:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestFileSourceEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		title         string
+		name          string
+		content       string
+		expected      []*endpoint.Endpoint
+		expectedError string
+	}{
+		{
+			title: "yaml",
+			name:  "records.yaml",
+			content: `
+endpoints:
+- dnsName: vm1.example.org
+  recordType: A
+  targets: [192.0.2.10, 192.0.2.11]
+  recordTTL: 300
+- dnsName: partner.example.org.
+  recordType: CNAME
+  targets: [partner.example.com.]
+  setIdentifier: eu
+  providerSpecific:
+  - name: aws/weight
+    value: "10"
+- dnsName: vm2.example.org
+  targets: [192.0.2.12, "2001:db8::12"]
+`,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "vm1.example.org", Targets: endpoint.Targets{"192.0.2.10", "192.0.2.11"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+				{DNSName: "partner.example.org", Targets: endpoint.Targets{"partner.example.com"}, RecordType: endpoint.RecordTypeCNAME, SetIdentifier: "eu", ProviderSpecific: endpoint.ProviderSpecific{{Name: "aws/weight", Value: "10"}}},
+				{DNSName: "vm2.example.org", Targets: endpoint.Targets{"192.0.2.12"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "vm2.example.org", Targets: endpoint.Targets{"2001:db8::12"}, RecordType: endpoint.RecordTypeAAAA},
+			},
+		},
+		{
+			title: "json",
+			name:  "records.json",
+			content: `{
+	"endpoints": [
+		{"dnsName": "vm1.example.org", "recordType": "A", "targets": ["192.0.2.10"]}
+	]
+}`,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "vm1.example.org", Targets: endpoint.Targets{"192.0.2.10"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "zone file",
+			name:  "records.zone",
+			content: `$ORIGIN example.org.
+$TTL 300
+vm1              IN A     192.0.2.10
+vm1          60  IN A     192.0.2.11
+vm1              IN AAAA  2001:db8::10
+partner          IN CNAME partner.example.com.
+_sip._tcp        IN SRV   10 20 5060 vm1
+info             IN TXT   "some" "text"
+`,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "vm1.example.org", Targets: endpoint.Targets{"192.0.2.10", "192.0.2.11"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60},
+				{DNSName: "vm1.example.org", Targets: endpoint.Targets{"2001:db8::10"}, RecordType: endpoint.RecordTypeAAAA, RecordTTL: 300},
+				{DNSName: "partner.example.org", Targets: endpoint.Targets{"partner.example.com"}, RecordType: endpoint.RecordTypeCNAME, RecordTTL: 300},
+				{DNSName: "_sip._tcp.example.org", Targets: endpoint.Targets{"10 20 5060 vm1.example.org"}, RecordType: endpoint.RecordTypeSRV, RecordTTL: 300},
+				{DNSName: "info.example.org", Targets: endpoint.Targets{`"sometext"`}, RecordType: endpoint.RecordTypeTXT, RecordTTL: 300},
+			},
+		},
+		{
+			title: "yaml syntax error",
+			name:  "records.yaml",
+			content: `endpoints:
+- dnsName: vm1.example.org
+  targets: a: b
+`,
+			expectedError: "records.yaml: yaml: line 3: mapping values are not allowed in this context",
+		},
+		{
+			title: "yaml unknown field",
+			name:  "records.yml",
+			content: `endpoints:
+- dnsName: vm1.example.org
+  target: 192.0.2.10
+`,
+			expectedError: "line 3: field target not found",
+		},
+		{
+			title: "missing targets",
+			name:  "records.yaml",
+			content: `endpoints:
+- dnsName: vm1.example.org
+  targets: [192.0.2.10]
+- dnsName: vm2.example.org
+`,
+			expectedError: "records.yaml:4 (vm2.example.org): targets are required",
+		},
+		{
+			title: "missing dnsName",
+			name:  "records.json",
+			content: `{
+	"endpoints": [
+		{"dnsName": "vm1.example.org", "targets": ["192.0.2.10"]},
+		{"targets": ["192.0.2.11"]}
+	]
+}`,
+			expectedError: "records.json:4: dnsName is required",
+		},
+		{
+			title: "unsupported record type",
+			name:  "records.yaml",
+			content: `endpoints:
+- dnsName: example.org
+  recordType: MX
+  targets: [10 mail.example.org]
+`,
+			expectedError: "records.yaml:2 (example.org): record type MX isn't supported",
+		},
+		{
+			title: "zone file syntax error",
+			name:  "records.zone",
+			content: `$ORIGIN example.org.
+vm1 IN A 192.0.2.10
+vm2 IN A 192.0.2
+`,
+			expectedError: "records.zone: dns: bad A A: \"192.0.2\" at line: 3",
+		},
+		{
+			title: "zone file with unsupported record type",
+			name:  "records.zone",
+			content: `$ORIGIN example.org.
+@ IN MX 10 mail
+`,
+			expectedError: "records.zone: record type MX of example.org isn't supported",
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), tc.name, tc.content)
+
+			fs, err := NewFileSource([]string{path})
+			if tc.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			endpoints, err := fs.Endpoints(context.Background())
+			require.NoError(t, err)
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
+func TestFileSourceMultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := writeFile(t, dir, "records.yaml", "endpoints:\n- dnsName: vm1.example.org\n  targets: [192.0.2.10]\n")
+	zonePath := writeFile(t, dir, "records.zone", "vm2.example.org. 300 IN A 192.0.2.11\n")
+
+	fs, err := NewFileSource([]string{yamlPath, zonePath})
+	require.NoError(t, err)
+
+	endpoints, err := fs.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "vm1.example.org", Targets: endpoint.Targets{"192.0.2.10"}, RecordType: endpoint.RecordTypeA},
+		{DNSName: "vm2.example.org", Targets: endpoint.Targets{"192.0.2.11"}, RecordType: endpoint.RecordTypeA, RecordTTL: 300},
+	})
+
+	_, err = NewFileSource(nil)
+	assert.Error(t, err)
+	_, err = NewFileSource([]string{filepath.Join(dir, "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestFileSourceWatch(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "records.yaml", "endpoints: []\n")
+	fs, err := NewFileSource([]string{path})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan struct{}, 10)
+	go fs.(*fileSource).watch(ctx, func() {
+		handled <- struct{}{}
+	}, 10*time.Millisecond)
+
+	// nothing changed
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, handled)
+
+	writeFile(t, filepath.Dir(path), "records.yaml", "endpoints:\n- dnsName: vm1.example.org\n  targets: [192.0.2.10]\n")
+	select {
+	case <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("event handler wasn't called")
+	}
+}
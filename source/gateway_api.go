@@ -0,0 +1,497 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+// The resources of the Kubernetes Gateway API. Gateways and HTTPRoutes are served by the beta version,
+// TLSRoutes and TCPRoutes only by the experimental alpha version.
+var (
+	gatewayAPIGatewayGVR   = schema.GroupVersionResource{Group: gatewayAPIGroup, Version: "v1beta1", Resource: "gateways"}
+	gatewayAPIHTTPRouteGVR = schema.GroupVersionResource{Group: gatewayAPIGroup, Version: "v1beta1", Resource: "httproutes"}
+	gatewayAPITLSRouteGVR  = schema.GroupVersionResource{Group: gatewayAPIGroup, Version: "v1alpha2", Resource: "tlsroutes"}
+	gatewayAPITCPRouteGVR  = schema.GroupVersionResource{Group: gatewayAPIGroup, Version: "v1alpha2", Resource: "tcproutes"}
+)
+
+// gatewayRoute holds the fields shared by the route kinds of the Gateway API. There is no client
+// for the Gateway API in the dependencies, so the objects are read from their unstructured form.
+type gatewayRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   gatewayRouteSpec   `json:"spec,omitempty"`
+	Status gatewayRouteStatus `json:"status,omitempty"`
+}
+
+type gatewayRouteSpec struct {
+	ParentRefs []gatewayParentRef `json:"parentRefs,omitempty"`
+	// Hostnames isn't part of TCPRoutes
+	Hostnames []string `json:"hostnames,omitempty"`
+}
+
+type gatewayParentRef struct {
+	Group       string `json:"group,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+type gatewayRouteStatus struct {
+	Parents []gatewayRouteParentStatus `json:"parents,omitempty"`
+}
+
+type gatewayRouteParentStatus struct {
+	ParentRef  gatewayParentRef   `json:"parentRef"`
+	Conditions []gatewayCondition `json:"conditions,omitempty"`
+}
+
+type gatewayCondition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// gateway holds the fields of Gateway API Gateways needed to publish their routes
+type gateway struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   gatewaySpec   `json:"spec,omitempty"`
+	Status gatewayStatus `json:"status,omitempty"`
+}
+
+type gatewaySpec struct {
+	Listeners []gatewayListener `json:"listeners,omitempty"`
+}
+
+type gatewayListener struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+type gatewayStatus struct {
+	Addresses []gatewayAddress `json:"addresses,omitempty"`
+}
+
+type gatewayAddress struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value"`
+}
+
+// gatewayRouteSource is an implementation of Source for the routes of the Kubernetes Gateway API.
+// The hostnames are the intersection of spec.hostnames of the routes with the hostnames of the
+// listeners they are attached to, and the targets from the addresses of the Gateways that accepted the routes.
+type gatewayRouteSource struct {
+	kind                     string
+	namespace                string
+	annotationFilter         string
+	labelSelector            labels.Selector
+	fqdnTemplate             *template.Template
+	combineFQDNAnnotation    bool
+	ignoreHostnameAnnotation bool
+	routeInformer            informers.GenericInformer
+	gatewayInformer          informers.GenericInformer
+}
+
+// NewGatewayHTTPRouteSource creates a new gatewayRouteSource for HTTPRoutes with the given config.
+func NewGatewayHTTPRouteSource(dynamicKubeClient dynamic.Interface, namespace, annotationFilter, labelFilter, fqdnTemplate string, combineFqdnAnnotation, ignoreHostnameAnnotation bool) (Source, error) {
+	return newGatewayRouteSource("httproute", gatewayAPIHTTPRouteGVR, dynamicKubeClient, namespace, annotationFilter, labelFilter, fqdnTemplate, combineFqdnAnnotation, ignoreHostnameAnnotation)
+}
+
+// NewGatewayTLSRouteSource creates a new gatewayRouteSource for TLSRoutes with the given config.
+func NewGatewayTLSRouteSource(dynamicKubeClient dynamic.Interface, namespace, annotationFilter, labelFilter, fqdnTemplate string, combineFqdnAnnotation, ignoreHostnameAnnotation bool) (Source, error) {
+	return newGatewayRouteSource("tlsroute", gatewayAPITLSRouteGVR, dynamicKubeClient, namespace, annotationFilter, labelFilter, fqdnTemplate, combineFqdnAnnotation, ignoreHostnameAnnotation)
+}
+
+// NewGatewayTCPRouteSource creates a new gatewayRouteSource for TCPRoutes with the given config.
+func NewGatewayTCPRouteSource(dynamicKubeClient dynamic.Interface, namespace, annotationFilter, labelFilter, fqdnTemplate string, combineFqdnAnnotation, ignoreHostnameAnnotation bool) (Source, error) {
+	return newGatewayRouteSource("tcproute", gatewayAPITCPRouteGVR, dynamicKubeClient, namespace, annotationFilter, labelFilter, fqdnTemplate, combineFqdnAnnotation, ignoreHostnameAnnotation)
+}
+
+func newGatewayRouteSource(kind string, routeGVR schema.GroupVersionResource, dynamicKubeClient dynamic.Interface, namespace, annotationFilter, labelFilter, fqdnTemplate string, combineFqdnAnnotation, ignoreHostnameAnnotation bool) (Source, error) {
+	tmpl, err := parseTemplate(fqdnTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := labels.Parse(labelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use shared informers to listen for add/update/delete of the routes in the specified namespace
+	// and of the Gateways in all namespaces, since routes may be attached to Gateways of other namespaces.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	routeInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, nil)
+	routeInformer := routeInformerFactory.ForResource(routeGVR)
+	gatewayInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, metav1.NamespaceAll, nil)
+	gatewayInformer := gatewayInformerFactory.ForResource(gatewayAPIGatewayGVR)
+
+	// Add default resource event handlers to properly initialize informers.
+	for _, informer := range []informers.GenericInformer{routeInformer, gatewayInformer} {
+		informer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+				},
+			},
+		)
+	}
+
+	// TODO informer is not explicitly stopped since controller is not passing in its channel.
+	routeInformerFactory.Start(wait.NeverStop)
+	gatewayInformerFactory.Start(wait.NeverStop)
+
+	// wait for the local cache to be populated.
+	err = poll(time.Second, 60*time.Second, func() (bool, error) {
+		return routeInformer.Informer().HasSynced() && gatewayInformer.Informer().HasSynced(), nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sync cache")
+	}
+
+	return &gatewayRouteSource{
+		kind:                     kind,
+		namespace:                namespace,
+		annotationFilter:         annotationFilter,
+		labelSelector:            labelSelector,
+		fqdnTemplate:             tmpl,
+		combineFQDNAnnotation:    combineFqdnAnnotation,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		routeInformer:            routeInformer,
+		gatewayInformer:          gatewayInformer,
+	}, nil
+}
+
+// Endpoints returns endpoint objects for each host-target combination that should be processed.
+// Retrieves all routes of the source's kind in the source's namespace(s).
+func (sc *gatewayRouteSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	objs, err := sc.routeInformer.Lister().ByNamespace(sc.namespace).List(sc.labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []*gatewayRoute
+	for _, obj := range objs {
+		route := &gatewayRoute{}
+		if err := fromUnstructured(obj, route); err != nil {
+			return nil, errors.Wrapf(err, "failed to convert to %s", sc.kind)
+		}
+		routes = append(routes, route)
+	}
+
+	routes, err = sc.filterByAnnotations(routes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to filter %ss", sc.kind)
+	}
+
+	endpoints := []*endpoint.Endpoint{}
+
+	for _, route := range routes {
+		// Check controller annotation to see if we are responsible.
+		controller, ok := route.Annotations[controllerAnnotationKey]
+		if ok && controller != controllerAnnotationValue {
+			log.Debugf("Skipping %s %s/%s because controller value does not match, found: %s, required: %s",
+				sc.kind, route.Namespace, route.Name, controller, controllerAnnotationValue)
+			continue
+		}
+
+		routeEndpoints, err := sc.endpointsFromRoute(route)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(routeEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from %s %s/%s", sc.kind, route.Namespace, route.Name)
+			continue
+		}
+
+		log.Debugf("Endpoints generated from %s: %s/%s: %v", sc.kind, route.Namespace, route.Name, routeEndpoints)
+		sc.setResourceLabel(route, routeEndpoints)
+		endpoints = append(endpoints, routeEndpoints...)
+	}
+
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+
+	return endpoints, nil
+}
+
+// endpointsFromRoute extracts the endpoints from a route
+func (sc *gatewayRouteSource) endpointsFromRoute(route *gatewayRoute) ([]*endpoint.Endpoint, error) {
+	gateways, listeners, err := sc.acceptingGateways(route)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := getTargetsFromTargetAnnotation(route.Annotations)
+	if len(targets) == 0 {
+		for _, gw := range gateways {
+			for _, address := range gw.Status.Addresses {
+				if address.Value != "" && !containsTarget(targets, address.Value) {
+					targets = append(targets, address.Value)
+				}
+			}
+		}
+	}
+
+	hostnames := routeHostnames(route.Spec.Hostnames, listeners)
+
+	// Skip endpoints if we do not want entries from annotations
+	if !sc.ignoreHostnameAnnotation {
+		hostnames = append(hostnames, getHostnamesFromAnnotations(route.Annotations)...)
+	}
+
+	// apply template if hostnames are missing on the route
+	if (sc.combineFQDNAnnotation || len(hostnames) == 0) && sc.fqdnTemplate != nil {
+		var buf bytes.Buffer
+		if err := sc.fqdnTemplate.Execute(&buf, route); err != nil {
+			return nil, errors.Wrapf(err, "failed to apply template on %s %s/%s", sc.kind, route.Namespace, route.Name)
+		}
+		templateHostnames := strings.Split(strings.Replace(buf.String(), " ", "", -1), ",")
+		if sc.combineFQDNAnnotation {
+			hostnames = append(hostnames, templateHostnames...)
+		} else {
+			hostnames = templateHostnames
+		}
+	}
+
+	if len(targets) == 0 {
+		log.Debugf("No targets could be found for %s %s/%s, it isn't accepted by a Gateway with addresses", sc.kind, route.Namespace, route.Name)
+		return nil, nil
+	}
+
+	ttl, err := getTTLFromAnnotations(route.Annotations)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(route.Annotations)
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		hostname = strings.TrimSuffix(hostname, ".")
+		if hostname == "" {
+			continue
+		}
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier)...)
+	}
+	return endpoints, nil
+}
+
+// acceptingGateways returns the parent Gateways which accepted the route, and the listeners of
+// them the route is attached to
+func (sc *gatewayRouteSource) acceptingGateways(route *gatewayRoute) ([]*gateway, []gatewayListener, error) {
+	var (
+		gateways  []*gateway
+		listeners []gatewayListener
+	)
+	for _, ref := range route.Spec.ParentRefs {
+		if (ref.Group != "" && ref.Group != gatewayAPIGroup) || (ref.Kind != "" && ref.Kind != "Gateway") {
+			continue
+		}
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = route.Namespace
+		}
+		if !route.accepted(namespace, ref) {
+			log.Debugf("Skipping Gateway %s/%s of %s %s/%s because it didn't accept the route", namespace, ref.Name, sc.kind, route.Namespace, route.Name)
+			continue
+		}
+
+		obj, err := sc.gatewayInformer.Lister().ByNamespace(namespace).Get(ref.Name)
+		if err != nil {
+			log.Debugf("Skipping Gateway %s/%s of %s %s/%s: %v", namespace, ref.Name, sc.kind, route.Namespace, route.Name, err)
+			continue
+		}
+		gw := &gateway{}
+		if err := fromUnstructured(obj, gw); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to convert to Gateway")
+		}
+		gateways = append(gateways, gw)
+
+		for _, listener := range gw.Spec.Listeners {
+			if ref.SectionName == "" || ref.SectionName == listener.Name {
+				listeners = append(listeners, listener)
+			}
+		}
+	}
+	return gateways, listeners, nil
+}
+
+// routeHostnames returns the hostnames of a route which match the listeners it is attached to.
+// Routes without hostnames get the hostnames of the listeners, and listeners without a hostname
+// match every hostname of the route.
+func routeHostnames(hostnames []string, listeners []gatewayListener) []string {
+	var result []string
+	for _, listener := range listeners {
+		if len(hostnames) == 0 {
+			if listener.Hostname != "" && !containsTarget(result, listener.Hostname) {
+				result = append(result, listener.Hostname)
+			}
+			continue
+		}
+		for _, hostname := range hostnames {
+			if match, ok := intersectHostname(hostname, listener.Hostname); ok && !containsTarget(result, match) {
+				result = append(result, match)
+			}
+		}
+	}
+	return result
+}
+
+// intersectHostname returns the more specific one of the hostnames of a route and a listener, if they
+// match each other. A wildcard matches all subdomains of the domain following it, as specified by the
+// Gateway API.
+func intersectHostname(route, listener string) (string, bool) {
+	switch {
+	case listener == "" || route == listener:
+		return route, true
+	case wildcardMatches(listener, route):
+		return route, true
+	case wildcardMatches(route, listener):
+		return listener, true
+	default:
+		return "", false
+	}
+}
+
+// wildcardMatches returns whether the wildcard hostname matches the hostname, which can be a wildcard
+// of a subdomain itself.
+func wildcardMatches(wildcard, hostname string) bool {
+	if !strings.HasPrefix(wildcard, "*.") {
+		return false
+	}
+	hostname = strings.TrimPrefix(hostname, "*.")
+	suffix := wildcard[1:]
+	return len(hostname) > len(suffix) && strings.HasSuffix(hostname, suffix)
+}
+
+// accepted returns whether the parent Gateway of the namespace accepted the route, according to the status of the route
+func (route *gatewayRoute) accepted(namespace string, ref gatewayParentRef) bool {
+	for _, parent := range route.Status.Parents {
+		parentNamespace := parent.ParentRef.Namespace
+		if parentNamespace == "" {
+			parentNamespace = route.Namespace
+		}
+		if parentNamespace != namespace || parent.ParentRef.Name != ref.Name || parent.ParentRef.SectionName != ref.SectionName {
+			continue
+		}
+		for _, condition := range parent.Conditions {
+			if condition.Type == "Accepted" && condition.Status == "True" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterByAnnotations filters a list of routes by a given annotation selector.
+func (sc *gatewayRouteSource) filterByAnnotations(routes []*gatewayRoute) ([]*gatewayRoute, error) {
+	selector, err := getLabelSelector(sc.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty filter returns original list
+	if selector.Empty() {
+		return routes, nil
+	}
+
+	filteredList := []*gatewayRoute{}
+	for _, route := range routes {
+		// include route if its annotations match the selector
+		if matchLabelSelector(selector, route.Annotations) {
+			filteredList = append(filteredList, route)
+		}
+	}
+
+	return filteredList, nil
+}
+
+func (sc *gatewayRouteSource) setResourceLabel(route *gatewayRoute, endpoints []*endpoint.Endpoint) {
+	for _, ep := range endpoints {
+		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("%s/%s/%s", sc.kind, route.Namespace, route.Name)
+		setConflictResolutionLabels(ep, route.ObjectMeta)
+	}
+}
+
+// AddEventHandler calls the handler on changes of the routes and of the Gateways, whose addresses are the targets.
+func (sc *gatewayRouteSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debugf("Adding event handler for %s", sc.kind)
+
+	// Right now there is no way to remove event handler from informer, see:
+	// https://github.com/kubernetes/kubernetes/issues/79610
+	for _, informer := range []informers.GenericInformer{sc.routeInformer, sc.gatewayInformer} {
+		informer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					handler()
+				},
+				UpdateFunc: func(old interface{}, new interface{}) {
+					handler()
+				},
+				DeleteFunc: func(obj interface{}) {
+					handler()
+				},
+			},
+		)
+	}
+}
+
+// fromUnstructured converts an object of a dynamic informer into one of the types of this file
+func fromUnstructured(obj runtime.Object, into interface{}) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return errors.New("could not convert")
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), into)
+}
+
+func containsTarget(targets endpoint.Targets, target string) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// This is a compile-time validation that gatewayRouteSource is a Source.
+var _ Source = &gatewayRouteSource{}
+
+func newTestGateway(namespace, name string, listeners []gatewayListener, addresses ...string) *gateway {
+	gw := &gateway{
+		TypeMeta:   metav1.TypeMeta{APIVersion: gatewayAPIGatewayGVR.GroupVersion().String(), Kind: "Gateway"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       gatewaySpec{Listeners: listeners},
+	}
+	for _, address := range addresses {
+		gw.Status.Addresses = append(gw.Status.Addresses, gatewayAddress{Value: address})
+	}
+	return gw
+}
+
+// newTestRoute creates a route accepted by all of its parents
+func newTestRoute(kind, namespace, name string, hostnames []string, annotations map[string]string, parents ...gatewayParentRef) *gatewayRoute {
+	route := &gatewayRoute{
+		TypeMeta:   metav1.TypeMeta{Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+		Spec:       gatewayRouteSpec{ParentRefs: parents, Hostnames: hostnames},
+	}
+	for _, parent := range parents {
+		route.Status.Parents = append(route.Status.Parents, gatewayRouteParentStatus{
+			ParentRef:  parent,
+			Conditions: []gatewayCondition{{Type: "Accepted", Status: "True"}},
+		})
+	}
+	return route
+}
+
+func createUnstructured(t *testing.T, client *fakeDynamic.FakeDynamicClient, gvr schema.GroupVersionResource, obj interface{}) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	u := &unstructured.Unstructured{Object: content}
+	_, err = client.Resource(gvr).Namespace(u.GetNamespace()).Create(context.Background(), u, metav1.CreateOptions{})
+	require.NoError(t, err)
+}
+
+func TestGatewayHTTPRouteSourceEndpoints(t *testing.T) {
+	listeners := []gatewayListener{{Name: "http"}, {Name: "wildcard", Hostname: "*.example.org"}, {Name: "foo", Hostname: "foo.example.org"}}
+	gateways := []*gateway{
+		newTestGateway("default", "internal", listeners, "10.0.0.1", "10.0.0.2"),
+		newTestGateway("infra", "external", []gatewayListener{{Name: "https"}}, "lb.example.com"),
+		newTestGateway("default", "pending", listeners),
+	}
+
+	for _, ti := range []struct {
+		title                    string
+		route                    *gatewayRoute
+		annotationFilter         string
+		labelFilter              string
+		fqdnTemplate             string
+		combineFQDNAnnotation    bool
+		ignoreHostnameAnnotation bool
+		expected                 []*endpoint.Endpoint
+	}{
+		{
+			title: "hostnames of the route",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org", "bar.example.org"}, nil, gatewayParentRef{Name: "internal"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "httproute/default/foo"}},
+				{DNSName: "bar.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "httproute/default/foo"}},
+			},
+		},
+		{
+			title: "gateway of another namespace with hostname address",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Namespace: "infra", Name: "external"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "multiple gateways",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Name: "internal"}, gatewayParentRef{Namespace: "infra", Name: "external"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME},
+			},
+		},
+		{
+			title: "hostname of the listener",
+			route: newTestRoute("HTTPRoute", "default", "foo", nil, nil, gatewayParentRef{Name: "internal", SectionName: "wildcard"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "*.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "hostnames of the route matching the listener",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org", "bar.example.com", "example.org"}, nil, gatewayParentRef{Name: "internal", SectionName: "wildcard"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "wildcard of the route narrowed to the listener",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"*.example.org"}, nil, gatewayParentRef{Name: "internal", SectionName: "foo"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:    "hostnames of the route not matching the listener",
+			route:    newTestRoute("HTTPRoute", "default", "foo", []string{"bar.example.org"}, nil, gatewayParentRef{Name: "internal", SectionName: "foo"}),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title:    "listener without hostname",
+			route:    newTestRoute("HTTPRoute", "default", "foo", nil, nil, gatewayParentRef{Name: "internal", SectionName: "http"}),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "not accepted by the gateway",
+			route: func() *gatewayRoute {
+				route := newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Name: "internal"})
+				route.Status.Parents[0].Conditions[0].Status = "False"
+				return route
+			}(),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "accepted by another listener",
+			route: func() *gatewayRoute {
+				route := newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Name: "internal", SectionName: "http"})
+				route.Status.Parents[0].ParentRef.SectionName = "wildcard"
+				return route
+			}(),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title:    "gateway without addresses",
+			route:    newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Name: "pending"}),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title:    "missing gateway",
+			route:    newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Name: "missing"}),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "annotations",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, map[string]string{
+				hostnameAnnotationKey: "bar.example.org",
+				targetAnnotationKey:   "1.2.3.4",
+				ttlAnnotationKey:      "60",
+			}, gatewayParentRef{Name: "internal"}),
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60},
+				{DNSName: "bar.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60},
+			},
+		},
+		{
+			title: "ignored hostname annotation",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, map[string]string{
+				hostnameAnnotationKey: "bar.example.org",
+			}, gatewayParentRef{Name: "internal"}),
+			ignoreHostnameAnnotation: true,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title: "other controller",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, map[string]string{
+				controllerAnnotationKey: "some-other-tool",
+			}, gatewayParentRef{Name: "internal"}),
+			expected: []*endpoint.Endpoint{},
+		},
+		{
+			title: "annotation filter",
+			route: newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, map[string]string{
+				"kubernetes.io/ingress.class": "internal",
+			}, gatewayParentRef{Name: "internal"}),
+			annotationFilter: "kubernetes.io/ingress.class=external",
+			expected:         []*endpoint.Endpoint{},
+		},
+		{
+			title:       "label filter",
+			route:       newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Name: "internal"}),
+			labelFilter: "dns=public",
+			expected:    []*endpoint.Endpoint{},
+		},
+		{
+			title:        "template without hostnames",
+			route:        newTestRoute("HTTPRoute", "default", "foo", nil, nil, gatewayParentRef{Name: "internal", SectionName: "http"}),
+			fqdnTemplate: "{{.Name}}.{{.Namespace}}.example.org",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.default.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:        "template ignored with hostnames",
+			route:        newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Name: "internal"}),
+			fqdnTemplate: "{{.Name}}.{{.Namespace}}.example.org",
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:                 "template combined with hostnames",
+			route:                 newTestRoute("HTTPRoute", "default", "foo", []string{"foo.example.org"}, nil, gatewayParentRef{Name: "internal"}),
+			fqdnTemplate:          "{{.Name}}.{{.Namespace}}.example.org",
+			combineFQDNAnnotation: true,
+			expected: []*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "foo.default.example.org", Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+	} {
+		t.Run(ti.title, func(t *testing.T) {
+			client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme())
+			for _, gw := range gateways {
+				createUnstructured(t, client, gatewayAPIGatewayGVR, gw)
+			}
+			ti.route.APIVersion = gatewayAPIHTTPRouteGVR.GroupVersion().String()
+			createUnstructured(t, client, gatewayAPIHTTPRouteGVR, ti.route)
+
+			src, err := NewGatewayHTTPRouteSource(client, "", ti.annotationFilter, ti.labelFilter, ti.fqdnTemplate, ti.combineFQDNAnnotation, ti.ignoreHostnameAnnotation)
+			require.NoError(t, err)
+
+			endpoints, err := src.Endpoints(context.Background())
+			require.NoError(t, err)
+			validateEndpoints(t, endpoints, ti.expected)
+		})
+	}
+}
+
+func TestGatewayTCPRouteSourceEndpoints(t *testing.T) {
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	createUnstructured(t, client, gatewayAPIGatewayGVR, newTestGateway("default", "internal", []gatewayListener{{Name: "tcp"}}, "10.0.0.1"))
+	route := newTestRoute("TCPRoute", "default", "db", nil, map[string]string{hostnameAnnotationKey: "db.example.org"}, gatewayParentRef{Name: "internal"})
+	route.APIVersion = gatewayAPITCPRouteGVR.GroupVersion().String()
+	createUnstructured(t, client, gatewayAPITCPRouteGVR, route)
+	// routes of other kinds aren't published
+	other := newTestRoute("HTTPRoute", "default", "web", []string{"web.example.org"}, nil, gatewayParentRef{Name: "internal"})
+	other.APIVersion = gatewayAPIHTTPRouteGVR.GroupVersion().String()
+	createUnstructured(t, client, gatewayAPIHTTPRouteGVR, other)
+
+	src, err := NewGatewayTCPRouteSource(client, "default", "", "", "", false, false)
+	require.NoError(t, err)
+
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "db.example.org", Targets: endpoint.Targets{"10.0.0.1"}, RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "tcproute/default/db"}},
+	})
+}
+
+func TestGatewayTLSRouteSourceEndpoints(t *testing.T) {
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	createUnstructured(t, client, gatewayAPIGatewayGVR, newTestGateway("default", "internal", []gatewayListener{{Name: "tls", Hostname: "*.internal.example.org"}}, "10.0.0.1"))
+	route := newTestRoute("TLSRoute", "default", "api", []string{"api.internal.example.org"}, nil, gatewayParentRef{Name: "internal"})
+	route.APIVersion = gatewayAPITLSRouteGVR.GroupVersion().String()
+	createUnstructured(t, client, gatewayAPITLSRouteGVR, route)
+	// routes without hostnames get the hostname of their listener
+	listenerRoute := newTestRoute("TLSRoute", "default", "auth", nil, nil, gatewayParentRef{Name: "internal", SectionName: "tls"})
+	listenerRoute.APIVersion = gatewayAPITLSRouteGVR.GroupVersion().String()
+	createUnstructured(t, client, gatewayAPITLSRouteGVR, listenerRoute)
+	// routes of other kinds aren't published
+	other := newTestRoute("TCPRoute", "default", "db", nil, map[string]string{hostnameAnnotationKey: "db.example.org"}, gatewayParentRef{Name: "internal"})
+	other.APIVersion = gatewayAPITCPRouteGVR.GroupVersion().String()
+	createUnstructured(t, client, gatewayAPITCPRouteGVR, other)
+
+	src, err := NewGatewayTLSRouteSource(client, "default", "", "", "", false, false)
+	require.NoError(t, err)
+
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "api.internal.example.org", Targets: endpoint.Targets{"10.0.0.1"}, RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "tlsroute/default/api"}},
+		{DNSName: "*.internal.example.org", Targets: endpoint.Targets{"10.0.0.1"}, RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "tlsroute/default/auth"}},
+	})
+}
+
+func TestIntersectHostname(t *testing.T) {
+	for _, ti := range []struct {
+		route    string
+		listener string
+		expected string
+		match    bool
+	}{
+		{route: "foo.example.org", listener: "", expected: "foo.example.org", match: true},
+		{route: "foo.example.org", listener: "foo.example.org", expected: "foo.example.org", match: true},
+		{route: "foo.example.org", listener: "bar.example.org"},
+		{route: "foo.example.org", listener: "*.example.org", expected: "foo.example.org", match: true},
+		{route: "foo.bar.example.org", listener: "*.example.org", expected: "foo.bar.example.org", match: true},
+		{route: "example.org", listener: "*.example.org"},
+		{route: "fooexample.org", listener: "*.example.org"},
+		{route: "*.example.org", listener: "foo.example.org", expected: "foo.example.org", match: true},
+		{route: "*.example.org", listener: "*.example.org", expected: "*.example.org", match: true},
+		{route: "*.foo.example.org", listener: "*.example.org", expected: "*.foo.example.org", match: true},
+		{route: "*.example.org", listener: "*.foo.example.org", expected: "*.foo.example.org", match: true},
+		{route: "*.example.org", listener: "*.example.com"},
+	} {
+		hostname, ok := intersectHostname(ti.route, ti.listener)
+		assert.Equal(t, ti.match, ok, "%s and %s", ti.route, ti.listener)
+		assert.Equal(t, ti.expected, hostname, "%s and %s", ti.route, ti.listener)
+	}
+}
+
+func TestNewGatewayRouteSourceInvalidFilters(t *testing.T) {
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme())
+
+	_, err := NewGatewayTLSRouteSource(client, "", "", "dns in (public", "", false, false)
+	assert.Error(t, err)
+
+	_, err = NewGatewayTLSRouteSource(client, "", "", "", "{{.Name", false, false)
+	assert.Error(t, err)
+}
+
+func TestGatewayRouteSourceAddEventHandler(t *testing.T) {
+	client := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	src, err := NewGatewayHTTPRouteSource(client, "", "", "", "", false, false)
+	require.NoError(t, err)
+
+	handled := make(chan struct{}, 10)
+	src.AddEventHandler(context.Background(), func() {
+		handled <- struct{}{}
+	})
+
+	// changes of gateways change the targets, so they trigger the handler like changes of routes
+	createUnstructured(t, client, gatewayAPIGatewayGVR, newTestGateway("default", "internal", nil, "10.0.0.1"))
+	select {
+	case <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("event handler wasn't called")
+	}
+}
@@ -270,6 +270,7 @@ func (sc *httpProxySource) filterByAnnotations(httpProxies []*projectcontour.HTT
 func (sc *httpProxySource) setResourceLabel(httpProxy *projectcontour.HTTPProxy, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("HTTPProxy/%s/%s", httpProxy.Namespace, httpProxy.Name)
+		setConflictResolutionLabels(ep, httpProxy.ObjectMeta)
 	}
 }
 
@@ -26,11 +26,16 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
-	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/api/networking/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	kubeinformers "k8s.io/client-go/informers"
-	extinformers "k8s.io/client-go/informers/extensions/v1beta1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
@@ -46,6 +51,15 @@ const (
 	// Possible values for the ingress-hostname-source annotation
 	IngressHostnameSourceAnnotationOnlyValue   = "annotation-only"
 	IngressHostnameSourceDefinedHostsOnlyValue = "defined-hosts-only"
+
+	// ingressClassAnnotationKey is the legacy annotation selecting the class of an ingress, before spec.ingressClassName
+	ingressClassAnnotationKey = "kubernetes.io/ingress.class"
+)
+
+// The versions of ingresses. networking.k8s.io/v1 is served since Kubernetes 1.19, v1beta1 until 1.21.
+var (
+	ingressV1GVR      = schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}
+	ingressV1beta1GVR = schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"}
 )
 
 // ingressSource is an implementation of Source for Kubernetes ingress objects.
@@ -59,13 +73,15 @@ type ingressSource struct {
 	fqdnTemplate             *template.Template
 	combineFQDNAnnotation    bool
 	ignoreHostnameAnnotation bool
-	ingressInformer          extinformers.IngressInformer
+	ingressInformer          kubeinformers.GenericInformer
 	ignoreIngressTLSSpec     bool
 	ignoreIngressRulesSpec   bool
+	ingressClassNames        []string
 }
 
-// NewIngressSource creates a new ingressSource with the given config.
-func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, ignoreHostnameAnnotation bool, ignoreIngressTLSSpec bool, ignoreIngressRulesSpec bool) (Source, error) {
+// NewIngressSource creates a new ingressSource with the given config. Ingresses are read in version
+// networking.k8s.io/v1 if the cluster serves it, otherwise in networking.k8s.io/v1beta1.
+func NewIngressSource(dynamicKubeClient dynamic.Interface, kubeClient kubernetes.Interface, namespace, annotationFilter string, fqdnTemplate string, combineFqdnAnnotation bool, ignoreHostnameAnnotation bool, ignoreIngressTLSSpec bool, ignoreIngressRulesSpec bool, ingressClassNames []string) (Source, error) {
 	var (
 		tmpl *template.Template
 		err  error
@@ -79,10 +95,43 @@ func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 		}
 	}
 
+	if len(ingressClassNames) > 0 {
+		selector, err := getLabelSelector(annotationFilter)
+		if err != nil {
+			return nil, err
+		}
+		requirements, _ := selector.Requirements()
+		for _, requirement := range requirements {
+			if requirement.Key() == ingressClassAnnotationKey {
+				return nil, fmt.Errorf("ingress classes can't be combined with an annotation filter on %s", ingressClassAnnotationKey)
+			}
+		}
+	}
+
 	// Use shared informer to listen for add/update/delete of ingresses in the specified namespace.
 	// Set resync period to 0, to prevent processing when nothing has changed.
-	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
-	ingressInformer := informerFactory.Extensions().V1beta1().Ingresses()
+	var (
+		informerFactory interface{ Start(stopCh <-chan struct{}) }
+		ingressInformer kubeinformers.GenericInformer
+	)
+	ingressGVR, err := IngressGVR(kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if ingressGVR == ingressV1GVR {
+		// k8s.io/api has no types of networking.k8s.io/v1 ingresses yet, so they are watched as unstructured objects
+		dynamicInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, nil)
+		ingressInformer = dynamicInformerFactory.ForResource(ingressV1GVR)
+		informerFactory = dynamicInformerFactory
+	} else {
+		log.Infof("Falling back to %s ingresses since %s isn't served", ingressV1beta1GVR.GroupVersion(), ingressV1GVR.GroupVersion())
+		kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
+		ingressInformer, err = kubeInformerFactory.ForResource(ingressV1beta1GVR)
+		if err != nil {
+			return nil, err
+		}
+		informerFactory = kubeInformerFactory
+	}
 
 	// Add default resource event handlers to properly initialize informer.
 	ingressInformer.Informer().AddEventHandler(
@@ -113,6 +162,7 @@ func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 		ingressInformer:          ingressInformer,
 		ignoreIngressTLSSpec:     ignoreIngressTLSSpec,
 		ignoreIngressRulesSpec:   ignoreIngressRulesSpec,
+		ingressClassNames:        ingressClassNames,
 	}
 	return sc, nil
 }
@@ -120,7 +170,7 @@ func NewIngressSource(kubeClient kubernetes.Interface, namespace, annotationFilt
 // Endpoints returns endpoint objects for each host-target combination that should be processed.
 // Retrieves all ingress resources on all namespaces
 func (sc *ingressSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	ingresses, err := sc.ingressInformer.Lister().Ingresses(sc.namespace).List(labels.Everything())
+	ingresses, err := sc.listIngresses()
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +178,7 @@ func (sc *ingressSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, e
 	if err != nil {
 		return nil, err
 	}
+	ingresses = sc.filterByIngressClass(ingresses)
 
 	endpoints := []*endpoint.Endpoint{}
 
@@ -207,6 +258,32 @@ func (sc *ingressSource) endpointsFromTemplate(ing *v1beta1.Ingress) ([]*endpoin
 	return endpoints, nil
 }
 
+// listIngresses lists the ingresses of the informer. Ingresses of networking.k8s.io/v1 are converted
+// to v1beta1, which has the same fields for everything but the backends, which aren't needed here.
+func (sc *ingressSource) listIngresses() ([]*v1beta1.Ingress, error) {
+	objs, err := sc.ingressInformer.Lister().ByNamespace(sc.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	ingresses := make([]*v1beta1.Ingress, 0, len(objs))
+	for _, obj := range objs {
+		switch obj := obj.(type) {
+		case *v1beta1.Ingress:
+			ingresses = append(ingresses, obj)
+		case *unstructured.Unstructured:
+			ingress := &v1beta1.Ingress{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), ingress); err != nil {
+				return nil, fmt.Errorf("failed to convert ingress %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+			}
+			ingresses = append(ingresses, ingress)
+		default:
+			return nil, fmt.Errorf("unexpected object of type %T in ingress informer", obj)
+		}
+	}
+	return ingresses, nil
+}
+
 // filterByAnnotations filters a list of ingresses by a given annotation selector.
 func (sc *ingressSource) filterByAnnotations(ingresses []*v1beta1.Ingress) ([]*v1beta1.Ingress, error) {
 	selector, err := getLabelSelector(sc.annotationFilter)
@@ -231,9 +308,36 @@ func (sc *ingressSource) filterByAnnotations(ingresses []*v1beta1.Ingress) ([]*v
 	return filteredList, nil
 }
 
+// filterByIngressClass filters a list of ingresses by their class. The class is given by spec.ingressClassName,
+// or by the legacy annotation for ingresses without it.
+func (sc *ingressSource) filterByIngressClass(ingresses []*v1beta1.Ingress) []*v1beta1.Ingress {
+	// no ingress classes returns original list
+	if len(sc.ingressClassNames) == 0 {
+		return ingresses
+	}
+
+	filteredList := []*v1beta1.Ingress{}
+
+	for _, ingress := range ingresses {
+		class := ingress.Annotations[ingressClassAnnotationKey]
+		if ingress.Spec.IngressClassName != nil {
+			class = *ingress.Spec.IngressClassName
+		}
+		for _, name := range sc.ingressClassNames {
+			if class == name {
+				filteredList = append(filteredList, ingress)
+				break
+			}
+		}
+	}
+
+	return filteredList
+}
+
 func (sc *ingressSource) setResourceLabel(ingress *v1beta1.Ingress, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("ingress/%s/%s", ingress.Namespace, ingress.Name)
+		setConflictResolutionLabels(ep, ingress.ObjectMeta)
 	}
 }
 
@@ -326,6 +430,40 @@ func targetsFromIngressStatus(status v1beta1.IngressStatus) endpoint.Targets {
 	return targets
 }
 
+// IngressGVR returns the group, version and resource ingresses are read in by the ingress source, which
+// is networking.k8s.io/v1 if the cluster serves it, otherwise networking.k8s.io/v1beta1
+func IngressGVR(kubeClient kubernetes.Interface) (schema.GroupVersionResource, error) {
+	v1Served, err := ingressV1Served(kubeClient)
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	if v1Served {
+		return ingressV1GVR, nil
+	}
+	return ingressV1beta1GVR, nil
+}
+
+// ingressV1Served returns whether the cluster serves ingresses of networking.k8s.io/v1. Only a missing
+// group version means that it isn't served, other errors of the discovery are returned.
+func ingressV1Served(kubeClient kubernetes.Interface) (bool, error) {
+	resources, err := kubeClient.Discovery().ServerResourcesForGroupVersion(ingressV1GVR.GroupVersion().String())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to discover the versions of ingresses: %v", err)
+	}
+	if resources == nil {
+		return false, nil
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == ingressV1GVR.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (sc *ingressSource) AddEventHandler(ctx context.Context, handler func()) {
 	log.Debug("Adding event handler for ingress")
 
@@ -25,8 +25,11 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -35,6 +38,22 @@ import (
 // Validates that ingressSource is a Source
 var _ Source = &ingressSource{}
 
+// newIngressV1beta1Clientset creates a fake clientset of a cluster serving ingresses only in networking.k8s.io/v1beta1
+func newIngressV1beta1Clientset() *fake.Clientset {
+	fakeClient := fake.NewSimpleClientset()
+	fakeClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "networking.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "networkpolicies", Namespaced: true, Kind: "NetworkPolicy"}},
+		},
+		{
+			GroupVersion: "networking.k8s.io/v1beta1",
+			APIResources: []metav1.APIResource{{Name: "ingresses", Namespaced: true, Kind: "Ingress"}},
+		},
+	}
+	return fakeClient
+}
+
 type IngressSuite struct {
 	suite.Suite
 	sc             Source
@@ -42,10 +61,11 @@ type IngressSuite struct {
 }
 
 func (suite *IngressSuite) SetupTest() {
-	fakeClient := fake.NewSimpleClientset()
+	fakeClient := newIngressV1beta1Clientset()
 	var err error
 
 	suite.sc, err = NewIngressSource(
+		fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme()),
 		fakeClient,
 		"",
 		"",
@@ -54,6 +74,7 @@ func (suite *IngressSuite) SetupTest() {
 		false,
 		false,
 		false,
+		nil,
 	)
 	suite.NoError(err, "should initialize ingress source")
 
@@ -65,7 +86,7 @@ func (suite *IngressSuite) SetupTest() {
 		hostnames:   []string{"v1"},
 		annotations: map[string]string{ALBDualstackAnnotationKey: ALBDualstackAnnotationValue},
 	}).Ingress()
-	_, err = fakeClient.ExtensionsV1beta1().Ingresses(suite.fooWithTargets.Namespace).Create(context.Background(), suite.fooWithTargets, metav1.CreateOptions{})
+	_, err = fakeClient.NetworkingV1beta1().Ingresses(suite.fooWithTargets.Namespace).Create(context.Background(), suite.fooWithTargets, metav1.CreateOptions{})
 	suite.NoError(err, "should succeed")
 }
 
@@ -96,6 +117,7 @@ func TestNewIngressSource(t *testing.T) {
 		annotationFilter         string
 		fqdnTemplate             string
 		combineFQDNAndAnnotation bool
+		ingressClassNames        []string
 		expectError              bool
 	}{
 		{
@@ -128,10 +150,28 @@ func TestNewIngressSource(t *testing.T) {
 			expectError:      false,
 			annotationFilter: "kubernetes.io/ingress.class=nginx",
 		},
+		{
+			title:             "ingress classes",
+			expectError:       false,
+			ingressClassNames: []string{"nginx", "internal"},
+		},
+		{
+			title:             "ingress classes with another annotation filter",
+			expectError:       false,
+			annotationFilter:  "example.com/public=true",
+			ingressClassNames: []string{"nginx"},
+		},
+		{
+			title:             "ingress classes with an annotation filter on the class",
+			expectError:       true,
+			annotationFilter:  "kubernetes.io/ingress.class=nginx",
+			ingressClassNames: []string{"nginx"},
+		},
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			_, err := NewIngressSource(
-				fake.NewSimpleClientset(),
+				fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme()),
+				newIngressV1beta1Clientset(),
 				"",
 				ti.annotationFilter,
 				ti.fqdnTemplate,
@@ -139,6 +179,7 @@ func TestNewIngressSource(t *testing.T) {
 				false,
 				false,
 				false,
+				ti.ingressClassNames,
 			)
 			if ti.expectError {
 				assert.Error(t, err)
@@ -349,6 +390,7 @@ func testIngressEndpoints(t *testing.T) {
 		ignoreHostnameAnnotation bool
 		ignoreIngressTLSSpec     bool
 		ignoreIngressRulesSpec   bool
+		ingressClassNames        []string
 	}{
 		{
 			title:           "no ingress",
@@ -1157,6 +1199,66 @@ func testIngressEndpoints(t *testing.T) {
 				},
 			},
 		},
+		{
+			title:             "ingress classes by field and by annotation",
+			targetNamespace:   "",
+			ingressClassNames: []string{"public", "dmz"},
+			ingressItems: []fakeIngress{
+				{
+					name:             "fake1",
+					namespace:        namespace,
+					ingressClassName: "public",
+					dnsnames:         []string{"example.org"},
+					ips:              []string{"1.2.3.4"},
+				},
+				{
+					name:        "fake2",
+					namespace:   namespace,
+					annotations: map[string]string{"kubernetes.io/ingress.class": "dmz"},
+					dnsnames:    []string{"dmz.example.org"},
+					ips:         []string{"1.2.3.4"},
+				},
+				{
+					name:             "fake3",
+					namespace:        namespace,
+					ingressClassName: "internal",
+					dnsnames:         []string{"internal.example.org"},
+					ips:              []string{"1.2.3.4"},
+				},
+				{
+					name:      "fake4",
+					namespace: namespace,
+					dnsnames:  []string{"unclassified.example.org"},
+					ips:       []string{"1.2.3.4"},
+				},
+			},
+			expected: []*endpoint.Endpoint{
+				{
+					DNSName: "example.org",
+					Targets: endpoint.Targets{"1.2.3.4"},
+				},
+				{
+					DNSName: "dmz.example.org",
+					Targets: endpoint.Targets{"1.2.3.4"},
+				},
+			},
+		},
+		{
+			title:             "ingress class field takes precedence over the annotation",
+			targetNamespace:   "",
+			ingressClassNames: []string{"public"},
+			ingressItems: []fakeIngress{
+				{
+					name:             "fake1",
+					namespace:        namespace,
+					ingressClassName: "internal",
+					annotations:      map[string]string{"kubernetes.io/ingress.class": "public"},
+					dnsnames:         []string{"example.org"},
+					ips:              []string{"1.2.3.4"},
+				},
+			},
+			expected: []*endpoint.Endpoint{},
+		},
 	} {
 		t.Run(ti.title, func(t *testing.T) {
 			ingresses := make([]*v1beta1.Ingress, 0)
@@ -1164,8 +1266,9 @@ func testIngressEndpoints(t *testing.T) {
 				ingresses = append(ingresses, item.Ingress())
 			}
 
-			fakeClient := fake.NewSimpleClientset()
+			fakeClient := newIngressV1beta1Clientset()
 			source, _ := NewIngressSource(
+				fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme()),
 				fakeClient,
 				ti.targetNamespace,
 				ti.annotationFilter,
@@ -1174,9 +1277,10 @@ func testIngressEndpoints(t *testing.T) {
 				ti.ignoreHostnameAnnotation,
 				ti.ignoreIngressTLSSpec,
 				ti.ignoreIngressRulesSpec,
+				ti.ingressClassNames,
 			)
 			for _, ingress := range ingresses {
-				_, err := fakeClient.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(context.Background(), ingress, metav1.CreateOptions{})
+				_, err := fakeClient.NetworkingV1beta1().Ingresses(ingress.Namespace).Create(context.Background(), ingress, metav1.CreateOptions{})
 				require.NoError(t, err)
 			}
 
@@ -1194,7 +1298,7 @@ func testIngressEndpoints(t *testing.T) {
 					}
 
 					// Check for the presence of this ingress.
-					_, err := ingressLister.Ingresses(ingress.Namespace).Get(ingress.Name)
+					_, err := ingressLister.ByNamespace(ingress.Namespace).Get(ingress.Name)
 					if err != nil {
 						allIngressesPresent = false
 						break
@@ -1218,13 +1322,14 @@ func testIngressEndpoints(t *testing.T) {
 
 // ingress specific helper functions
 type fakeIngress struct {
-	dnsnames    []string
-	tlsdnsnames [][]string
-	ips         []string
-	hostnames   []string
-	namespace   string
-	name        string
-	annotations map[string]string
+	dnsnames         []string
+	tlsdnsnames      [][]string
+	ips              []string
+	hostnames        []string
+	namespace        string
+	name             string
+	annotations      map[string]string
+	ingressClassName string
 }
 
 func (ing fakeIngress) Ingress() *v1beta1.Ingress {
@@ -1243,6 +1348,9 @@ func (ing fakeIngress) Ingress() *v1beta1.Ingress {
 			},
 		},
 	}
+	if ing.ingressClassName != "" {
+		ingress.Spec.IngressClassName = &ing.ingressClassName
+	}
 	for _, dnsname := range ing.dnsnames {
 		ingress.Spec.Rules = append(ingress.Spec.Rules, v1beta1.IngressRule{
 			Host: dnsname,
@@ -1265,3 +1373,67 @@ func (ing fakeIngress) Ingress() *v1beta1.Ingress {
 	}
 	return ingress
 }
+
+func TestIngressSourceV1(t *testing.T) {
+	fakeClient := fake.NewSimpleClientset()
+	fakeClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "networking.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "ingresses", Namespaced: true, Kind: "Ingress"}},
+		},
+	}
+	fakeDynamicClient := fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme())
+
+	// ingresses of networking.k8s.io/v1 have other backends than v1beta1
+	ingress := &unstructured.Unstructured{}
+	require.NoError(t, ingress.UnmarshalJSON([]byte(`{
+	"apiVersion": "networking.k8s.io/v1",
+	"kind": "Ingress",
+	"metadata": {"namespace": "default", "name": "foo", "annotations": {"external-dns.alpha.kubernetes.io/ttl": "60"}},
+	"spec": {
+		"ingressClassName": "public",
+		"defaultBackend": {"service": {"name": "foo", "port": {"number": 80}}},
+		"rules": [{"host": "foo.example.org", "http": {"paths": [{"path": "/", "pathType": "Prefix", "backend": {"service": {"name": "foo", "port": {"name": "http"}}}}]}}],
+		"tls": [{"hosts": ["tls.example.org"], "secretName": "foo"}]
+	},
+	"status": {"loadBalancer": {"ingress": [{"ip": "1.2.3.4"}, {"hostname": "lb.example.com"}]}}
+}`)))
+	_, err := fakeDynamicClient.Resource(ingressV1GVR).Namespace("default").Create(context.Background(), ingress, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// the ingress isn't found in v1beta1, so it must be read in v1
+	source, err := NewIngressSource(fakeDynamicClient, fakeClient, "", "", "", false, false, false, false, []string{"public"})
+	require.NoError(t, err)
+
+	gvr, err := IngressGVR(fakeClient)
+	require.NoError(t, err)
+	assert.Equal(t, ingressV1GVR, gvr)
+
+	endpoints, err := source.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/foo"}},
+		{DNSName: "foo.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME, RecordTTL: 60, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/foo"}},
+		{DNSName: "tls.example.org", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: endpoint.RecordTypeA, RecordTTL: 60, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/foo"}},
+		{DNSName: "tls.example.org", Targets: endpoint.Targets{"lb.example.com"}, RecordType: endpoint.RecordTypeCNAME, RecordTTL: 60, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "ingress/default/foo"}},
+	})
+}
+
+func TestIngressSourceV1beta1(t *testing.T) {
+	fakeClient := newIngressV1beta1Clientset()
+	_, err := NewIngressSource(fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme()), fakeClient, "", "", "", false, false, false, false, nil)
+	require.NoError(t, err)
+
+	gvr, err := IngressGVR(fakeClient)
+	require.NoError(t, err)
+	assert.Equal(t, ingressV1beta1GVR, gvr)
+}
+
+func TestIngressSourceDiscoveryError(t *testing.T) {
+	// the fake discovery fails for group versions it doesn't know, which must not be taken for v1 not being served
+	_, err := NewIngressSource(fakeDynamic.NewSimpleDynamicClient(runtime.NewScheme()), fake.NewSimpleClientset(), "", "", "", false, false, false, false, nil)
+	assert.Error(t, err)
+
+	_, err = IngressGVR(fake.NewSimpleClientset())
+	assert.Error(t, err)
+}
@@ -277,6 +277,7 @@ func (sc *ingressRouteSource) filterByAnnotations(ingressRoutes []*contour.Ingre
 func (sc *ingressRouteSource) setResourceLabel(ingressRoute *contour.IngressRoute, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("ingressroute/%s/%s", ingressRoute.Namespace, ingressRoute.Name)
+		setConflictResolutionLabels(ep, ingressRoute.ObjectMeta)
 	}
 }
 
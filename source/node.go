@@ -124,12 +124,7 @@ func (ns *nodeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, erro
 			log.Warn(err)
 		}
 
-		// create new endpoint with the information we already have
-		ep := &endpoint.Endpoint{
-			RecordType: "A", // hardcoded DNS record type
-			RecordTTL:  ttl,
-		}
-
+		var dnsName string
 		if ns.fqdnTemplate != nil {
 			// Process the whole template string
 			var buf bytes.Buffer
@@ -138,10 +133,10 @@ func (ns *nodeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, erro
 				return nil, fmt.Errorf("failed to apply template on node %s: %v", node.Name, err)
 			}
 
-			ep.DNSName = buf.String()
-			log.Debugf("applied template for %s, converting to %s", node.Name, ep.DNSName)
+			dnsName = buf.String()
+			log.Debugf("applied template for %s, converting to %s", node.Name, dnsName)
 		} else {
-			ep.DNSName = node.Name
+			dnsName = node.Name
 			log.Debugf("not applying template for %s", node.Name)
 		}
 
@@ -150,14 +145,23 @@ func (ns *nodeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, erro
 			return nil, fmt.Errorf("failed to get node address from %s: %s", node.Name, err.Error())
 		}
 
-		ep.Targets = endpoint.Targets(addrs)
-		ep.Labels = endpoint.NewLabels()
-
-		log.Debugf("adding endpoint %s", ep)
-		if _, ok := endpoints[ep.DNSName]; ok {
-			endpoints[ep.DNSName].Targets = append(endpoints[ep.DNSName].Targets, ep.Targets...)
-		} else {
-			endpoints[ep.DNSName] = ep
+		// create an endpoint per address family, IPv6 addresses get an AAAA record
+		for _, addr := range addrs {
+			recordType := suitableType(addr)
+			key := dnsName + "/" + recordType
+			if ep, ok := endpoints[key]; ok {
+				ep.Targets = append(ep.Targets, addr)
+				continue
+			}
+			ep := &endpoint.Endpoint{
+				DNSName:    dnsName,
+				Targets:    endpoint.Targets{addr},
+				RecordType: recordType,
+				RecordTTL:  ttl,
+				Labels:     endpoint.NewLabels(),
+			}
+			log.Debugf("adding endpoint %s", ep)
+			endpoints[key] = ep
 		}
 	}
 
@@ -17,14 +17,17 @@ limitations under the License.
 package source
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"text/template"
 	"time"
 
 	"sigs.k8s.io/external-dns/endpoint"
 
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
@@ -34,15 +37,35 @@ import (
 )
 
 type podSource struct {
-	client        kubernetes.Interface
-	namespace     string
-	podInformer   coreinformers.PodInformer
-	nodeInformer  coreinformers.NodeInformer
-	compatibility string
+	client             kubernetes.Interface
+	namespace          string
+	annotationFilter   string
+	annotationSelector labels.Selector
+	labelSelector      labels.Selector
+	fqdnTemplate       *template.Template
+	podInformer        coreinformers.PodInformer
+	nodeInformer       coreinformers.NodeInformer
+	compatibility      string
+	publishPodIPs      bool
 }
 
 // NewPodSource creates a new podSource with the given config.
-func NewPodSource(kubeClient kubernetes.Interface, namespace string, compatibility string) (Source, error) {
+func NewPodSource(kubeClient kubernetes.Interface, namespace, annotationFilter, labelFilter, fqdnTemplate, compatibility string, publishPodIPs bool) (Source, error) {
+	tmpl, err := parseTemplate(fqdnTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := labels.Parse(labelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	annotationSelector, err := getLabelSelector(annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+
 	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(namespace))
 	podInformer := informerFactory.Core().V1().Pods()
 	nodeInformer := informerFactory.Core().V1().Nodes()
@@ -63,7 +86,7 @@ func NewPodSource(kubeClient kubernetes.Interface, namespace string, compatibili
 	informerFactory.Start(wait.NeverStop)
 
 	// wait for the local cache to be populated.
-	err := poll(time.Second, 60*time.Second, func() (bool, error) {
+	err = poll(time.Second, 60*time.Second, func() (bool, error) {
 		return podInformer.Informer().HasSynced() &&
 			nodeInformer.Informer().HasSynced(), nil
 	})
@@ -72,76 +95,200 @@ func NewPodSource(kubeClient kubernetes.Interface, namespace string, compatibili
 	}
 
 	return &podSource{
-		client:        kubeClient,
-		podInformer:   podInformer,
-		nodeInformer:  nodeInformer,
-		namespace:     namespace,
-		compatibility: compatibility,
+		client:             kubeClient,
+		podInformer:        podInformer,
+		nodeInformer:       nodeInformer,
+		namespace:          namespace,
+		annotationFilter:   annotationFilter,
+		annotationSelector: annotationSelector,
+		labelSelector:      labelSelector,
+		fqdnTemplate:       tmpl,
+		compatibility:      compatibility,
+		publishPodIPs:      publishPodIPs,
 	}, nil
 }
 
-func (*podSource) AddEventHandler(ctx context.Context, handler func()) {
+func (ps *podSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for pod")
 
+	// Right now there is no way to remove event handler from informer, see:
+	// https://github.com/kubernetes/kubernetes/issues/79610
+	ps.podInformer.Informer().AddEventHandler(
+		cache.FilteringResourceEventHandler{
+			// pods without records, which are most of them, are ignored
+			FilterFunc: ps.mayHaveRecords,
+			Handler: cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					handler()
+				},
+				UpdateFunc: func(old interface{}, new interface{}) {
+					handler()
+				},
+				DeleteFunc: func(obj interface{}) {
+					handler()
+				},
+			},
+		},
+	)
+}
+
+// mayHaveRecords returns whether the pod, or the last known state of a deleted pod, may have records
+func (ps *podSource) mayHaveRecords(obj interface{}) bool {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return false
+	}
+	if !pod.Spec.HostNetwork && !ps.publishPodIPs {
+		return false
+	}
+	if !ps.labelSelector.Matches(labels.Set(pod.Labels)) || !matchLabelSelector(ps.annotationSelector, pod.Annotations) {
+		return false
+	}
+	// every pod is named by the template, but only while publishing pod IPs
+	if ps.fqdnTemplate != nil && ps.publishPodIPs {
+		return true
+	}
+	internalHostnames, hostnames := ps.hostnames(pod)
+	return len(internalHostnames) > 0 || len(hostnames) > 0
 }
 
 func (ps *podSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	pods, err := ps.podInformer.Lister().Pods(ps.namespace).List(labels.Everything())
+	pods, err := ps.podInformer.Lister().Pods(ps.namespace).List(ps.labelSelector)
 	if err != nil {
 		return nil, err
 	}
 
-	domains := make(map[string][]string)
+	pods, err = ps.filterByAnnotations(pods)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := make(map[string]endpoint.Targets)
 	for _, pod := range pods {
-		if !pod.Spec.HostNetwork {
+		if !pod.Spec.HostNetwork && !ps.publishPodIPs {
 			log.Debugf("skipping pod %s. hostNetwork=false", pod.Name)
 			continue
 		}
 
-		if domain, ok := pod.Annotations[internalHostnameAnnotationKey]; ok {
-			if _, ok := domains[domain]; !ok {
-				domains[domain] = []string{}
-			}
-			domains[domain] = append(domains[domain], pod.Status.PodIP)
+		// the IPs of pods which aren't running may already be reused
+		if !pod.Spec.HostNetwork && pod.Status.Phase != corev1.PodRunning {
+			log.Debugf("skipping pod %s. phase=%s", pod.Name, pod.Status.Phase)
+			continue
 		}
 
-		if domain, ok := pod.Annotations[hostnameAnnotationKey]; ok {
-			if _, ok := domains[domain]; !ok {
-				domains[domain] = []string{}
-			}
-
-			node, _ := ps.nodeInformer.Lister().Get(pod.Spec.NodeName)
-			for _, address := range node.Status.Addresses {
-				if address.Type == corev1.NodeExternalIP {
-					domains[domain] = append(domains[domain], address.Address)
-				}
+		// the template is shared with other sources, so it only names pods once their IPs are published,
+		// otherwise every hostNetwork pod would suddenly get a record
+		internalHostnames, hostnames := ps.hostnames(pod)
+		if len(internalHostnames) == 0 && len(hostnames) == 0 && ps.fqdnTemplate != nil && ps.publishPodIPs {
+			var buf bytes.Buffer
+			if err := ps.fqdnTemplate.Execute(&buf, pod); err != nil {
+				return nil, fmt.Errorf("failed to apply template on pod %s: %v", pod.Name, err)
 			}
+			hostnames = append(hostnames, buf.String())
 		}
 
-		if ps.compatibility == "kops-dns-controller" {
-			if domain, ok := pod.Annotations[kopsDNSControllerInternalHostnameAnnotationKey]; ok {
-				if _, ok := domains[domain]; !ok {
-					domains[domain] = []string{}
-				}
-				domains[domain] = append(domains[domain], pod.Status.PodIP)
-			}
+		for _, domain := range internalHostnames {
+			domains[domain] = append(domains[domain], ps.podIPs(pod)...)
+		}
 
-			if domain, ok := pod.Annotations[kopsDNSControllerHostnameAnnotationKey]; ok {
-				if _, ok := domains[domain]; !ok {
-					domains[domain] = []string{}
-				}
-
-				node, _ := ps.nodeInformer.Lister().Get(pod.Spec.NodeName)
-				for _, address := range node.Status.Addresses {
-					if address.Type == corev1.NodeExternalIP {
-						domains[domain] = append(domains[domain], address.Address)
-					}
-				}
+		// hostNetwork pods are reachable on the external IPs of their nodes
+		for _, domain := range hostnames {
+			if !pod.Spec.HostNetwork {
+				domains[domain] = append(domains[domain], ps.podIPs(pod)...)
+				continue
 			}
+			domains[domain] = append(domains[domain], ps.nodeExternalIPs(pod)...)
 		}
 	}
 	endpoints := []*endpoint.Endpoint{}
 	for domain, targets := range domains {
-		endpoints = append(endpoints, endpoint.NewEndpoint(domain, endpoint.RecordTypeA, targets...))
+		endpoints = append(endpoints, endpointsForHostname(domain, targets, endpoint.TTL(0), nil, "")...)
 	}
 	return endpoints, nil
 }
+
+// hostnames returns the internal hostnames and the hostnames of the pod given by its annotations
+func (ps *podSource) hostnames(pod *corev1.Pod) (internalHostnames, hostnames []string) {
+	if domain, ok := pod.Annotations[internalHostnameAnnotationKey]; ok {
+		internalHostnames = append(internalHostnames, domain)
+	}
+	if domain, ok := pod.Annotations[hostnameAnnotationKey]; ok {
+		hostnames = append(hostnames, domain)
+	}
+
+	if ps.compatibility == "kops-dns-controller" {
+		if domain, ok := pod.Annotations[kopsDNSControllerInternalHostnameAnnotationKey]; ok {
+			internalHostnames = append(internalHostnames, domain)
+		}
+		if domain, ok := pod.Annotations[kopsDNSControllerHostnameAnnotationKey]; ok {
+			hostnames = append(hostnames, domain)
+		}
+	}
+	return internalHostnames, hostnames
+}
+
+// podIPs returns the IPs of both families of pods without hostNetwork, and the primary IP of hostNetwork pods
+func (ps *podSource) podIPs(pod *corev1.Pod) []string {
+	ips := []string{}
+	if !pod.Spec.HostNetwork {
+		for _, podIP := range pod.Status.PodIPs {
+			if podIP.IP != "" {
+				ips = append(ips, podIP.IP)
+			}
+		}
+	}
+	if len(ips) == 0 && pod.Status.PodIP != "" {
+		ips = append(ips, pod.Status.PodIP)
+	}
+	return ips
+}
+
+// nodeExternalIPs returns the external IPs of the node the pod is running on
+func (ps *podSource) nodeExternalIPs(pod *corev1.Pod) []string {
+	ips := []string{}
+	node, err := ps.nodeInformer.Lister().Get(pod.Spec.NodeName)
+	if err != nil {
+		log.Debugf("failed to get node %s of pod %s: %v", pod.Spec.NodeName, pod.Name, err)
+		return ips
+	}
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeExternalIP {
+			ips = append(ips, address.Address)
+		}
+	}
+	return ips
+}
+
+// filterByAnnotations filters a list of pods by a given annotation selector.
+func (ps *podSource) filterByAnnotations(pods []*corev1.Pod) ([]*corev1.Pod, error) {
+	labelSelector, err := metav1.ParseToLabelSelector(ps.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty filter returns original list
+	if selector.Empty() {
+		return pods, nil
+	}
+
+	filteredList := []*corev1.Pod{}
+
+	for _, pod := range pods {
+		// convert the pod's annotations to an equivalent label selector
+		annotations := labels.Set(pod.Annotations)
+
+		// include pod if its annotations match the selector
+		if selector.Matches(annotations) {
+			filteredList = append(filteredList, pod)
+		}
+	}
+
+	return filteredList, nil
+}
@@ -19,11 +19,14 @@ package source
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
@@ -407,7 +410,7 @@ func TestPodSource(t *testing.T) {
 				}
 			}
 
-			client, err := NewPodSource(kubernetes, tc.targetNamespace, tc.compatibility)
+			client, err := NewPodSource(kubernetes, tc.targetNamespace, "", "", "", tc.compatibility, false)
 			require.NoError(t, err)
 
 			endpoints, err := client.Endpoints(ctx)
@@ -423,3 +426,315 @@ func TestPodSource(t *testing.T) {
 
 	}
 }
+
+// TestPodSourcePublishPodIPs tests that the IPs of pods are published regardless of hostNetwork and that pods are filtered.
+func TestPodSourcePublishPodIPs(t *testing.T) {
+	nodes := []*corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "my-node1",
+			},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeExternalIP, Address: "54.10.11.1"},
+					{Type: corev1.NodeInternalIP, Address: "10.0.1.1"},
+				},
+			},
+		},
+	}
+	newPod := func(name, namespace string, hostNetwork bool, phase corev1.PodPhase, annotations, labels map[string]string, ips ...string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Annotations: annotations,
+				Labels:      labels,
+			},
+			Spec: corev1.PodSpec{
+				HostNetwork: hostNetwork,
+				NodeName:    "my-node1",
+			},
+			Status: corev1.PodStatus{
+				Phase: phase,
+			},
+		}
+		for _, ip := range ips {
+			pod.Status.PodIPs = append(pod.Status.PodIPs, corev1.PodIP{IP: ip})
+		}
+		if len(ips) > 0 {
+			pod.Status.PodIP = ips[0]
+		}
+		return pod
+	}
+
+	for _, tc := range []struct {
+		title            string
+		annotationFilter string
+		labelFilter      string
+		fqdnTemplate     string
+		publishPodIPs    bool
+		pods             []*corev1.Pod
+		expected         []*endpoint.Endpoint
+	}{
+		{
+			title:         "publish the IPs of both families of annotated pods",
+			publishPodIPs: true,
+			pods: []*corev1.Pod{
+				newPod("my-pod1", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, nil, "10.244.0.1", "2001:db8::1"),
+				newPod("my-pod2", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, nil, "10.244.0.2", "2001:db8::2"),
+				newPod("my-pod3", "default", false, corev1.PodRunning, map[string]string{internalHostnameAnnotationKey: "internal.a.foo.example.org"}, nil, "10.244.0.3"),
+				newPod("my-pod4", "default", false, corev1.PodRunning, nil, nil, "10.244.0.4"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"10.244.0.1", "10.244.0.2"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"2001:db8::1", "2001:db8::2"}, RecordType: endpoint.RecordTypeAAAA},
+				{DNSName: "internal.a.foo.example.org", Targets: endpoint.Targets{"10.244.0.3"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:         "keep publishing the external IPs of the nodes of hostNetwork pods",
+			publishPodIPs: true,
+			pods: []*corev1.Pod{
+				newPod("my-pod1", "kube-system", true, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, nil, "10.0.1.1"),
+				newPod("my-pod2", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "b.foo.example.org"}, nil, "10.244.0.2"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"54.10.11.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "b.foo.example.org", Targets: endpoint.Targets{"10.244.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:         "skip pods which aren't running",
+			publishPodIPs: true,
+			pods: []*corev1.Pod{
+				newPod("my-pod1", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, nil, "10.244.0.1"),
+				newPod("my-pod2", "default", false, corev1.PodPending, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, nil, "10.244.0.2"),
+				newPod("my-pod3", "default", false, corev1.PodSucceeded, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, nil, "10.244.0.3"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"10.244.0.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:         "name pods without hostname annotations with the template",
+			fqdnTemplate:  "{{.Name}}.{{.Namespace}}.pods.example.com",
+			publishPodIPs: true,
+			pods: []*corev1.Pod{
+				newPod("my-pod1", "default", false, corev1.PodRunning, nil, nil, "10.244.0.1"),
+				newPod("my-pod2", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, nil, "10.244.0.2"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "my-pod1.default.pods.example.com", Targets: endpoint.Targets{"10.244.0.1"}, RecordType: endpoint.RecordTypeA},
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"10.244.0.2"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:        "don't name pods with the template without publishing pod IPs",
+			fqdnTemplate: "{{.Name}}.{{.Namespace}}.pods.example.com",
+			pods: []*corev1.Pod{
+				newPod("my-pod1", "kube-system", true, corev1.PodRunning, nil, nil, "10.0.1.1"),
+				newPod("my-pod2", "default", false, corev1.PodRunning, nil, nil, "10.244.0.2"),
+				newPod("my-pod3", "kube-system", true, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, nil, "10.0.1.1"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"54.10.11.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:         "filter pods by labels",
+			labelFilter:   "app=web",
+			publishPodIPs: true,
+			pods: []*corev1.Pod{
+				newPod("my-pod1", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "a.foo.example.org"}, map[string]string{"app": "web"}, "10.244.0.1"),
+				newPod("my-pod2", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "b.foo.example.org"}, map[string]string{"app": "db"}, "10.244.0.2"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"10.244.0.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			title:            "filter pods by annotations",
+			annotationFilter: "kubernetes.io/ingress.class=nginx",
+			publishPodIPs:    true,
+			pods: []*corev1.Pod{
+				newPod("my-pod1", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "a.foo.example.org", "kubernetes.io/ingress.class": "nginx"}, nil, "10.244.0.1"),
+				newPod("my-pod2", "default", false, corev1.PodRunning, map[string]string{hostnameAnnotationKey: "b.foo.example.org"}, nil, "10.244.0.2"),
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "a.foo.example.org", Targets: endpoint.Targets{"10.244.0.1"}, RecordType: endpoint.RecordTypeA},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			kubernetes := fake.NewSimpleClientset()
+			ctx := context.Background()
+
+			for _, node := range nodes {
+				_, err := kubernetes.CoreV1().Nodes().Create(ctx, node, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			for _, pod := range tc.pods {
+				_, err := kubernetes.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			client, err := NewPodSource(kubernetes, "", tc.annotationFilter, tc.labelFilter, tc.fqdnTemplate, "", tc.publishPodIPs)
+			require.NoError(t, err)
+
+			endpoints, err := client.Endpoints(ctx)
+			require.NoError(t, err)
+
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+}
+
+func TestNewPodSourceInvalidFilters(t *testing.T) {
+	_, err := NewPodSource(fake.NewSimpleClientset(), "", "", "app in (web", "", "", false)
+	assert.Error(t, err)
+
+	_, err = NewPodSource(fake.NewSimpleClientset(), "", "kubernetes.io/ingress.class in (nginx", "", "", "", false)
+	assert.Error(t, err)
+
+	_, err = NewPodSource(fake.NewSimpleClientset(), "", "", "", "{{.Name", "", false)
+	assert.Error(t, err)
+}
+
+// TestPodSourceMayHaveRecords tests that events of pods which are filtered out or whose IPs aren't published are ignored.
+func TestPodSourceMayHaveRecords(t *testing.T) {
+	newPod := func(hostNetwork bool, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "my-pod1",
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+			Spec: corev1.PodSpec{
+				HostNetwork: hostNetwork,
+			},
+		}
+	}
+	annotated := map[string]string{hostnameAnnotationKey: "a.foo.example.org"}
+	annotatedNginx := map[string]string{hostnameAnnotationKey: "a.foo.example.org", "kubernetes.io/ingress.class": "nginx"}
+
+	for _, tc := range []struct {
+		title            string
+		annotationFilter string
+		fqdnTemplate     string
+		publishPodIPs    bool
+		pod              *corev1.Pod
+		expected         bool
+	}{
+		{
+			title:    "annotated hostNetwork pod",
+			pod:      newPod(true, annotated),
+			expected: true,
+		},
+		{
+			title: "pod without hostNetwork",
+			pod:   newPod(false, annotated),
+		},
+		{
+			title:         "pod without hostNetwork publishing pod IPs",
+			publishPodIPs: true,
+			pod:           newPod(false, annotated),
+			expected:      true,
+		},
+		{
+			title:            "pod not matching the annotation filter",
+			annotationFilter: "kubernetes.io/ingress.class=nginx",
+			pod:              newPod(true, annotated),
+		},
+		{
+			title:            "pod matching the annotation filter",
+			annotationFilter: "kubernetes.io/ingress.class=nginx",
+			pod:              newPod(true, annotatedNginx),
+			expected:         true,
+		},
+		{
+			title:        "hostNetwork pod without annotations and a template",
+			fqdnTemplate: "{{.Name}}.pods.example.com",
+			pod:          newPod(true, nil),
+		},
+		{
+			title:         "hostNetwork pod without annotations and a template publishing pod IPs",
+			fqdnTemplate:  "{{.Name}}.pods.example.com",
+			publishPodIPs: true,
+			pod:           newPod(true, nil),
+			expected:      true,
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			client, err := NewPodSource(fake.NewSimpleClientset(), "", tc.annotationFilter, "", tc.fqdnTemplate, "", tc.publishPodIPs)
+			require.NoError(t, err)
+
+			ps := client.(*podSource)
+			assert.Equal(t, tc.expected, ps.mayHaveRecords(tc.pod))
+			assert.Equal(t, tc.expected, ps.mayHaveRecords(cache.DeletedFinalStateUnknown{Obj: tc.pod}))
+		})
+	}
+}
+
+// TestPodSourceAddEventHandler tests that the event handler is only called for pods with hostname annotations.
+func TestPodSourceAddEventHandler(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset()
+	ctx := context.Background()
+
+	client, err := NewPodSource(kubernetes, "", "", "", "", "", false)
+	require.NoError(t, err)
+
+	handled := make(chan struct{}, 10)
+	client.AddEventHandler(ctx, func() {
+		handled <- struct{}{}
+	})
+	waitHandled := func() {
+		select {
+		case <-handled:
+		case <-time.After(10 * time.Second):
+			t.Fatal("event handler wasn't called")
+		}
+	}
+
+	pods := kubernetes.CoreV1().Pods("kube-system")
+	unannotated := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-pod1",
+			Namespace: "kube-system",
+		},
+		Spec: corev1.PodSpec{
+			HostNetwork: true,
+			NodeName:    "my-node1",
+		},
+	}
+	annotated := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-pod2",
+			Namespace: "kube-system",
+			Annotations: map[string]string{
+				hostnameAnnotationKey: "a.foo.example.org",
+			},
+		},
+		Spec: corev1.PodSpec{
+			HostNetwork: true,
+			NodeName:    "my-node1",
+		},
+	}
+
+	// the events are delivered in order, so the handler would have been called for the pod without annotations first
+	_, err = pods.Create(ctx, unannotated, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = pods.Create(ctx, annotated, metav1.CreateOptions{})
+	require.NoError(t, err)
+	waitHandled()
+	assert.Empty(t, handled, "event handler was called for a pod without hostname annotations")
+
+	annotated.Spec.NodeName = "my-node2"
+	_, err = pods.Update(ctx, annotated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	waitHandled()
+
+	require.NoError(t, pods.Delete(ctx, unannotated.Name, metav1.DeleteOptions{}))
+	require.NoError(t, pods.Delete(ctx, annotated.Name, metav1.DeleteOptions{}))
+	waitHandled()
+	assert.Empty(t, handled, "event handler was called for a pod without hostname annotations")
+}
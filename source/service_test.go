@@ -247,6 +247,31 @@ func testServiceSourceEndpoints(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"annotated dual-stack services return an A and an AAAA endpoint",
+			"",
+			"",
+			"testing",
+			"foo",
+			v1.ServiceTypeLoadBalancer,
+			"",
+			"",
+			false,
+			false,
+			map[string]string{},
+			map[string]string{
+				hostnameAnnotationKey: "foo.example.org.",
+			},
+			"",
+			[]string{},
+			[]string{"1.2.3.4", "2001:db8::1"},
+			[]string{},
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+			false,
+		},
 		{
 			"hostname annotation on services is ignored",
 			"",
@@ -841,6 +866,31 @@ func testServiceSourceEndpoints(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"services annotated with legacy mate annotations return an AAAA endpoint for IPv6 in compatibility mode",
+			"",
+			"",
+			"testing",
+			"foo",
+			v1.ServiceTypeLoadBalancer,
+			"mate",
+			"",
+			false,
+			false,
+			map[string]string{},
+			map[string]string{
+				"zalando.org/dnsname": "foo.example.org.",
+			},
+			"",
+			[]string{},
+			[]string{"1.2.3.4", "2001:db8::1"},
+			[]string{},
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+				{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}},
+			},
+			false,
+		},
 		{
 			"services annotated with legacy molecule annotations return an endpoint in compatibility mode",
 			"",
@@ -25,6 +25,7 @@ import (
 	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -53,6 +54,10 @@ const (
 	controllerAnnotationValue = "dns-controller"
 	// The annotation used for defining the desired hostname
 	internalHostnameAnnotationKey = "external-dns.alpha.kubernetes.io/internal-hostname"
+	// The annotation used for ranking resources that claim the same DNS name, higher values win
+	ownershipPriorityAnnotationKey = "external-dns.alpha.kubernetes.io/ownership-priority"
+	// The annotation used for allowing the adoption of existing records without owner
+	adoptAnnotationKey = "external-dns.alpha.kubernetes.io/adopt"
 )
 
 // Provider-specific annotations
@@ -75,6 +80,23 @@ type Source interface {
 	AddEventHandler(context.Context, func())
 }
 
+// EndpointResult is the outcome of a synchronization for an endpoint of a source.
+type EndpointResult struct {
+	// Endpoint is the endpoint as returned by the source
+	Endpoint *endpoint.Endpoint
+	// Targets are the targets of the record published by the DNS provider, empty if the record doesn't exist
+	Targets endpoint.Targets
+	// Conflict describes why someone else keeps the record from being published, empty without conflict
+	Conflict string
+	// Err is the error of the DNS provider applying the changes of the record
+	Err error
+}
+
+// ResultReporter is implemented by sources which report the outcome of synchronizations back on their resources.
+type ResultReporter interface {
+	ReportResults(ctx context.Context, results []EndpointResult)
+}
+
 func getTTLFromAnnotations(annotations map[string]string) (endpoint.TTL, error) {
 	ttlNotConfigured := endpoint.TTL(0)
 	ttlAnnotation, exists := annotations[ttlAnnotationKey]
@@ -114,6 +136,25 @@ func getHostnamesFromAnnotations(annotations map[string]string) []string {
 	return strings.Split(strings.Replace(hostnameAnnotation, " ", "", -1), ",")
 }
 
+// setConflictResolutionLabels copies the information used by conflict resolvers to
+// choose between resources claiming the same DNS name, and whether the resource may
+// adopt an existing record without owner, onto the endpoint labels.
+func setConflictResolutionLabels(ep *endpoint.Endpoint, meta metav1.ObjectMeta) {
+	if priority, ok := meta.Annotations[ownershipPriorityAnnotationKey]; ok {
+		if _, err := strconv.Atoi(priority); err != nil {
+			log.Warnf("Ignoring invalid ownership priority %q on %s/%s", priority, meta.Namespace, meta.Name)
+		} else {
+			ep.Labels[endpoint.OwnershipPriorityLabelKey] = priority
+		}
+	}
+	if !meta.CreationTimestamp.IsZero() {
+		ep.Labels[endpoint.CreationTimestampLabelKey] = meta.CreationTimestamp.UTC().Format(time.RFC3339)
+	}
+	if meta.Annotations[adoptAnnotationKey] == "true" {
+		ep.Labels[endpoint.AdoptLabelKey] = "true"
+	}
+}
+
 func getAccessFromAnnotations(annotations map[string]string) string {
 	return annotations[accessAnnotationKey]
 }
@@ -187,12 +228,16 @@ func getTargetsFromTargetAnnotation(annotations map[string]string) endpoint.Targ
 }
 
 // suitableType returns the DNS resource record type suitable for the target.
-// In this case type A for IPs and type CNAME for everything else.
+// In this case type A for IPv4 addresses, type AAAA for IPv6 addresses and type CNAME for everything else.
 func suitableType(target string) string {
-	if net.ParseIP(target) != nil {
-		return endpoint.RecordTypeA
+	ip := net.ParseIP(target)
+	if ip == nil {
+		return endpoint.RecordTypeCNAME
 	}
-	return endpoint.RecordTypeCNAME
+	if ip.To4() == nil {
+		return endpoint.RecordTypeAAAA
+	}
+	return endpoint.RecordTypeA
 }
 
 // endpointsForHostname returns the endpoint objects for each host-target combination.
@@ -200,12 +245,15 @@ func endpointsForHostname(hostname string, targets endpoint.Targets, ttl endpoin
 	var endpoints []*endpoint.Endpoint
 
 	var aTargets endpoint.Targets
+	var aaaaTargets endpoint.Targets
 	var cnameTargets endpoint.Targets
 
 	for _, t := range targets {
 		switch suitableType(t) {
 		case endpoint.RecordTypeA:
 			aTargets = append(aTargets, t)
+		case endpoint.RecordTypeAAAA:
+			aaaaTargets = append(aaaaTargets, t)
 		default:
 			cnameTargets = append(cnameTargets, t)
 		}
@@ -224,6 +272,19 @@ func endpointsForHostname(hostname string, targets endpoint.Targets, ttl endpoin
 		endpoints = append(endpoints, epA)
 	}
 
+	if len(aaaaTargets) > 0 {
+		epAAAA := &endpoint.Endpoint{
+			DNSName:          strings.TrimSuffix(hostname, "."),
+			Targets:          aaaaTargets,
+			RecordTTL:        ttl,
+			RecordType:       endpoint.RecordTypeAAAA,
+			Labels:           endpoint.NewLabels(),
+			ProviderSpecific: providerSpecific,
+			SetIdentifier:    setIdentifier,
+		}
+		endpoints = append(endpoints, epAAAA)
+	}
+
 	if len(cnameTargets) > 0 {
 		epCNAME := &endpoint.Endpoint{
 			DNSName:          strings.TrimSuffix(hostname, "."),
@@ -19,8 +19,10 @@ package source
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
@@ -94,6 +96,8 @@ func TestSuitableType(t *testing.T) {
 		target, recordType, expected string
 	}{
 		{"8.8.8.8", "", "A"},
+		{"2001:db8::1", "", "AAAA"},
+		{"::ffff:8.8.8.8", "", "A"},
 		{"foo.example.org", "", "CNAME"},
 		{"bar.eu-central-1.elb.amazonaws.com", "", "CNAME"},
 	} {
@@ -105,3 +109,68 @@ func TestSuitableType(t *testing.T) {
 		}
 	}
 }
+
+func TestEndpointsForHostname(t *testing.T) {
+	endpoints := endpointsForHostname("foo.example.org.", endpoint.Targets{"1.2.3.4", "2001:db8::1", "lb.example.com", "2001:db8::2"}, endpoint.TTL(60), nil, "")
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}, RecordTTL: 60},
+		{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1", "2001:db8::2"}, RecordTTL: 60},
+		{DNSName: "foo.example.org", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"lb.example.com"}, RecordTTL: 60},
+	})
+}
+
+func TestSetConflictResolutionLabels(t *testing.T) {
+	for _, tc := range []struct {
+		title    string
+		meta     metav1.ObjectMeta
+		expected endpoint.Labels
+	}{
+		{
+			title:    "no priority annotation and creation timestamp",
+			meta:     metav1.ObjectMeta{Name: "foo"},
+			expected: endpoint.Labels{},
+		},
+		{
+			title: "priority annotation and creation timestamp",
+			meta: metav1.ObjectMeta{
+				Name:              "foo",
+				Annotations:       map[string]string{ownershipPriorityAnnotationKey: "10"},
+				CreationTimestamp: metav1.NewTime(time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)),
+			},
+			expected: endpoint.Labels{
+				endpoint.OwnershipPriorityLabelKey: "10",
+				endpoint.CreationTimestampLabelKey: "2021-02-03T04:05:06Z",
+			},
+		},
+		{
+			title: "invalid priority annotation",
+			meta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{ownershipPriorityAnnotationKey: "high"},
+			},
+			expected: endpoint.Labels{},
+		},
+		{
+			title: "adopt annotation",
+			meta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{adoptAnnotationKey: "true"},
+			},
+			expected: endpoint.Labels{endpoint.AdoptLabelKey: "true"},
+		},
+		{
+			title: "adopt annotation disabled",
+			meta: metav1.ObjectMeta{
+				Name:        "foo",
+				Annotations: map[string]string{adoptAnnotationKey: "false"},
+			},
+			expected: endpoint.Labels{},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			ep := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+			setConflictResolutionLabels(ep, tc.meta)
+			assert.Equal(t, tc.expected, ep.Labels)
+		})
+	}
+}
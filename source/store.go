@@ -48,13 +48,20 @@ type Config struct {
 	IgnoreHostnameAnnotation       bool
 	IgnoreIngressTLSSpec           bool
 	IgnoreIngressRulesSpec         bool
+	IngressClassNames              []string
 	Compatibility                  string
 	PublishInternal                bool
 	PublishHostIP                  bool
+	PublishPodIPs                  bool
 	AlwaysPublishNotReadyAddresses bool
 	ConnectorServer                string
+	ConnectorCAFile                string
+	ConnectorCertFile              string
+	ConnectorKeyFile               string
+	ConnectorTokenFile             string
 	CRDSourceAPIVersion            string
 	CRDSourceKind                  string
+	FilePaths                      []string
 	KubeConfig                     string
 	APIServerURL                   string
 	ServiceTypeFilter              []string
@@ -189,13 +196,17 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 		if err != nil {
 			return nil, err
 		}
-		return NewIngressSource(client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.IgnoreIngressTLSSpec, cfg.IgnoreIngressRulesSpec)
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewIngressSource(dynamicClient, client, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation, cfg.IgnoreIngressTLSSpec, cfg.IgnoreIngressRulesSpec, cfg.IngressClassNames)
 	case "pod":
 		client, err := p.KubeClient()
 		if err != nil {
 			return nil, err
 		}
-		return NewPodSource(client, cfg.Namespace, cfg.Compatibility)
+		return NewPodSource(client, cfg.Namespace, cfg.AnnotationFilter, cfg.LabelFilter, cfg.FQDNTemplate, cfg.Compatibility, cfg.PublishPodIPs)
 	case "istio-gateway":
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
@@ -248,6 +259,24 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 			return nil, err
 		}
 		return NewContourHTTPProxySource(dynamicClient, cfg.Namespace, cfg.AnnotationFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+	case "gateway-httproute":
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewGatewayHTTPRouteSource(dynamicClient, cfg.Namespace, cfg.AnnotationFilter, cfg.LabelFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+	case "gateway-tlsroute":
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewGatewayTLSRouteSource(dynamicClient, cfg.Namespace, cfg.AnnotationFilter, cfg.LabelFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
+	case "gateway-tcproute":
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewGatewayTCPRouteSource(dynamicClient, cfg.Namespace, cfg.AnnotationFilter, cfg.LabelFilter, cfg.FQDNTemplate, cfg.CombineFQDNAndAnnotation, cfg.IgnoreHostnameAnnotation)
 	case "gloo-proxy":
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
@@ -267,7 +296,9 @@ func BuildWithConfig(source string, p ClientGenerator, cfg *Config) (Source, err
 	case "fake":
 		return NewFakeSource(cfg.FQDNTemplate)
 	case "connector":
-		return NewConnectorSource(cfg.ConnectorServer)
+		return NewConnectorSource(cfg.ConnectorServer, cfg.ConnectorCAFile, cfg.ConnectorCertFile, cfg.ConnectorKeyFile, cfg.ConnectorTokenFile)
+	case "file":
+		return NewFileSource(cfg.FilePaths)
 	case "crd":
 		client, err := p.KubeClient()
 		if err != nil {
@@ -92,7 +92,7 @@ func (suite *ByNamesTestSuite) TestAllInitialized() {
 	fakeDynamic, _ := newDynamicKubernetesClient()
 
 	mockClientGenerator := new(MockClientGenerator)
-	mockClientGenerator.On("KubeClient").Return(fakeKube.NewSimpleClientset(), nil)
+	mockClientGenerator.On("KubeClient").Return(newIngressV1beta1Clientset(), nil)
 	mockClientGenerator.On("IstioClient").Return(NewFakeConfigStore(), nil)
 	mockClientGenerator.On("DynamicKubernetesClient").Return(fakeDynamic, nil)
 
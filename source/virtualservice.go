@@ -314,6 +314,7 @@ func (sc *virtualServiceSource) filterByAnnotations(virtualservices []networking
 func (sc *virtualServiceSource) setResourceLabel(virtualservice networkingv1alpha3.VirtualService, endpoints []*endpoint.Endpoint) {
 	for _, ep := range endpoints {
 		ep.Labels[endpoint.ResourceLabelKey] = fmt.Sprintf("virtualservice/%s/%s", virtualservice.Namespace, virtualservice.Name)
+		setConflictResolutionLabels(ep, virtualservice.ObjectMeta)
 	}
 }
 